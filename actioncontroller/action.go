@@ -10,6 +10,9 @@ type Context struct {
 	context.Context
 
 	Params Parameters
+
+	// Request is the request processed by the action.
+	Request *Request
 }
 
 // Result defines a contract that represents the result of action method.
@@ -32,6 +35,11 @@ func (fn AnonymousAction) Process(ctx *Context) Result {
 type NamedAction struct {
 	Name    string
 	Request []activerecord.Attribute
+
+	// Permitted are parameters permitted by the action, when they are not
+	// set, attributes of the request are permitted.
+	Permitted *StrongParameters
+
 	AnonymousAction
 }
 
@@ -39,6 +47,14 @@ func (a *NamedAction) ActionRequest() []activerecord.Attribute {
 	return a.Request
 }
 
+// ActionPermitted returns parameters permitted by the action.
+func (a *NamedAction) ActionPermitted() *StrongParameters {
+	if a.Permitted == nil {
+		return &StrongParameters{Attributes: a.Request}
+	}
+	return a.Permitted
+}
+
 func (a *NamedAction) ActionName() string {
 	return a.Name
 }
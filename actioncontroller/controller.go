@@ -6,26 +6,123 @@ import (
 
 type actionsMap map[string]*NamedAction
 
-func (m actionsMap) copy() actionsMap {
-	mm := make(actionsMap, len(m))
-	for name, action := range m {
-		mm[name] = action
+type C struct {
+	actions        actionsMap
+	params         map[string]*StrongParameters
+	filters        filterChain
+	filterable     bool
+	sortable       bool
+	sortableFields []string
+}
+
+// Inherit copies actions, permitted parameters and filters of the parent
+// controller. Filters of the parent are executed before filters declared
+// after inheritance, actions with the same name override parent actions.
+func (c *C) Inherit(parent *ActionController) {
+	for name, action := range parent.declared {
+		c.actions[name] = action
+	}
+	for name, params := range parent.params {
+		c.params[name] = c.params[name].merge(params)
 	}
-	return mm
+	c.filters = append(c.filters, parent.filters...)
+	c.filterable = c.filterable || parent.filterable
+	c.sortable = c.sortable || parent.sortable
+	c.sortableFields = append(c.sortableFields, parent.sortableFields...)
+}
+
+// Filterable declares the index action filters resources by the "where"
+// parameter, usually with the Filter method of the activerecord.Relation.
+func (c *C) Filterable() {
+	c.filterable = true
+}
+
+// Sortable declares the index action orders resources by the "orderBy"
+// parameter, usually with the OrderBy method of the activerecord.Relation.
+//
+// Fields of associations are delimited by dot, e.g. "author.name". When
+// fields are not declared, the index action could be ordered by any attribute.
+func (c *C) Sortable(fields ...string) {
+	c.sortable = true
+	c.sortableFields = append(c.sortableFields, fields...)
+}
+
+// BeforeAction declares a filter executed before the actions with the given
+// names, or before all actions, when names are omitted. The filter halts the
+// chain by returning non-nil result, the result is returned instead of the
+// result of the action.
+//
+// Use BeforeFilter to declare a named filter, that could be skipped.
+func (c *C) BeforeAction(action AnonymousAction, only ...string) {
+	c.BeforeFilter("", action, Only(only...))
 }
 
-type C struct {
-	actions actionsMap
-	params  map[string][]activerecord.Attribute
+// AfterAction declares a filter executed after the actions with the given
+// names, or after all actions, when names are omitted. Non-nil result of
+// the filter replaces the result of the action.
+//
+// Use AfterFilter to declare a filter receiving the result of the action.
+func (c *C) AfterAction(action AnonymousAction, only ...string) {
+	c.AfterFilter("", func(ctx *Context, result Result) Result {
+		if r := action(ctx); r != nil {
+			return r
+		}
+		return result
+	}, Only(only...))
+}
+
+// AroundAction declares a filter executed before the actions with the given
+// names, or before all actions, when names are omitted.
+//
+// Deprecated: the filter cannot process the action, so it is executed as
+// a before filter. Use AroundFilter to wrap the processing of the action.
+func (c *C) AroundAction(action AnonymousAction, only ...string) {
+	c.BeforeAction(action, only...)
 }
 
-func (c *C) BeforeAction(action AnonymousAction, only ...string) {
+// BeforeFilter declares a named filter executed before the action. The
+// filter halts the chain by returning non-nil result, the result is returned
+// instead of the result of the action.
+func (c *C) BeforeFilter(name string, action AnonymousAction, opts ...FilterOption) {
+	c.filters = append(c.filters, filter{
+		kind: filterBefore, name: name, scope: newFilterScope(opts), before: action,
+	})
 }
 
-func (c *C) AfterAction(action AnonymousAction, only ...string) {
+// AfterFilter declares a named filter executed after the action.
+func (c *C) AfterFilter(name string, action AfterFilter, opts ...FilterOption) {
+	c.filters = append(c.filters, filter{
+		kind: filterAfter, name: name, scope: newFilterScope(opts), after: action,
+	})
 }
 
-func (c *C) AroundAction(action AnonymousAction, only ...string) {
+// AroundFilter declares a named filter wrapping the action.
+func (c *C) AroundFilter(name string, action AroundFilter, opts ...FilterOption) {
+	c.filters = append(c.filters, filter{
+		kind: filterAround, name: name, scope: newFilterScope(opts), around: action,
+	})
+}
+
+// SkipBeforeFilter skips the before filter with the given name declared
+// previously, e.g. inherited from the parent controller.
+func (c *C) SkipBeforeFilter(name string, opts ...FilterOption) {
+	c.skip(filterBefore, name, opts)
+}
+
+// SkipAfterFilter skips the after filter with the given name.
+func (c *C) SkipAfterFilter(name string, opts ...FilterOption) {
+	c.skip(filterAfter, name, opts)
+}
+
+// SkipAroundFilter skips the around filter with the given name.
+func (c *C) SkipAroundFilter(name string, opts ...FilterOption) {
+	c.skip(filterAround, name, opts)
+}
+
+func (c *C) skip(kind filterKind, name string, opts []FilterOption) {
+	c.filters = append(c.filters, filter{
+		kind: filterSkip, name: name, scope: newFilterScope(opts), skip: kind,
+	})
 }
 
 func (c *C) Action(name string, a AnonymousAction) {
@@ -33,9 +130,19 @@ func (c *C) Action(name string, a AnonymousAction) {
 }
 
 func (c *C) Permit(params []activerecord.Attribute, names ...string) {
+	c.PermitParameters(&StrongParameters{Attributes: params}, names...)
+}
+
+// PermitParameters permits parameters to the actions with the given names,
+// e.g. nested hashes and arrays of hashes:
+//
+//	c.PermitParameters(actioncontroller.Require(Book).Permit("title").
+//		PermitArray("tags", actioncontroller.Require(Tag).Permit("name")),
+//		actioncontroller.ActionCreate,
+//	)
+func (c *C) PermitParameters(params *StrongParameters, names ...string) {
 	for _, name := range names {
-		p := c.params[name]
-		c.params[name] = append(p, params...)
+		c.params[name] = c.params[name].merge(params)
 	}
 }
 
@@ -61,6 +168,15 @@ func (c *C) Destroy(a AnonymousAction) {
 
 type ActionController struct {
 	actions actionsMap
+
+	// declared are actions without filters, they are kept along with
+	// permitted parameters and filters to inherit the controller.
+	declared       actionsMap
+	params         map[string]*StrongParameters
+	filters        filterChain
+	filterable     bool
+	sortable       bool
+	sortableFields []string
 }
 
 func New(init func(*C)) *ActionController {
@@ -72,18 +188,51 @@ func New(init func(*C)) *ActionController {
 }
 
 func Initialize(init func(*C)) (*ActionController, error) {
-	c := C{actions: make(actionsMap), params: make(map[string][]activerecord.Attribute)}
+	c := C{actions: make(actionsMap), params: make(map[string]*StrongParameters)}
 	init(&c)
 
+	declared := make(actionsMap, len(c.actions))
+	actions := make(actionsMap, len(c.actions))
 	for actionName, action := range c.actions {
-		action.Request = c.params[actionName]
+		permitted := c.params[actionName]
+		action = &NamedAction{
+			Name:            action.Name,
+			Request:         permitted.attributes(),
+			Permitted:       permitted,
+			AnonymousAction: action.AnonymousAction,
+		}
+		declared[actionName] = action
+		actions[actionName] = c.filters.wrap(action)
 	}
 
 	return &ActionController{
-		actions: c.actions.copy(),
+		actions:        actions,
+		declared:       declared,
+		params:         c.params,
+		filters:        c.filters,
+		filterable:     c.filterable,
+		sortable:       c.sortable,
+		sortableFields: c.sortableFields,
 	}, nil
 }
 
+// Filterable returns true, when the index action filters resources by the
+// "where" parameter.
+func (c *ActionController) Filterable() bool {
+	return c.filterable
+}
+
+// Sortable returns true, when the index action orders resources by the
+// "orderBy" parameter.
+func (c *ActionController) Sortable() bool {
+	return c.sortable
+}
+
+// SortableFields returns fields the index action could be ordered by.
+func (c *ActionController) SortableFields() []string {
+	return c.sortableFields
+}
+
 func (c *ActionController) HasAction(actionName string) bool {
 	_, ok := c.actions[actionName]
 	return ok
@@ -0,0 +1,177 @@
+package actioncontroller
+
+// AfterFilter is executed after the action, it receives the result of the
+// action and returns the result to respond with, so the filter can replace
+// or wrap the original result.
+type AfterFilter func(ctx *Context, result Result) Result
+
+// AroundFilter wraps the processing of the action, the filter must call
+// Process of the given action to continue the chain.
+type AroundFilter func(ctx *Context, action Action) Result
+
+// FilterOption restricts the set of actions the filter applies to.
+type FilterOption func(*filterScope)
+
+// Only applies the filter only to the actions with the given names.
+func Only(actionNames ...string) FilterOption {
+	return func(s *filterScope) {
+		s.only = append(s.only, actionNames...)
+	}
+}
+
+// Except applies the filter to all actions, but the actions with the
+// given names.
+func Except(actionNames ...string) FilterOption {
+	return func(s *filterScope) {
+		s.except = append(s.except, actionNames...)
+	}
+}
+
+type filterScope struct {
+	only   []string
+	except []string
+}
+
+func newFilterScope(opts []FilterOption) filterScope {
+	var s filterScope
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+func (s filterScope) applies(actionName string) bool {
+	for _, name := range s.except {
+		if name == actionName {
+			return false
+		}
+	}
+	if len(s.only) == 0 {
+		return true
+	}
+	for _, name := range s.only {
+		if name == actionName {
+			return true
+		}
+	}
+	return false
+}
+
+type filterKind int
+
+const (
+	filterBefore filterKind = iota
+	filterAfter
+	filterAround
+	filterSkip
+)
+
+type filter struct {
+	kind  filterKind
+	name  string
+	scope filterScope
+
+	before AnonymousAction
+	after  AfterFilter
+	around AroundFilter
+
+	// skip is the kind of the skipped filter.
+	skip filterKind
+}
+
+type filterChain []filter
+
+// forAction returns filters applicable to the action in order of their
+// declaration, skipped filters are excluded from the chain.
+func (fc filterChain) forAction(actionName string) filterChain {
+	chain := make(filterChain, 0, len(fc))
+	for _, f := range fc {
+		if !f.scope.applies(actionName) {
+			continue
+		}
+		if f.kind != filterSkip {
+			chain = append(chain, f)
+			continue
+		}
+
+		// Skip removes all filters with the given name declared before.
+		n := 0
+		for _, ff := range chain {
+			if ff.kind != f.skip || ff.name != f.name {
+				chain[n] = ff
+				n++
+			}
+		}
+		chain = chain[:n]
+	}
+	return chain
+}
+
+// wrap returns an action that processes the chain of filters around
+// the given action.
+//
+// Filters are executed in order of declaration, each filter wraps the rest
+// of the chain: a before filter is executed before the rest of the chain,
+// an after filter is executed on the result of the rest of the chain, so
+// after filters are executed in reverse order, and an around filter calls
+// the rest of the chain itself.
+//
+// When a before filter returns non-nil result, or an around filter does not
+// process the action, the chain halts and the result is returned without
+// processing the action and after filters.
+func (fc filterChain) wrap(action *NamedAction) *NamedAction {
+	chain := fc.forAction(action.Name)
+	if len(chain) == 0 {
+		return action
+	}
+
+	return &NamedAction{
+		Name:      action.Name,
+		Request:   action.Request,
+		Permitted: action.Permitted,
+		AnonymousAction: func(ctx *Context) Result {
+			var halted bool
+			return chain.process(ctx, action, &halted)
+		},
+	}
+}
+
+// process executes the first filter of the chain around the rest of the
+// chain, the action is processed at the end of the chain.
+func (fc filterChain) process(ctx *Context, action *NamedAction, halted *bool) Result {
+	if len(fc) == 0 {
+		return action.Process(ctx)
+	}
+
+	f, rest := fc[0], fc[1:]
+	switch f.kind {
+	case filterBefore:
+		if result := f.before(ctx); result != nil {
+			*halted = true
+			return result
+		}
+		return rest.process(ctx, action, halted)
+	case filterAfter:
+		result := rest.process(ctx, action, halted)
+		if *halted {
+			return result
+		}
+		return f.after(ctx, result)
+	default:
+		var processed bool
+		next := &NamedAction{
+			Name:      action.Name,
+			Request:   action.Request,
+			Permitted: action.Permitted,
+			AnonymousAction: func(ctx *Context) Result {
+				processed = true
+				return rest.process(ctx, action, halted)
+			},
+		}
+		result := f.around(ctx, next)
+		if !processed {
+			*halted = true
+		}
+		return result
+	}
+}
@@ -0,0 +1,235 @@
+package actioncontroller_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/actioncontroller"
+	"github.com/activegraph/activegraph/actionview"
+)
+
+func respond(val interface{}, err error) actioncontroller.Result {
+	return actionview.ResultFunc(func(*actioncontroller.Context) (interface{}, error) {
+		return val, err
+	})
+}
+
+func TestActionController_Filters(t *testing.T) {
+	var calls []string
+
+	base := actioncontroller.New(func(c *actioncontroller.C) {
+		c.BeforeFilter("authenticate", func(ctx *actioncontroller.Context) actioncontroller.Result {
+			calls = append(calls, "authenticate")
+			if ctx.Request.Header.Get("Authorization") == "" {
+				return respond(nil, errors.New("unauthorized"))
+			}
+			return nil
+		})
+		c.AroundFilter("trace", func(ctx *actioncontroller.Context, a actioncontroller.Action) actioncontroller.Result {
+			calls = append(calls, "trace:"+a.ActionName())
+			return a.Process(ctx)
+		})
+	})
+
+	novels := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Inherit(base)
+		c.SkipBeforeFilter("authenticate", actioncontroller.Only(actioncontroller.ActionIndex))
+		c.AfterFilter("wrap", func(ctx *actioncontroller.Context, r actioncontroller.Result) actioncontroller.Result {
+			calls = append(calls, "wrap")
+			val, err := r.Execute(ctx)
+			return respond(map[string]interface{}{"novel": val}, err)
+		}, actioncontroller.Except(actioncontroller.ActionIndex))
+
+		c.Index(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			calls = append(calls, "index")
+			return respond([]string{"Dune"}, nil)
+		})
+		c.Show(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			calls = append(calls, "show")
+			return respond("Dune", nil)
+		})
+	})
+
+	process := func(actionName string, header http.Header) (interface{}, error) {
+		ctx := &actioncontroller.Context{
+			Context: context.Background(),
+			Request: &actioncontroller.Request{Header: header},
+		}
+		return novels.Action(actionName).Process(ctx).Execute(ctx)
+	}
+
+	tests := []struct {
+		name   string
+		action string
+		header http.Header
+		want   interface{}
+		err    string
+		calls  []string
+	}{
+		{
+			name:   "Skipped",
+			action: actioncontroller.ActionIndex,
+			want:   []string{"Dune"},
+			calls:  []string{"trace:index", "index"},
+		},
+		{
+			name:   "Halted",
+			action: actioncontroller.ActionShow,
+			err:    "unauthorized",
+			calls:  []string{"authenticate"},
+		},
+		{
+			name:   "Passed",
+			action: actioncontroller.ActionShow,
+			header: http.Header{"Authorization": {"token"}},
+			want:   map[string]interface{}{"novel": "Dune"},
+			calls:  []string{"authenticate", "trace:show", "show", "wrap"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			val, err := process(tt.action, tt.header)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, val)
+			}
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+func TestActionController_FiltersOrder(t *testing.T) {
+	var calls []string
+
+	before := func(name string) actioncontroller.AnonymousAction {
+		return func(ctx *actioncontroller.Context) actioncontroller.Result {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	after := func(name string) actioncontroller.AfterFilter {
+		return func(ctx *actioncontroller.Context, r actioncontroller.Result) actioncontroller.Result {
+			calls = append(calls, name)
+			return r
+		}
+	}
+	around := func(name string) actioncontroller.AroundFilter {
+		return func(ctx *actioncontroller.Context, a actioncontroller.Action) actioncontroller.Result {
+			calls = append(calls, name+":begin")
+			defer func() { calls = append(calls, name+":end") }()
+			return a.Process(ctx)
+		}
+	}
+
+	novels := actioncontroller.New(func(c *actioncontroller.C) {
+		c.BeforeFilter("before1", before("before1"))
+		c.AfterFilter("after1", after("after1"))
+		c.AroundFilter("around1", around("around1"))
+		c.BeforeFilter("before2", before("before2"))
+		c.AfterFilter("after2", after("after2"))
+		c.AroundFilter("around2", around("around2"))
+		c.BeforeFilter("before3", before("before3"))
+
+		c.Index(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			calls = append(calls, "index")
+			return respond(nil, nil)
+		})
+	})
+
+	ctx := &actioncontroller.Context{Context: context.Background()}
+	novels.Action(actioncontroller.ActionIndex).Process(ctx)
+
+	// Each filter wraps filters declared after it, so after filters
+	// are executed in reverse order of declaration.
+	assert.Equal(t, []string{
+		"before1",
+		"around1:begin",
+		"before2",
+		"around2:begin",
+		"before3",
+		"index",
+		"around2:end",
+		"after2",
+		"around1:end",
+		"after1",
+	}, calls)
+}
+
+func TestActionController_FiltersHalted(t *testing.T) {
+	var calls []string
+
+	novels := actioncontroller.New(func(c *actioncontroller.C) {
+		c.AfterFilter("after", func(ctx *actioncontroller.Context, r actioncontroller.Result) actioncontroller.Result {
+			calls = append(calls, "after")
+			return r
+		})
+		c.AroundFilter("cache", func(ctx *actioncontroller.Context, a actioncontroller.Action) actioncontroller.Result {
+			calls = append(calls, "cache")
+			return respond("cached", nil)
+		})
+
+		c.Index(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			calls = append(calls, "index")
+			return respond(nil, nil)
+		})
+	})
+
+	ctx := &actioncontroller.Context{Context: context.Background()}
+	val, err := novels.Action(actioncontroller.ActionIndex).Process(ctx).Execute(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", val)
+
+	// The around filter does not process the action, so the chain halts.
+	assert.Equal(t, []string{"cache"}, calls)
+}
+
+func TestActionController_FiltersActions(t *testing.T) {
+	var calls []string
+
+	filter := func(name string, result actioncontroller.Result) actioncontroller.AnonymousAction {
+		return func(ctx *actioncontroller.Context) actioncontroller.Result {
+			calls = append(calls, name)
+			return result
+		}
+	}
+
+	novels := actioncontroller.New(func(c *actioncontroller.C) {
+		c.BeforeAction(filter("before", nil))
+		c.BeforeAction(filter("beforeShow", nil), actioncontroller.ActionShow)
+		c.AfterAction(filter("after", nil))
+		c.AfterAction(filter("afterShow", respond("replaced", nil)), actioncontroller.ActionShow)
+
+		c.Index(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			calls = append(calls, "index")
+			return respond("index", nil)
+		})
+		c.Show(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			calls = append(calls, "show")
+			return respond("show", nil)
+		})
+	})
+
+	process := func(actionName string) interface{} {
+		ctx := &actioncontroller.Context{Context: context.Background()}
+		val, err := novels.Action(actionName).Process(ctx).Execute(ctx)
+		require.NoError(t, err)
+		return val
+	}
+
+	assert.Equal(t, "index", process(actioncontroller.ActionIndex))
+	assert.Equal(t, []string{"before", "index", "after"}, calls)
+
+	// Non-nil result of the after action replaces the result.
+	calls = nil
+	assert.Equal(t, "replaced", process(actioncontroller.ActionShow))
+	assert.Equal(t, []string{"before", "beforeShow", "show", "afterShow", "after"}, calls)
+}
@@ -0,0 +1,85 @@
+package graphql
+
+import (
+	"strings"
+
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/internal"
+
+	"github.com/graphql-go/graphql"
+)
+
+// FilterArg is the name of the argument of index queries, that filters
+// the list of resources.
+const FilterArg = "where"
+
+// operatorsconv returns an input object of filter operators applicable to
+// the attributes of the given type, input objects are shared by all filters.
+func operatorsconv(b *internal.SchemaBuilder, t string) graphql.Input {
+	scalar := typeconv(t)
+	if scalar == nil {
+		return nil
+	}
+
+	name := scalar.Name() + "Filter"
+	if input, ok := b.Inputs[name]; ok {
+		return input
+	}
+
+	fields := graphql.InputObjectConfigFieldMap{
+		activerecord.FilterEq:     &graphql.InputObjectFieldConfig{Type: scalar},
+		activerecord.FilterNe:     &graphql.InputObjectFieldConfig{Type: scalar},
+		activerecord.FilterIn:     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(scalar))},
+		activerecord.FilterLt:     &graphql.InputObjectFieldConfig{Type: scalar},
+		activerecord.FilterGt:     &graphql.InputObjectFieldConfig{Type: scalar},
+		activerecord.FilterIsNull: &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+	}
+	if t == activerecord.String {
+		fields[activerecord.FilterContains] = &graphql.InputObjectFieldConfig{Type: scalar}
+	}
+
+	input := graphql.NewInputObject(graphql.InputObjectConfig{Name: name, Fields: fields})
+	b.Inputs[name] = input
+	return input
+}
+
+// filterconv returns the filter input object of the relation, the filter
+// combines operators of attributes, filters of associations the record
+// belongs to, and nested filters with "and", "or" and "not" combinators.
+func filterconv(b *internal.SchemaBuilder, rel *activerecord.Relation) graphql.Input {
+	name := strings.Title(rel.Name()) + "Filter"
+	if input, ok := b.Inputs[name]; ok {
+		return input
+	}
+
+	var input *graphql.InputObject
+
+	// Fields are defined lazily, since filters reference themselves and
+	// filters of associations.
+	fields := func() graphql.InputObjectConfigFieldMap {
+		fields := graphql.InputObjectConfigFieldMap{
+			activerecord.FilterAnd: &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(input))},
+			activerecord.FilterOr:  &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(input))},
+			activerecord.FilterNot: &graphql.InputObjectFieldConfig{Type: input},
+		}
+		for _, attr := range rel.AttributesForInspect() {
+			if operators := operatorsconv(b, attr.CastType()); operators != nil {
+				fields[attr.AttributeName()] = &graphql.InputObjectFieldConfig{Type: operators}
+			}
+		}
+		for _, assoc := range rel.ReflectOnAllAssociations() {
+			if _, ok := assoc.Association.(*activerecord.BelongsTo); ok {
+				fields[assoc.AssociationName()] = &graphql.InputObjectFieldConfig{
+					Type: filterconv(b, assoc.Relation),
+				}
+			}
+		}
+		return fields
+	}
+
+	input = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: name, Fields: graphql.InputObjectConfigFieldMapThunk(fields),
+	})
+	b.Inputs[name] = input
+	return input
+}
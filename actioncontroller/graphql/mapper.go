@@ -1,15 +1,20 @@
 package graphql
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/activegraph/activegraph/actioncontroller"
 	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activesupport"
+	"github.com/activegraph/activegraph/internal"
 
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/handler"
+	"github.com/pkg/errors"
 )
 
 type ErrConstraintNotFound struct {
@@ -24,6 +29,27 @@ func (e ErrConstraintNotFound) Error() string {
 	)
 }
 
+// ErrActionNotMatched is returned, when none of the actions matched to the
+// path matches the request.
+type ErrActionNotMatched struct {
+	Operation string
+	Name      string
+}
+
+func (e ErrActionNotMatched) Error() string {
+	return fmt.Sprintf("no action matches %s '%s'", e.Operation, e.Name)
+}
+
+// ErrUnsupportedOperation is returned on matching of the path to the
+// unsupported operation.
+type ErrUnsupportedOperation struct {
+	Operation string
+}
+
+func (e ErrUnsupportedOperation) Error() string {
+	return fmt.Sprintf("unsupported operation '%s'", e.Operation)
+}
+
 const (
 	// GraphQL operations.
 	OperationQuery        = "query"        // a read-only fetch.
@@ -53,14 +79,18 @@ func argsconv(attrs []activerecord.Attribute) graphql.FieldConfigArgument {
 	return args
 }
 
-func objconv(name string, attrs []activerecord.Attribute) *graphql.Object {
+func objconv(
+	name string, attrs []activerecord.Attribute, interfaces ...*graphql.Interface,
+) *graphql.Object {
 	fields := make(graphql.Fields, len(attrs))
 	for _, attr := range attrs {
 		fields[attr.AttributeName()] = &graphql.Field{
 			Name: attr.AttributeName(), Type: typeconv(attr.CastType()),
 		}
 	}
-	return graphql.NewObject(graphql.ObjectConfig{Name: name, Fields: fields})
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name, Fields: fields, Interfaces: interfaces,
+	})
 }
 
 type resource struct {
@@ -75,19 +105,89 @@ type matching struct {
 	constraints actioncontroller.Constraints
 }
 
+// newContext returns the context of the action resolving the field, field
+// arguments are parameters of the action.
+func newContext(p graphql.ResolveParams) *actioncontroller.Context {
+	return newActionContext(p.Context, actioncontroller.Parameters(p.Args))
+}
+
+func newActionContext(ctx context.Context, params actioncontroller.Parameters) *actioncontroller.Context {
+	request := *actioncontroller.RequestFromContext(ctx)
+	request.Params = params
+
+	return &actioncontroller.Context{
+		Context: ctx, Params: params, Request: &request,
+	}
+}
+
+func processAction(action actioncontroller.Action, context *actioncontroller.Context) (interface{}, error) {
+	result := action.Process(context)
+	return result.Execute(context)
+}
+
 func newResolveFunc(action actioncontroller.Action) graphql.FieldResolveFn {
 	return func(p graphql.ResolveParams) (interface{}, error) {
-		context := &actioncontroller.Context{
-			Context: p.Context, Params: actioncontroller.Parameters(p.Args),
+		return processAction(action, newContext(p))
+	}
+}
+
+// newMatchResolveFunc returns a function, that processes the first action
+// of alternatives matching the request. Actions with matchers are evaluated
+// in the order of registration, the action without matcher is processed,
+// when none of them matches.
+func newMatchResolveFunc(alternatives []matching) graphql.FieldResolveFn {
+	ordered := make([]matching, 0, len(alternatives))
+	for _, alt := range alternatives {
+		if alt.constraints.Match != nil {
+			ordered = append(ordered, alt)
+		}
+	}
+	for _, alt := range alternatives {
+		if alt.constraints.Match == nil {
+			ordered = append(ordered, alt)
+		}
+	}
+
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		context := newContext(p)
+		for _, alt := range ordered {
+			if alt.constraints.Match == nil || alt.constraints.Match.Matches(context.Request) {
+				return processAction(alt.action, context)
+			}
 		}
-		result := action.Process(context)
-		return result.Execute(context)
+		return nil, ErrActionNotMatched{Operation: ordered[0].operation, Name: ordered[0].name}
 	}
 }
 
 type Mapper struct {
+	// Federation exposes the schema as an Apollo Federation v2 subgraph,
+	// resources backed by activerecord are resolved as entities by their
+	// primary keys.
+	Federation bool
+
 	resources []resource
 	matchings []matching
+	closures  []ActionClosure
+}
+
+// ActionClosure wraps the action processed by the field of the schema, e.g.
+// to measure the duration of actions. Nodes and entities are fetched through
+// the show action wrapped for the field of the show query.
+type ActionClosure func(field string, action actioncontroller.Action) actioncontroller.Action
+
+// EncloseActions wraps actions of the mapped resources and paths with the
+// closure, closures are applied in the order of registration, when the
+// schema is created.
+func (m *Mapper) EncloseActions(closure ActionClosure) {
+	m.closures = append(m.closures, closure)
+}
+
+// enclose wraps the action with registered closures.
+func (m *Mapper) enclose(field string, action actioncontroller.Action) actioncontroller.Action {
+	for _, closure := range m.closures {
+		action = closure(field, action)
+	}
+	return action
 }
 
 func (m *Mapper) Resources(
@@ -96,11 +196,32 @@ func (m *Mapper) Resources(
 	m.resources = append(m.resources, resource{model, controller})
 }
 
+// Match matches the query or mutation field to the action. Arguments of the
+// field are defined by the request constraint, and the payload object by the
+// response constraint.
+//
+// The same path could be matched to alternative actions with different
+// matchers, the action is chosen on each request, e.g. by the version of
+// the API:
+//
+//	m.Match("query", "stats", statsV2, actioncontroller.Constraints{
+//		Request:  request,
+//		Response: response,
+//		Match:    actioncontroller.MatchHeader("X-API-Version", "2"),
+//	})
+//	m.Match("query", "stats", stats, actioncontroller.Constraints{
+//		Request:  request,
+//		Response: response,
+//	})
 func (m *Mapper) Match(
 	via, path string,
 	action actioncontroller.Action,
 	constraints ...actioncontroller.Constraints,
 ) {
+	if via != OperationQuery && via != OperationMutation {
+		panic(ErrUnsupportedOperation{Operation: via})
+	}
+
 	var constraint actioncontroller.Constraints
 	if len(constraints) > 0 {
 		constraint = constraints[len(constraints)-1]
@@ -115,159 +236,475 @@ func (m *Mapper) Match(
 	m.matchings = append(m.matchings, matching{via, path, action, constraint})
 }
 
-func (m *Mapper) primaryKey(model actioncontroller.AbstractModel) graphql.FieldConfigArgument {
+// primaryKey returns the argument of the primary key, nodes are identified
+// by the global identifiers returned in the "id" field, so the argument
+// of the node is ID.
+func (m *Mapper) primaryKey(model actioncontroller.AbstractModel, node bool) graphql.FieldConfigArgument {
+	var t graphql.Input = graphql.ID
+	if !node {
+		t = typeconv(model.AttributeForInspect(model.PrimaryKey()).CastType())
+	}
 	return graphql.FieldConfigArgument{
-		model.PrimaryKey(): &graphql.ArgumentConfig{
-			Type: graphql.NewNonNull(
-				typeconv(model.AttributeForInspect(model.PrimaryKey()).CastType()),
-			),
-		},
+		model.PrimaryKey(): &graphql.ArgumentConfig{Type: graphql.NewNonNull(t)},
 	}
 }
 
-func (m *Mapper) newAction(
-	name string,
-	args []activerecord.Attribute,
-	result []activerecord.Attribute,
-	action actioncontroller.Action,
-) *graphql.Field {
+// decodeID returns the resolver, that decodes the primary key argument of
+// the node into the primary key of the relation before resolving.
+func decodeID(
+	model actioncontroller.AbstractModel, node bool, resolve graphql.FieldResolveFn,
+) graphql.FieldResolveFn {
+	rel, ok := model.(*activerecord.Relation)
+	if !ok || !node {
+		return resolve
+	}
+
+	pk := rel.PrimaryKey()
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		id, err := primaryKeyOf(rel, p.Args[pk])
+		if err != nil {
+			return nil, err
+		}
+		p.Args = internal.CopyMap(p.Args)
+		p.Args[pk] = id
+		return resolve(p)
+	}
+}
+
+// unionAttributes returns attributes of all lists, the attribute with the
+// same name must be of the same type in all lists.
+func unionAttributes(lists ...[]activerecord.Attribute) ([]activerecord.Attribute, error) {
+	var (
+		union []activerecord.Attribute
+		types = make(map[string]string)
+	)
+	for _, attrs := range lists {
+		for _, attr := range attrs {
+			name := attr.AttributeName()
+			t, ok := types[name]
+			if ok && t != attr.CastType() {
+				return nil, errors.Errorf("attribute %q is both %s and %s", name, t, attr.CastType())
+			}
+			if !ok {
+				types[name] = attr.CastType()
+				union = append(union, attr)
+			}
+		}
+	}
+	return union, nil
+}
+
+// newMatchAction creates a field of the matched path, arguments and payload
+// of the field are combined from constraints of alternative actions.
+func (m *Mapper) newMatchAction(alternatives []matching) (*graphql.Field, error) {
+	var (
+		name      = alternatives[0].name
+		requests  = make([][]activerecord.Attribute, 0, len(alternatives))
+		responses = make([][]activerecord.Attribute, 0, len(alternatives))
+	)
+	for _, alt := range alternatives {
+		requests = append(requests, alt.constraints.Request.Attributes)
+		responses = append(responses, alt.constraints.Response.Attributes)
+	}
+
+	args, err := unionAttributes(requests...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "request of %s '%s'", alternatives[0].operation, name)
+	}
+	result, err := unionAttributes(responses...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "response of %s '%s'", alternatives[0].operation, name)
+	}
+
+	enclosed := make([]matching, len(alternatives))
+	for i, alt := range alternatives {
+		alt.action = m.enclose(name, alt.action)
+		enclosed[i] = alt
+	}
+
 	return &graphql.Field{
 		Name:    name,
 		Args:    argsconv(args),
 		Type:    objconv(strings.Title(name)+"Payload", result),
-		Resolve: newResolveFunc(action),
+		Resolve: newMatchResolveFunc(enclosed),
+	}, nil
+}
+
+// groupMatchings groups alternative actions by operations and paths, groups
+// are ordered by the first registration.
+func (m *Mapper) groupMatchings() [][]matching {
+	var (
+		groups  [][]matching
+		indices = make(map[[2]string]int)
+	)
+	for _, matching := range m.matchings {
+		key := [2]string{matching.operation, matching.name}
+		i, ok := indices[key]
+		if !ok {
+			i = len(groups)
+			indices[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], matching)
 	}
+	return groups
 }
 
 func (m *Mapper) newIndexAction(
-	model actioncontroller.AbstractModel, output graphql.Output, action actioncontroller.Action,
-) *graphql.Field {
+	b *internal.SchemaBuilder,
+	resource resource,
+	output graphql.Output,
+	action actioncontroller.Action,
+) (*graphql.Field, error) {
+
+	model := resource.model
 
-	args := make(graphql.FieldConfigArgument, len(action.ActionRequest()))
+	args := make(graphql.FieldConfigArgument, len(action.ActionRequest())+1)
 	for _, attr := range action.ActionRequest() {
 		args[attr.AttributeName()] = &graphql.ArgumentConfig{
 			Type: typeconv(attr.CastType()),
 		}
 	}
 
+	// Resources backed by activerecord are filtered by the "where" argument
+	// and ordered by the "orderBy" argument, when the controller declares
+	// the index action applies them, e.g. with Filter and OrderBy methods
+	// of the activerecord.Relation.
+	if rel, ok := model.(*activerecord.Relation); ok {
+		if c, ok := resource.controller.(interface{ Filterable() bool }); ok && c.Filterable() {
+			args[FilterArg] = &graphql.ArgumentConfig{Type: filterconv(b, rel)}
+		}
+		if c, ok := resource.controller.(interface{ Sortable() bool }); ok && c.Sortable() {
+			fields, err := sortableFields(rel, resource.controller)
+			if err != nil {
+				return nil, err
+			}
+			args[OrderArg] = &graphql.ArgumentConfig{
+				Type: graphql.NewList(graphql.NewNonNull(orderconv(b, rel, fields))),
+			}
+		}
+	}
+
+	name := model.Name() + "s"
 	return &graphql.Field{
-		Name:    model.Name() + "s",
+		Name:    name,
 		Args:    args,
 		Type:    graphql.NewList(output),
-		Resolve: newResolveFunc(action),
-	}
+		Resolve: newResolveFunc(m.enclose(name, action)),
+	}, nil
 }
 
 func (m *Mapper) newShowAction(
-	model actioncontroller.AbstractModel, output graphql.Output, action actioncontroller.Action,
+	model actioncontroller.AbstractModel, node bool, output graphql.Output, action actioncontroller.Action,
 ) *graphql.Field {
 	return &graphql.Field{
 		Name:    model.Name(),
-		Args:    m.primaryKey(model),
+		Args:    m.primaryKey(model, node),
 		Type:    output,
-		Resolve: newResolveFunc(action),
+		Resolve: decodeID(model, node, newResolveFunc(m.enclose(model.Name(), action))),
 	}
 }
 
-func (m *Mapper) newUpdateAction(
-	operation string, model actioncontroller.AbstractModel, output graphql.Output, action actioncontroller.Action,
-) *graphql.Field {
-
-	objFields := make(graphql.InputObjectConfigFieldMap, len(action.ActionRequest()))
-	for _, attr := range action.ActionRequest() {
-		objFields[attr.AttributeName()] = &graphql.InputObjectFieldConfig{
+// inputconv returns an input object of the permitted parameters, nested
+// parameters are converted to nested input objects named after the parent.
+func inputconv(name string, permitted *actioncontroller.StrongParameters) *graphql.InputObject {
+	fields := make(graphql.InputObjectConfigFieldMap, len(permitted.Attributes)+len(permitted.Nested))
+	for _, attr := range permitted.Attributes {
+		fields[attr.AttributeName()] = &graphql.InputObjectFieldConfig{
 			Type: typeconv(attr.CastType()),
 		}
 	}
+	for _, nested := range permitted.Nested {
+		var input graphql.Input = inputconv(
+			strings.TrimSuffix(name, "Input")+strings.Title(nested.Name)+"Input", nested.Params,
+		)
+		if nested.Array {
+			input = graphql.NewList(graphql.NewNonNull(input))
+		}
+		fields[nested.Name] = &graphql.InputObjectFieldConfig{Type: input}
+	}
+	return graphql.NewInputObject(graphql.InputObjectConfig{Name: name, Fields: fields})
+}
+
+func (m *Mapper) newUpdateAction(
+	operation string, model actioncontroller.AbstractModel, node bool,
+	output graphql.Output, action actioncontroller.Action,
+) *graphql.Field {
+
+	name := operation + strings.Title(model.Name())
+	permitted := actioncontroller.PermittedParameters(action)
+	action = m.enclose(name, action)
 
 	args := graphql.FieldConfigArgument{
 		model.Name(): &graphql.ArgumentConfig{
-			Type: graphql.NewNonNull(graphql.NewInputObject(graphql.InputObjectConfig{
-				Name:   strings.Title(operation) + strings.Title(model.Name()) + "Input",
-				Fields: objFields,
-			})),
+			Type: graphql.NewNonNull(inputconv(
+				strings.Title(operation)+strings.Title(model.Name())+"Input", permitted,
+			)),
 		},
 	}
 
 	// TODO: separate creation and update
 	if operation == "update" {
-		args[model.PrimaryKey()] = m.primaryKey(model)[model.PrimaryKey()]
+		args[model.PrimaryKey()] = m.primaryKey(model, node)[model.PrimaryKey()]
+	}
+
+	// Input of the resource is filtered by permitted parameters, so the
+	// action receives values casted to types of the attributes.
+	resolve := func(p graphql.ResolveParams) (interface{}, error) {
+		context := newContext(p)
+		input, err := context.Params.Require(model.Name())
+		if err != nil {
+			return nil, err
+		}
+		if context.Params[model.Name()], err = input.Permit(permitted); err != nil {
+			return nil, err
+		}
+		return processAction(action, context)
+	}
+
+	if operation == "update" {
+		resolve = decodeID(model, node, resolve)
 	}
 
 	return &graphql.Field{
-		Name:    operation + strings.Title(model.Name()),
+		Name:    name,
 		Args:    args,
 		Type:    output,
-		Resolve: newResolveFunc(action),
+		Resolve: resolve,
 	}
 }
 
 func (m *Mapper) newDestroyAction(
-	model actioncontroller.AbstractModel, output graphql.Output, action actioncontroller.Action,
+	model actioncontroller.AbstractModel, node bool, output graphql.Output, action actioncontroller.Action,
 ) *graphql.Field {
+	name := "delete" + strings.Title(model.Name())
 	return &graphql.Field{
-		Name:    "delete" + strings.Title(model.Name()),
-		Args:    m.primaryKey(model),
+		Name:    name,
+		Args:    m.primaryKey(model, node),
 		Type:    output,
-		Resolve: newResolveFunc(action),
+		Resolve: decodeID(model, node, newResolveFunc(m.enclose(name, action))),
 	}
 }
 
-func (m *Mapper) Map() (http.Handler, error) {
-	queries := make(graphql.Fields)
-	mutations := make(graphql.Fields)
+// primaryKeyOf converts the identifier to the type of the primary key.
+// Identifiers could be global identifiers of the nodes, and numbers of
+// the JSON representation are decoded as floats.
+func primaryKeyOf(rel *activerecord.Relation, id interface{}) (interface{}, error) {
+	if s, ok := id.(string); ok {
+		if typename, localID, err := internal.FromGlobalID(s); err == nil && typename == rel.Name() {
+			id = localID
+		}
+	}
+	if rel.AttributeForInspect(rel.PrimaryKey()).CastType() != activerecord.Int {
+		return id, nil
+	}
+
+	switch v := id.(type) {
+	case float64:
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Errorf("invalid primary key %q of %s", v, rel.Name())
+		}
+		return i, nil
+	}
+	return id, nil
+}
+
+// find returns the record processed by the show action as a hash, when the
+// record does not exist, it returns nil. Records are fetched through the
+// action, so filters, scopes and authorization of the action apply.
+func (m *Mapper) find(
+	ctx context.Context, rel *activerecord.Relation, output *graphql.Object,
+	show actioncontroller.Action, id interface{},
+) (interface{}, error) {
+	id, err := primaryKeyOf(rel, id)
+	if err != nil {
+		return nil, err
+	}
+
+	params := actioncontroller.Parameters{rel.PrimaryKey(): id}
+	result, err := processAction(show, newActionContext(ctx, params))
+	if err != nil {
+		if _, ok := err.(activerecord.ErrRecordNotFound); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var h map[string]interface{}
+	switch result := result.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		h = result
+	case activesupport.Hash:
+		h = result
+	default:
+		return nil, errors.Errorf("%T is not a hash of %s", result, rel.Name())
+	}
+
+	// Entities and nodes are resolved as hashes, so the type of the record
+	// is stored within the hash.
+	h = internal.CopyMap(h)
+	h["__typename"] = output.Name()
+	return h, nil
+}
+
+// isTypeOf returns a function, that returns true for the hashes of the
+// records found by the find method.
+func (m *Mapper) isTypeOf(output *graphql.Object) func(value interface{}) bool {
+	return func(value interface{}) bool {
+		h, ok := value.(map[string]interface{})
+		return ok && h["__typename"] == output.Name()
+	}
+}
+
+// newEntity creates a federated entity resolved by the primary key of
+// the relation through the show action.
+func (m *Mapper) newEntity(
+	rel *activerecord.Relation, output *graphql.Object, show actioncontroller.Action,
+) internal.Entity {
+	pk := rel.PrimaryKey()
+
+	resolve := func(ctx context.Context, repr map[string]interface{}) (interface{}, error) {
+		return m.find(ctx, rel, output, show, repr[pk])
+	}
+
+	return internal.Entity{
+		Object:   output,
+		Keys:     []internal.EntityKey{{Fields: pk, Resolve: resolve}},
+		IsTypeOf: m.isTypeOf(output),
+	}
+}
+
+// newNode creates a Relay node, the node is fetched by the local identifier
+// of the global identifier through the show action.
+func (m *Mapper) newNode(
+	rel *activerecord.Relation, output *graphql.Object, show actioncontroller.Action,
+) internal.NodeType {
+	return internal.NodeType{
+		Name:   rel.Name(),
+		Object: output,
+		Fetch: func(ctx context.Context, id string) (interface{}, error) {
+			return m.find(ctx, rel, output, show, id)
+		},
+		IsTypeOf: m.isTypeOf(output),
+	}
+}
+
+// showAction returns the show action of the controller, or nil, when the
+// controller does not define it.
+func showAction(controller actioncontroller.AbstractController) actioncontroller.Action {
+	for _, action := range controller.ActionMethods() {
+		if action.ActionName() == actioncontroller.ActionShow {
+			return action
+		}
+	}
+	return nil
+}
+
+// MapSchema registers objects, queries and mutations of the mapped resources
+// in the schema builder. Output objects of the resources are registered by
+// their names, so other contributors of the schema could reference them.
+func (m *Mapper) MapSchema(b *internal.SchemaBuilder) error {
+	if m.Federation {
+		b.Federation = true
+	}
 
 	for _, resource := range m.resources {
+		rel, isRelation := resource.model.(*activerecord.Relation)
+
+		// Resources backed by activerecord implement the Relay "Node"
+		// interface, the "id" field is replaced with the global identifier.
+		// Nodes and entities are fetched through the show action, so only
+		// resources with the show action are refetchable.
+		show := showAction(resource.controller)
+		isNode := isRelation && show != nil
+		if isNode {
+			show = m.enclose(rel.Name(), show)
+		}
+
+		var interfaces []*graphql.Interface
+		if isNode {
+			interfaces = append(interfaces, b.Nodes.Interface())
+		}
+
 		output := objconv(
-			strings.Title(resource.model.Name()), resource.model.AttributesForInspect(),
+			strings.Title(resource.model.Name()), resource.model.AttributesForInspect(), interfaces...,
 		)
+		if err := b.AddOutput(output.Name(), graphql.NewNonNull(output)); err != nil {
+			return err
+		}
+		if isNode {
+			pk := rel.PrimaryKey()
+			output.AddFieldConfig("id", b.Nodes.IDField(rel.Name(), func(p graphql.ResolveParams) (interface{}, error) {
+				p.Info.FieldName = pk
+				return graphql.DefaultResolveFn(p)
+			}))
+			b.Nodes.Add(m.newNode(rel, output, show))
+		}
+		if isNode && m.Federation {
+			b.Entities = append(b.Entities, m.newEntity(rel, output, show))
+		}
 
 		for _, action := range resource.controller.ActionMethods() {
+			var err error
 			switch action.ActionName() {
 			case actioncontroller.ActionIndex:
-				query := m.newIndexAction(resource.model, output, action)
-				queries[query.Name] = query
+				var query *graphql.Field
+				if query, err = m.newIndexAction(b, resource, output, action); err == nil {
+					err = b.AddQuery(query.Name, query)
+				}
 			case actioncontroller.ActionShow:
-				query := m.newShowAction(resource.model, output, action)
-				queries[query.Name] = query
+				query := m.newShowAction(resource.model, isNode, output, action)
+				err = b.AddQuery(query.Name, query)
 			case actioncontroller.ActionUpdate, actioncontroller.ActionCreate:
-				mutation := m.newUpdateAction(action.ActionName(), resource.model, output, action)
-				mutations[mutation.Name] = mutation
+				mutation := m.newUpdateAction(action.ActionName(), resource.model, isNode, output, action)
+				err = b.AddMutation(mutation.Name, mutation)
 			case actioncontroller.ActionDestroy:
-				mutation := m.newDestroyAction(resource.model, output, action)
-				mutations[mutation.Name] = mutation
+				mutation := m.newDestroyAction(resource.model, isNode, output, action)
+				err = b.AddMutation(mutation.Name, mutation)
 			default:
 				// println("consider registering non-canonical action?")
 			}
+			if err != nil {
+				return err
+			}
 		}
 	}
 
-	for _, matching := range m.matchings {
-		switch matching.operation {
+	for _, alternatives := range m.groupMatchings() {
+		field, err := m.newMatchAction(alternatives)
+		if err != nil {
+			return err
+		}
+
+		switch alternatives[0].operation {
 		case OperationQuery:
+			err = b.AddQuery(field.Name, field)
 		case OperationMutation:
-			mutations[matching.name] = m.newAction(
-				matching.name,
-				matching.constraints.Request.Attributes,
-				matching.constraints.Response.Attributes,
-				matching.action,
-			)
+			err = b.AddMutation(field.Name, field)
+		}
+		if err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	var mutation *graphql.Object
-	if len(mutations) > 0 {
-		mutation = graphql.NewObject(graphql.ObjectConfig{
-			Name: "Mutation", Fields: mutations,
-		})
+// Schema returns the GraphQL schema of the mapped resources.
+func (m *Mapper) Schema() (graphql.Schema, error) {
+	b := internal.NewSchemaBuilder()
+	if err := m.MapSchema(b); err != nil {
+		return graphql.Schema{}, err
 	}
-	query := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Query", Fields: queries,
-	})
+	return b.Build(graphql.SchemaConfig{})
+}
 
-	schema, err := graphql.NewSchema(graphql.SchemaConfig{
-		Query: query, Mutation: mutation,
-	})
+func (m *Mapper) Map() (http.Handler, error) {
+	schema, err := m.Schema()
 	if err != nil {
 		return nil, err
 	}
@@ -278,7 +715,12 @@ func (m *Mapper) Map() (http.Handler, error) {
 		GraphiQL: true,
 	})
 
+	// Put the request into the context, so matchers of actions could
+	// choose the action by the request.
 	mux := http.NewServeMux()
-	mux.Handle("/graphql", h)
+	mux.HandleFunc("/graphql", func(rw http.ResponseWriter, r *http.Request) {
+		ctx := actioncontroller.WithRequest(r.Context(), &actioncontroller.Request{Header: r.Header})
+		h.ServeHTTP(rw, r.WithContext(ctx))
+	})
 	return mux, nil
 }
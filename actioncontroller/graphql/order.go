@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"strings"
+
+	"github.com/activegraph/activegraph/actioncontroller"
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/internal"
+
+	"github.com/graphql-go/graphql"
+)
+
+// OrderArg is the name of the argument of index queries, that orders the
+// list of resources.
+const OrderArg = "orderBy"
+
+// directionconv returns the enum of order directions shared by all orders.
+func directionconv(b *internal.SchemaBuilder) graphql.Input {
+	const name = "OrderDirection"
+	if input, ok := b.Inputs[name]; ok {
+		return input
+	}
+
+	input := graphql.NewEnum(graphql.EnumConfig{
+		Name: name,
+		Values: graphql.EnumValueConfigMap{
+			string(activerecord.Asc):  &graphql.EnumValueConfig{Value: string(activerecord.Asc)},
+			string(activerecord.Desc): &graphql.EnumValueConfig{Value: string(activerecord.Desc)},
+		},
+	})
+	b.Inputs[name] = input
+	return input
+}
+
+// sortableFields returns fields the controller allows to order by, all
+// attributes of the relation are sortable by default.
+func sortableFields(
+	rel *activerecord.Relation, controller actioncontroller.AbstractController,
+) ([]string, error) {
+	var fields []string
+	if c, ok := controller.(interface{ SortableFields() []string }); ok {
+		fields = c.SortableFields()
+	}
+	if len(fields) == 0 {
+		return rel.AttributeNames(), nil
+	}
+
+	// Ensure all fields are known to the relation, so orders submitted by
+	// clients always compile into the query.
+	for _, field := range fields {
+		order := map[string]interface{}{"field": field}
+		if _, err := rel.OrderBy([]interface{}{order}); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// orderconv returns the order input object of the relation, the field of
+// the order is an enum of the sortable fields.
+func orderconv(b *internal.SchemaBuilder, rel *activerecord.Relation, fields []string) graphql.Input {
+	name := strings.Title(rel.Name()) + "Order"
+	if input, ok := b.Inputs[name]; ok {
+		return input
+	}
+
+	values := make(graphql.EnumValueConfigMap, len(fields))
+	for _, field := range fields {
+		name := strings.ToUpper(strings.ReplaceAll(field, ".", "_"))
+		values[name] = &graphql.EnumValueConfig{Value: field}
+	}
+
+	input := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: name,
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.NewEnum(graphql.EnumConfig{
+					Name: name + "Field", Values: values,
+				})),
+			},
+			"direction": &graphql.InputObjectFieldConfig{
+				Type: directionconv(b), DefaultValue: string(activerecord.Asc),
+			},
+		},
+	})
+	b.Inputs[name] = input
+	return input
+}
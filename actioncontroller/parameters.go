@@ -1,37 +1,339 @@
 package actioncontroller
 
 import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/activegraph/activegraph/activerecord"
 )
 
+// ErrParameterMissing is returned, when the required parameter is missing
+// or the value is empty.
+type ErrParameterMissing struct {
+	Key string
+}
+
+func (e ErrParameterMissing) Error() string {
+	return fmt.Sprintf("param is missing or the value is empty: %s", e.Key)
+}
+
+// ErrUnpermittedParameters is returned on filtering of the parameters with
+// unpermitted keys, when the policy is UnpermittedRaise.
+type ErrUnpermittedParameters struct {
+	Keys []string
+}
+
+func (e ErrUnpermittedParameters) Error() string {
+	return fmt.Sprintf("found unpermitted parameters: %s", strings.Join(e.Keys, ", "))
+}
+
+// ErrInvalidParameter is returned, when the value of the parameter cannot
+// be casted to the type of the permitted attribute.
+type ErrInvalidParameter struct {
+	Key      string
+	TypeName string
+	Value    interface{}
+}
+
+func (e ErrInvalidParameter) Error() string {
+	return fmt.Sprintf("invalid value '%v' of %s parameter %q", e.Value, e.TypeName, e.Key)
+}
+
 type Parameters map[string]interface{}
 
+// Get returns the nested hash of parameters with the given key, when the
+// value is missing or it is not a hash, nil is returned.
 func (p Parameters) Get(key string) Parameters {
-	val, ok := p[key]
-	if !ok {
+	return toParameters(p[key])
+}
+
+func toParameters(val interface{}) Parameters {
+	switch val := val.(type) {
+	case Parameters:
+		return val
+	case map[string]interface{}:
+		return val
+	default:
 		return nil
 	}
-	params, ok := val.(map[string]interface{})
-	if !ok {
+}
+
+// GetArray returns the nested array of hashes with the given key, when the
+// value is missing or it is not an array of hashes, nil is returned.
+func (p Parameters) GetArray(key string) []Parameters {
+	switch val := p[key].(type) {
+	case []Parameters:
+		return val
+	case []interface{}:
+		params := make([]Parameters, 0, len(val))
+		for _, v := range val {
+			h := toParameters(v)
+			if h == nil {
+				return nil
+			}
+			params = append(params, h)
+		}
+		return params
+	default:
 		return nil
 	}
-	return params
+}
+
+// Require returns the nested hash of parameters with the given key, it
+// returns ErrParameterMissing, when the value is missing or empty.
+func (p Parameters) Require(key string) (Parameters, error) {
+	params := p.Get(key)
+	if len(params) == 0 {
+		return nil, ErrParameterMissing{Key: key}
+	}
+	return params, nil
+}
+
+// Permit returns a copy of parameters with the permitted keys only. Values
+// of attributes are casted to the types of the attributes, the unpermitted
+// keys are handled according to the policy of the permitted parameters.
+func (p Parameters) Permit(permitted *StrongParameters) (Parameters, error) {
+	var unpermitted []string
+
+	params, err := permitted.filter(p, "", &unpermitted)
+	if err != nil {
+		return nil, err
+	}
+	if len(unpermitted) == 0 {
+		return params, nil
+	}
+
+	sort.Strings(unpermitted)
+	switch permitted.policy() {
+	case UnpermittedLog:
+		log.Printf("actioncontroller: %s", ErrUnpermittedParameters{Keys: unpermitted})
+	case UnpermittedRaise:
+		return nil, ErrUnpermittedParameters{Keys: unpermitted}
+	}
+	return params, nil
 }
 
 func (p Parameters) ToH() map[string]interface{} {
 	return (map[string]interface{})(p)
 }
 
+// UnpermittedPolicy defines handling of the unpermitted parameters.
+type UnpermittedPolicy int
+
+const (
+	// UnpermittedDefault uses ActionOnUnpermittedParameters policy.
+	UnpermittedDefault UnpermittedPolicy = iota
+
+	UnpermittedDrop  // drops unpermitted parameters silently.
+	UnpermittedLog   // drops and logs unpermitted parameters.
+	UnpermittedRaise // returns ErrUnpermittedParameters.
+)
+
+// ActionOnUnpermittedParameters is the policy of strong parameters with
+// the UnpermittedDefault policy.
+var ActionOnUnpermittedParameters = UnpermittedDrop
+
+// NestedParameters are permitted parameters of the nested hash or the
+// nested array of hashes.
+type NestedParameters struct {
+	Name   string
+	Array  bool
+	Params *StrongParameters
+}
+
 type StrongParameters struct {
 	Attributes []activerecord.Attribute
+
+	// Nested are permitted nested hashes and arrays of hashes.
+	Nested []NestedParameters
+
+	// Unpermitted is the policy of unpermitted keys, the policy of the
+	// top-level parameters applies to keys of nested parameters.
+	Unpermitted UnpermittedPolicy
 }
 
+// Permit returns parameters with attributes and nested parameters of the
+// given names.
 func (p *StrongParameters) Permit(names ...string) *StrongParameters {
-	attrs := make([]activerecord.Attribute, 0, len(names))
+	permitted := make(map[string]bool, len(names))
+	for _, name := range names {
+		permitted[name] = true
+	}
+
+	params := &StrongParameters{Unpermitted: p.Unpermitted}
 	for _, attr := range p.Attributes {
-		attrs = append(attrs, attr)
+		if permitted[attr.AttributeName()] {
+			params.Attributes = append(params.Attributes, attr)
+		}
+	}
+	for _, nested := range p.Nested {
+		if permitted[nested.Name] {
+			params.Nested = append(params.Nested, nested)
+		}
+	}
+	return params
+}
+
+// PermitHash returns parameters, that additionally permit the nested hash.
+func (p *StrongParameters) PermitHash(name string, params *StrongParameters) *StrongParameters {
+	return p.permitNested(NestedParameters{Name: name, Params: params})
+}
+
+// PermitArray returns parameters, that additionally permit the nested
+// array of hashes.
+func (p *StrongParameters) PermitArray(name string, params *StrongParameters) *StrongParameters {
+	return p.permitNested(NestedParameters{Name: name, Array: true, Params: params})
+}
+
+func (p *StrongParameters) permitNested(nested NestedParameters) *StrongParameters {
+	return &StrongParameters{
+		Attributes:  p.Attributes,
+		Nested:      append(append([]NestedParameters(nil), p.Nested...), nested),
+		Unpermitted: p.Unpermitted,
+	}
+}
+
+// merge returns parameters, that permit attributes and nested parameters
+// of both parameters.
+func (p *StrongParameters) merge(p1 *StrongParameters) *StrongParameters {
+	if p == nil {
+		return p1
+	}
+	params := &StrongParameters{
+		Attributes:  append(append([]activerecord.Attribute(nil), p.Attributes...), p1.Attributes...),
+		Nested:      append(append([]NestedParameters(nil), p.Nested...), p1.Nested...),
+		Unpermitted: p.Unpermitted,
+	}
+	if p1.Unpermitted != UnpermittedDefault {
+		params.Unpermitted = p1.Unpermitted
+	}
+	return params
+}
+
+// attributes returns permitted attributes, p could be nil.
+func (p *StrongParameters) attributes() []activerecord.Attribute {
+	if p == nil {
+		return nil
+	}
+	return p.Attributes
+}
+
+func (p *StrongParameters) policy() UnpermittedPolicy {
+	if p.Unpermitted == UnpermittedDefault {
+		return ActionOnUnpermittedParameters
+	}
+	return p.Unpermitted
+}
+
+func (p *StrongParameters) filter(
+	params Parameters, prefix string, unpermitted *[]string,
+) (Parameters, error) {
+	var (
+		filtered = make(Parameters, len(params))
+		attrs    = make(map[string]activerecord.Attribute, len(p.Attributes))
+		nested   = make(map[string]NestedParameters, len(p.Nested))
+	)
+	for _, attr := range p.Attributes {
+		attrs[attr.AttributeName()] = attr
+	}
+	for _, n := range p.Nested {
+		nested[n.Name] = n
+	}
+
+	for key, val := range params {
+		if attr, ok := attrs[key]; ok {
+			v, err := cast(attr, val)
+			if err != nil {
+				return nil, ErrInvalidParameter{Key: prefix + key, TypeName: attr.CastType(), Value: val}
+			}
+			filtered[key] = v
+			continue
+		}
+
+		n, ok := nested[key]
+		if !ok {
+			*unpermitted = append(*unpermitted, prefix+key)
+			continue
+		}
+		if val == nil {
+			filtered[key] = nil
+			continue
+		}
+
+		if !n.Array {
+			h := params.Get(key)
+			if h == nil {
+				return nil, ErrInvalidParameter{Key: prefix + key, TypeName: "hash", Value: val}
+			}
+			v, err := n.Params.filter(h, prefix+key+".", unpermitted)
+			if err != nil {
+				return nil, err
+			}
+			filtered[key] = v
+			continue
+		}
+
+		hashes := params.GetArray(key)
+		if hashes == nil {
+			return nil, ErrInvalidParameter{Key: prefix + key, TypeName: "array", Value: val}
+		}
+		values := make([]Parameters, 0, len(hashes))
+		for i, h := range hashes {
+			v, err := n.Params.filter(h, prefix+key+"."+strconv.Itoa(i)+".", unpermitted)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		filtered[key] = values
+	}
+	return filtered, nil
+}
+
+// cast converts the value to the cast type of the attribute, numbers of the
+// JSON representation and strings are accepted as integers.
+func cast(attr activerecord.Attribute, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	switch attr.CastType() {
+	case activerecord.Int:
+		switch v := val.(type) {
+		case int:
+			return v, nil
+		case int32:
+			return int(v), nil
+		case int64:
+			return int(v), nil
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, activerecord.ErrInvalidValue{TypeName: activerecord.Int, Value: val}
+			}
+			return int(v), nil
+		case string:
+			return strconv.Atoi(v)
+		}
+	case activerecord.String:
+		if v, ok := val.(string); ok {
+			return v, nil
+		}
+	default:
+		return val, nil
+	}
+	return nil, activerecord.ErrInvalidValue{TypeName: attr.CastType(), Value: val}
+}
+
+// PermittedParameters returns parameters permitted by the action.
+func PermittedParameters(action Action) *StrongParameters {
+	if a, ok := action.(interface{ ActionPermitted() *StrongParameters }); ok {
+		return a.ActionPermitted()
 	}
-	return &StrongParameters{Attributes: attrs}
+	return &StrongParameters{Attributes: action.ActionRequest()}
 }
 
 func Require(rel *activerecord.Relation) *StrongParameters {
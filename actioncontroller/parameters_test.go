@@ -0,0 +1,208 @@
+package actioncontroller_test
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/actioncontroller"
+	"github.com/activegraph/activegraph/activerecord"
+)
+
+func TestParameters_Permit(t *testing.T) {
+	tag := &actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+		activerecord.StringAttr{Name: "name"},
+	}}
+	permitted := (&actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+		activerecord.StringAttr{Name: "title"},
+		activerecord.IntAttr{Name: "year"},
+		activerecord.IntAttr{Name: "pages"},
+	}}).Permit("title", "year").
+		PermitHash("author", tag).
+		PermitArray("tags", tag)
+
+	params := actioncontroller.Parameters{
+		"novel": map[string]interface{}{
+			"title":  "Dune",
+			"year":   float64(1965),
+			"pages":  412,
+			"author": map[string]interface{}{"name": "Frank Herbert", "born": 1920},
+			"tags":   []interface{}{map[string]interface{}{"name": "classic", "id": 1}},
+		},
+	}
+
+	novel, err := params.Require("novel")
+	require.NoError(t, err)
+
+	_, err = params.Require("book")
+	assert.EqualError(t, err, "param is missing or the value is empty: book")
+
+	tests := []struct {
+		name   string
+		policy actioncontroller.UnpermittedPolicy
+		want   actioncontroller.Parameters
+		log    string
+		err    string
+	}{
+		{
+			name:   "Drop",
+			policy: actioncontroller.UnpermittedDrop,
+			want: actioncontroller.Parameters{
+				"title":  "Dune",
+				"year":   1965,
+				"author": actioncontroller.Parameters{"name": "Frank Herbert"},
+				"tags":   []actioncontroller.Parameters{{"name": "classic"}},
+			},
+		},
+		{
+			name:   "Log",
+			policy: actioncontroller.UnpermittedLog,
+			want: actioncontroller.Parameters{
+				"title":  "Dune",
+				"year":   1965,
+				"author": actioncontroller.Parameters{"name": "Frank Herbert"},
+				"tags":   []actioncontroller.Parameters{{"name": "classic"}},
+			},
+			log: "actioncontroller: found unpermitted parameters: author.born, pages, tags.0.id\n",
+		},
+		{
+			name:   "Raise",
+			policy: actioncontroller.UnpermittedRaise,
+			err:    "found unpermitted parameters: author.born, pages, tags.0.id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, flags := log.Writer(), log.Flags()
+			defer log.SetOutput(output)
+			defer log.SetFlags(flags)
+
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+			log.SetFlags(0)
+
+			p := *permitted
+			p.Unpermitted = tt.policy
+
+			params, err := novel.Permit(&p)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, params)
+			assert.Equal(t, tt.log, buf.String())
+		})
+	}
+
+	_, err = actioncontroller.Parameters{"year": "MCMLXV"}.Permit(permitted)
+	assert.EqualError(t, err, `invalid value 'MCMLXV' of int parameter "year"`)
+}
+
+func TestParameters_PermitNestedArray(t *testing.T) {
+	chapter := &actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+		activerecord.StringAttr{Name: "title"},
+		activerecord.IntAttr{Name: "pages"},
+	}}
+	part := (&actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+		activerecord.StringAttr{Name: "name"},
+	}}).PermitArray("chapters", chapter)
+	permitted := (&actioncontroller.StrongParameters{
+		Unpermitted: actioncontroller.UnpermittedRaise,
+	}).PermitArray("parts", part)
+
+	params, err := actioncontroller.Parameters{
+		"parts": []interface{}{
+			map[string]interface{}{
+				"name": "Dune",
+				"chapters": []interface{}{
+					map[string]interface{}{"title": "Arrakis", "pages": float64(40)},
+					actioncontroller.Parameters{"title": "Muad'Dib", "pages": "32"},
+				},
+			},
+			map[string]interface{}{"name": "Book Three", "chapters": nil},
+		},
+	}.Permit(permitted)
+	require.NoError(t, err)
+	assert.Equal(t, actioncontroller.Parameters{
+		"parts": []actioncontroller.Parameters{
+			{
+				"name": "Dune",
+				"chapters": []actioncontroller.Parameters{
+					{"title": "Arrakis", "pages": 40},
+					{"title": "Muad'Dib", "pages": 32},
+				},
+			},
+			{"name": "Book Three", "chapters": nil},
+		},
+	}, params)
+
+	_, err = actioncontroller.Parameters{
+		"parts": []interface{}{
+			map[string]interface{}{"chapters": []interface{}{map[string]interface{}{"words": 1}}},
+		},
+	}.Permit(permitted)
+	assert.EqualError(t, err, "found unpermitted parameters: parts.0.chapters.0.words")
+}
+
+func TestParameters_PermitInvalid(t *testing.T) {
+	tag := &actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+		activerecord.IntAttr{Name: "rank"},
+	}}
+	permitted := (&actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+		activerecord.StringAttr{Name: "title"},
+		activerecord.IntAttr{Name: "year"},
+	}}).PermitHash("author", tag).PermitArray("tags", tag)
+
+	tests := []struct {
+		name   string
+		params actioncontroller.Parameters
+		err    actioncontroller.ErrInvalidParameter
+	}{
+		{
+			name:   "String",
+			params: actioncontroller.Parameters{"title": 1965},
+			err:    actioncontroller.ErrInvalidParameter{Key: "title", TypeName: "string", Value: 1965},
+		},
+		{
+			name:   "Fraction",
+			params: actioncontroller.Parameters{"year": 1965.5},
+			err:    actioncontroller.ErrInvalidParameter{Key: "year", TypeName: "int", Value: 1965.5},
+		},
+		{
+			name:   "Hash",
+			params: actioncontroller.Parameters{"author": "Frank Herbert"},
+			err:    actioncontroller.ErrInvalidParameter{Key: "author", TypeName: "hash", Value: "Frank Herbert"},
+		},
+		{
+			name:   "NestedHash",
+			params: actioncontroller.Parameters{"author": map[string]interface{}{"rank": "first"}},
+			err:    actioncontroller.ErrInvalidParameter{Key: "author.rank", TypeName: "int", Value: "first"},
+		},
+		{
+			name:   "Array",
+			params: actioncontroller.Parameters{"tags": []interface{}{"classic"}},
+			err: actioncontroller.ErrInvalidParameter{
+				Key: "tags", TypeName: "array", Value: []interface{}{"classic"},
+			},
+		},
+		{
+			name: "NestedArray",
+			params: actioncontroller.Parameters{"tags": []interface{}{
+				map[string]interface{}{"rank": 1}, map[string]interface{}{"rank": "last"},
+			}},
+			err: actioncontroller.ErrInvalidParameter{Key: "tags.1.rank", TypeName: "int", Value: "last"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.params.Permit(permitted)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
@@ -1,6 +1,34 @@
 package actioncontroller
 
+import (
+	"context"
+	"net/http"
+)
+
+// Request is the request processed by the action, matchers of constraints
+// choose the action by the request.
 type Request struct {
+	// Header contains header fields of the underlying HTTP request.
+	Header http.Header
+
+	// Params are parameters of the action.
+	Params Parameters
+}
+
+type requestKey struct{}
+
+// WithRequest returns a copy of the context, that carries the request.
+func WithRequest(ctx context.Context, r *Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+// RequestFromContext returns the request carried by the context, when the
+// context does not carry a request, an empty request is returned.
+func RequestFromContext(ctx context.Context) *Request {
+	if r, ok := ctx.Value(requestKey{}).(*Request); ok {
+		return r
+	}
+	return new(Request)
 }
 
 type Response struct {
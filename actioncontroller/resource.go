@@ -0,0 +1,235 @@
+package actioncontroller
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activesupport"
+)
+
+// ErrRecordInvalid is returned by actions of the resource controller, when
+// the record does not pass validations.
+type ErrRecordInvalid struct {
+	RecordName string
+	Err        error
+}
+
+func (e ErrRecordInvalid) Error() string {
+	return fmt.Sprintf("validation of %s failed: %s", e.RecordName, e.Err)
+}
+
+func (e ErrRecordInvalid) Unwrap() error {
+	return e.Err
+}
+
+// ScopeFunc returns the relation of records accessible by actions, e.g.
+// records owned by the current user.
+type ScopeFunc func(ctx *Context, rel *activerecord.Relation) *activerecord.Relation
+
+// AuthorizeFunc returns an error, when the action is not allowed.
+type AuthorizeFunc func(ctx *Context, actionName string) error
+
+// SaveFunc is executed before the validated record is saved, the returned
+// error aborts the action.
+type SaveFunc func(ctx *Context, rec *activerecord.ActiveRecord) error
+
+// ResourceOption configures the resource controller.
+type ResourceOption func(*resourceController)
+
+// WithScope sets the scope of records of all actions.
+func WithScope(scope ScopeFunc) ResourceOption {
+	return func(rc *resourceController) {
+		rc.scope = scope
+	}
+}
+
+// WithAuthorize sets the authorization of actions, it is executed as
+// the "authorize" before filter.
+func WithAuthorize(authorize AuthorizeFunc) ResourceOption {
+	return func(rc *resourceController) {
+		rc.authorize = authorize
+	}
+}
+
+// WithBeforeSave sets the hook executed before creation and update of
+// the record.
+func WithBeforeSave(beforeSave SaveFunc) ResourceOption {
+	return func(rc *resourceController) {
+		rc.beforeSave = beforeSave
+	}
+}
+
+// WithPermitted sets names of attributes permitted on creation and update,
+// by default all attributes, but the primary key, are permitted.
+func WithPermitted(attrNames ...string) ResourceOption {
+	return func(rc *resourceController) {
+		rc.permitted = attrNames
+	}
+}
+
+// WithSortable sets fields the index action could be ordered by.
+func WithSortable(fields ...string) ResourceOption {
+	return func(rc *resourceController) {
+		rc.sortable = fields
+	}
+}
+
+type resourceController struct {
+	rel        *activerecord.Relation
+	scope      ScopeFunc
+	authorize  AuthorizeFunc
+	beforeSave SaveFunc
+	permitted  []string
+	sortable   []string
+}
+
+// ResourceController returns a controller with canonical actions of the
+// relation. Actions of the controller could be overridden by inheritance:
+//
+//	books := actioncontroller.New(func(c *actioncontroller.C) {
+//		c.Inherit(actioncontroller.ResourceController(Book))
+//		c.Destroy(destroyBook)
+//	})
+func ResourceController(rel *activerecord.Relation, opts ...ResourceOption) *ActionController {
+	rc := resourceController{rel: rel}
+	for _, opt := range opts {
+		opt(&rc)
+	}
+	if rc.permitted == nil {
+		for _, attrName := range rel.AttributeNames() {
+			if attrName != rel.PrimaryKey() {
+				rc.permitted = append(rc.permitted, attrName)
+			}
+		}
+	}
+
+	return New(func(c *C) {
+		c.Permit(rel.AttributesForInspect(rc.permitted...), ActionCreate, ActionUpdate)
+		c.Filterable()
+		c.Sortable(rc.sortable...)
+
+		if rc.authorize != nil {
+			for _, actionName := range []string{
+				ActionIndex, ActionShow, ActionCreate, ActionUpdate, ActionDestroy,
+			} {
+				c.BeforeFilter("authorize", rc.authorizeAction(actionName), Only(actionName))
+			}
+		}
+
+		c.Index(rc.index)
+		c.Show(rc.show)
+		c.Create(rc.create)
+		c.Update(rc.update)
+		c.Destroy(rc.destroy)
+	})
+}
+
+func (rc *resourceController) authorizeAction(actionName string) AnonymousAction {
+	return func(ctx *Context) Result {
+		if err := rc.authorize(ctx, actionName); err != nil {
+			return hashResult{activesupport.Err(err)}
+		}
+		return nil
+	}
+}
+
+func (rc *resourceController) relation(ctx *Context) *activerecord.Relation {
+	rel := rc.rel.WithContext(ctx)
+	if rc.scope != nil {
+		rel = rc.scope(ctx, rel)
+	}
+	return rel
+}
+
+// find returns the record of the scope by the primary key.
+func (rc *resourceController) find(ctx *Context) activerecord.Result {
+	var (
+		pk = rc.rel.PrimaryKey()
+		id = ctx.Params[pk]
+	)
+	records, err := rc.relation(ctx).Where(pk, id).Limit(1).ToA()
+	if err != nil {
+		return activerecord.Err(err)
+	}
+	if len(records) == 0 {
+		return activerecord.Err(activerecord.ErrRecordNotFound{PrimaryKey: pk, ID: id})
+	}
+	return activerecord.Ok(records[0])
+}
+
+// save validates the record and executes the hook before saving, values
+// are validated on assignment, but the hook could change them.
+func (rc *resourceController) save(
+	ctx *Context, rec *activerecord.ActiveRecord, save func() (*activerecord.ActiveRecord, error),
+) activerecord.Result {
+	if err := rec.Validate(); err != nil {
+		return activerecord.Err(ErrRecordInvalid{RecordName: rc.rel.Name(), Err: err})
+	}
+	if rc.beforeSave != nil {
+		if err := rc.beforeSave(ctx, rec); err != nil {
+			return activerecord.Err(err)
+		}
+	}
+	return activerecord.Return(save())
+}
+
+// hashResult is the result of the resource action, records are converted
+// to hashes.
+type hashResult struct {
+	activesupport.Result
+}
+
+func (r hashResult) Execute(*Context) (interface{}, error) {
+	if r.IsErr() {
+		return nil, r.Err()
+	}
+
+	switch val := r.Ok().(type) {
+	case activesupport.HashConverter:
+		return val.ToHash(), nil
+	case activesupport.HashArrayConverter:
+		return val.ToHashArray(), nil
+	default:
+		return nil, errors.Errorf("%T does not support hash conversion", val)
+	}
+}
+
+func (rc *resourceController) index(ctx *Context) Result {
+	rel, err := rc.relation(ctx).Filter(ctx.Params.Get("where"))
+	if err != nil {
+		return hashResult{activesupport.Err(err)}
+	}
+	orders, _ := ctx.Params["orderBy"].([]interface{})
+	if rel, err = rel.OrderBy(orders); err != nil {
+		return hashResult{activesupport.Err(err)}
+	}
+	return hashResult{activesupport.Return(rel.ToA())}
+}
+
+func (rc *resourceController) show(ctx *Context) Result {
+	return hashResult{rc.find(ctx)}
+}
+
+func (rc *resourceController) create(ctx *Context) Result {
+	rec, err := rc.relation(ctx).Initialize(ctx.Params.Get(rc.rel.Name()))
+	if err != nil {
+		return hashResult{activesupport.Err(ErrRecordInvalid{RecordName: rc.rel.Name(), Err: err})}
+	}
+	return hashResult{rc.save(ctx, rec, rec.Insert)}
+}
+
+func (rc *resourceController) update(ctx *Context) Result {
+	return hashResult{rc.find(ctx).AndThen(func(v interface{}) activesupport.Result {
+		rec := v.(*activerecord.ActiveRecord)
+		if err := rec.AssignAttributes(ctx.Params.Get(rc.rel.Name())); err != nil {
+			return activesupport.Err(ErrRecordInvalid{RecordName: rc.rel.Name(), Err: err})
+		}
+		return rc.save(ctx, rec, rec.Update)
+	})}
+}
+
+func (rc *resourceController) destroy(ctx *Context) Result {
+	return hashResult{rc.find(ctx).Delete()}
+}
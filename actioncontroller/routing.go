@@ -22,10 +22,29 @@ type Matcher interface {
 	Matches(*Request) bool
 }
 
+// MatcherFunc is an adapter to use ordinary functions as matchers.
+type MatcherFunc func(*Request) bool
+
+func (fn MatcherFunc) Matches(r *Request) bool {
+	return fn(r)
+}
+
+// MatchHeader returns a matcher of requests with the header field of the
+// given value, e.g. the version of the API.
+func MatchHeader(key, value string) Matcher {
+	return MatcherFunc(func(r *Request) bool {
+		return r.Header.Get(key) == value
+	})
+}
+
 type Constraints struct {
 	Request  *StrongParameters
 	Response *StrongParameters
-	Match    Matcher
+
+	// Match chooses the action among alternative actions of the same path,
+	// it is evaluated on each request. Action without matcher is chosen,
+	// when none of the matchers match the request.
+	Match Matcher
 }
 
 type Mapper interface {
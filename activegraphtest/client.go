@@ -0,0 +1,165 @@
+// Package activegraphtest provides utilities for integration testing of
+// GraphQL services built with activegraph.
+//
+// A Client executes operations against the HTTP handler in-process and
+// returns responses with assertion helpers:
+//
+//	client := activegraphtest.NewController(t, &controller)
+//	resp := client.Do(`{ posts { title } }`)
+//	resp.AssertNoErrors()
+//	resp.AssertData("posts.0.title", "Hello, world")
+//	resp.AssertGolden()
+//
+// Database establishes the primary connection to an isolated sqlite
+// database, all changes made within the test are rolled back on its
+// completion.
+package activegraphtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/activegraph/activegraph"
+	"github.com/activegraph/activegraph/actioncontroller"
+)
+
+// DefaultPath is the default path of GraphQL requests.
+const DefaultPath = "/graphql"
+
+// Client executes GraphQL operations against the HTTP handler.
+type Client struct {
+	// Path is the URL path of GraphQL requests, DefaultPath by default.
+	Path string
+
+	// Header is sent with each request of the client.
+	Header http.Header
+
+	t       testing.TB
+	handler http.Handler
+
+	mu      sync.Mutex
+	goldens map[string]int
+}
+
+// New returns a new client that executes operations against the handler.
+func New(t testing.TB, handler http.Handler) *Client {
+	return &Client{
+		Path:    DefaultPath,
+		Header:  make(http.Header),
+		t:       t,
+		handler: handler,
+		goldens: make(map[string]int),
+	}
+}
+
+// NewController returns a new client of the controller.
+func NewController(t testing.TB, c *activegraph.Controller) *Client {
+	return New(t, c.HandleHTTP())
+}
+
+// NewMapper returns a new client of the resources mapped by the mapper.
+func NewMapper(t testing.TB, m actioncontroller.Mapper) *Client {
+	t.Helper()
+	handler, err := m.Map()
+	if err != nil {
+		t.Fatalf("activegraphtest: map resources: %v", err)
+	}
+	return New(t, handler)
+}
+
+// NewApplication returns a new client of the application.
+func NewApplication(t testing.TB, app *activegraph.Application) *Client {
+	t.Helper()
+	handler, err := app.Handler()
+	if err != nil {
+		t.Fatalf("activegraphtest: application handler: %v", err)
+	}
+	return New(t, handler)
+}
+
+// Option configures the request of the operation.
+type Option func(*operation)
+
+type operation struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+
+	header http.Header
+}
+
+// Variables sets variables of the operation.
+func Variables(vars map[string]interface{}) Option {
+	return func(op *operation) { op.Variables = vars }
+}
+
+// OperationName selects the operation to execute from the document.
+func OperationName(name string) Option {
+	return func(op *operation) { op.OperationName = name }
+}
+
+// Header sets the header of the request, it overrides the header of the
+// client.
+func Header(key, value string) Option {
+	return func(op *operation) { op.header.Set(key, value) }
+}
+
+// Do executes the GraphQL operation and returns the response. The test fails
+// when the response is not a valid GraphQL response.
+func (c *Client) Do(query string, opts ...Option) *Response {
+	c.t.Helper()
+
+	op := operation{Query: query, header: make(http.Header)}
+	for key, values := range c.Header {
+		op.header[key] = values
+	}
+	for _, opt := range opts {
+		opt(&op)
+	}
+
+	body, err := json.Marshal(op)
+	if err != nil {
+		c.t.Fatalf("activegraphtest: encode operation: %v", err)
+	}
+
+	path := c.Path
+	if path == "" {
+		path = DefaultPath
+	}
+
+	r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+	for key, values := range op.header {
+		r.Header[key] = values
+	}
+
+	rw := httptest.NewRecorder()
+	c.handler.ServeHTTP(rw, r)
+
+	resp := Response{
+		StatusCode: rw.Code,
+		Header:     rw.Header(),
+		Body:       rw.Body.Bytes(),
+		client:     c,
+	}
+	if err = json.Unmarshal(resp.Body, &resp); err != nil {
+		c.t.Fatalf("activegraphtest: decode response %q: %v", resp.Body, err)
+	}
+	return &resp
+}
+
+// golden returns the name of the next golden file of the test.
+func (c *Client) golden() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := c.t.Name()
+	n := c.goldens[name]
+	c.goldens[name]++
+	return goldenName(name, n)
+}
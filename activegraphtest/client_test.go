@@ -0,0 +1,134 @@
+package activegraphtest
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph"
+)
+
+type errNotFound struct{}
+
+func (errNotFound) Error() string { return "not found" }
+
+func (errNotFound) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "NOT_FOUND"}
+}
+
+type post struct {
+	Title string `json:"title"`
+	Likes int    `json:"likes"`
+}
+
+func newPostsController() *activegraph.Controller {
+	return &activegraph.Controller{
+		Queries: []activegraph.FuncDef{
+			activegraph.NewFunc("posts", func(ctx context.Context) ([]post, error) {
+				return []post{{Title: "Hello", Likes: 2}, {Title: "World"}}, nil
+			}),
+			activegraph.NewFunc("post", func(ctx context.Context, args struct {
+				Title string `json:"title"`
+			}) (*post, error) {
+				if args.Title != "Hello" {
+					return nil, errNotFound{}
+				}
+				return &post{Title: args.Title}, nil
+			}),
+		},
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	client := NewController(t, newPostsController())
+
+	resp := client.Do(`{ posts { title likes } }`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.AssertNoErrors()
+	resp.AssertData("posts.0", post{Title: "Hello", Likes: 2})
+	resp.AssertData("posts.1.title", "World")
+
+	var posts []post
+	resp.Decode("posts", &posts)
+	assert.Equal(t, []post{{"Hello", 2}, {"World", 0}}, posts)
+
+	resp = client.Do(`query Post($title: String!) { post(title: $title) { title } }`,
+		Variables(map[string]interface{}{"title": "Goodbye"}),
+		OperationName("Post"),
+	)
+	resp.AssertData("post", nil)
+	resp.AssertErrorCode("NOT_FOUND")
+	assert.Equal(t, []interface{}{"post"}, resp.Errors[0].Path)
+}
+
+func TestClient_Header(t *testing.T) {
+	var header http.Header
+	client := New(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		rw.Write([]byte(`{"data": null, "extensions": {"cost": {"requested": 3}}}`))
+	}))
+	client.Header.Set("Authorization", "Bearer token")
+
+	resp := client.Do(`{ posts { title } }`, Header("X-Request-Id", "1"))
+	resp.AssertExtension("cost.requested", 3)
+	assert.Equal(t, "Bearer token", header.Get("Authorization"))
+	assert.Equal(t, "1", header.Get("X-Request-Id"))
+}
+
+// recorder records failures of the assertions instead of the test.
+type recorder struct {
+	testing.TB
+	failures int
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(string, ...interface{}) { r.failures++ }
+
+func TestResponse_AssertFailure(t *testing.T) {
+	rec := &recorder{TB: t}
+	client := New(rec, newPostsController().HandleHTTP())
+
+	resp := client.Do(`{ posts { title } }`)
+	assert.False(t, resp.AssertData("posts.2.title", "Hello"))
+	assert.False(t, resp.AssertData("posts.0.title", "World"))
+	assert.False(t, resp.AssertErrorCode("NOT_FOUND"))
+	assert.False(t, resp.AssertExtension("cost", 1))
+	assert.Equal(t, 4, rec.failures)
+}
+
+func TestResponse_AssertGolden(t *testing.T) {
+	client := NewController(t, newPostsController())
+
+	client.Do(`{ posts { title likes } }`).AssertGolden()
+	client.Do(`{ post(title: "Goodbye") { title } }`).AssertGolden()
+}
+
+func TestResponse_AssertGoldenUpdate(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	client := New(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"data": {"b": 1, "a": [true]}}`))
+	}))
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	client.Do(`{ a b }`).AssertGolden()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "TestResponse_AssertGoldenUpdate.golden"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"data\": {\n    \"a\": [\n      true\n    ],\n    \"b\": 1\n  }\n}\n", string(b))
+
+	// The second golden file of the test does not exist yet.
+	t.Setenv(UpdateGoldenEnv, "")
+	rec := &recorder{TB: t}
+	client.t = rec
+	assert.False(t, client.Do(`{ a b }`).AssertGolden())
+}
@@ -0,0 +1,173 @@
+package activegraphtest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activerecord/migration"
+	"github.com/activegraph/activegraph/activerecord/sqlite3"
+)
+
+const (
+	// adapterName is the name of the connection adapter, that establishes
+	// prepared connections of test databases.
+	adapterName = "activegraphtest"
+
+	primaryConnectionName = "primary"
+)
+
+var (
+	// conns are prepared connections of test databases, that are waiting
+	// to be established by the adapter.
+	conns sync.Map
+
+	savepoints uint64
+)
+
+func init() {
+	activerecord.RegisterConnectionAdapter(adapterName, connect)
+}
+
+func connect(conf activerecord.DatabaseConfig) (activerecord.Conn, error) {
+	conn, ok := conns.LoadAndDelete(conf.Database)
+	if !ok {
+		return nil, errors.Errorf("test database %q is not prepared", conf.Database)
+	}
+	return conn.(activerecord.Conn), nil
+}
+
+// testConn is a connection of the test database, all operations are
+// executed within the transaction, that is rolled back on close.
+//
+// Transactions started through the connection are emulated with savepoints,
+// so the changes of committed transactions are rolled back as well.
+type testConn struct {
+	activerecord.Conn
+
+	// base is the connection to the database, that is closed after rollback
+	// of the test transaction.
+	base activerecord.Conn
+
+	// savepoint is the name of the savepoint of the nested transaction,
+	// it is empty for the test transaction.
+	savepoint string
+}
+
+func (c *testConn) BeginTransaction(ctx context.Context) (activerecord.Conn, error) {
+	savepoint := fmt.Sprintf("activegraphtest_%d", atomic.AddUint64(&savepoints, 1))
+	if err := c.Conn.Exec(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return nil, err
+	}
+	return &testConn{Conn: c.Conn, savepoint: savepoint}, nil
+}
+
+func (c *testConn) CommitTransaction(ctx context.Context) error {
+	if c.savepoint == "" {
+		return errors.Errorf("test transaction could not be committed")
+	}
+	return c.Conn.Exec(ctx, "RELEASE SAVEPOINT "+c.savepoint)
+}
+
+func (c *testConn) RollbackTransaction(ctx context.Context) error {
+	if c.savepoint == "" {
+		return errors.Errorf("test transaction could not be rolled back")
+	}
+	if err := c.Conn.Exec(ctx, "ROLLBACK TO SAVEPOINT "+c.savepoint); err != nil {
+		return err
+	}
+	return c.Conn.Exec(ctx, "RELEASE SAVEPOINT "+c.savepoint)
+}
+
+func (c *testConn) Ping(ctx context.Context) error {
+	return activerecord.Ping(ctx, c.Conn)
+}
+
+func (c *testConn) Close() error {
+	// Nested transactions are finished with commit or rollback.
+	if c.savepoint != "" {
+		return nil
+	}
+	err := c.Conn.RollbackTransaction(context.Background())
+	if e := c.base.Close(); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// Database establishes the primary connection to an isolated sqlite
+// database of the test, and executes the given schema statements. All
+// changes made within the test are rolled back when the test completes.
+//
+// Tests using the database must not run in parallel, since the primary
+// connection is shared by all relations.
+func Database(t testing.TB, schema ...string) activerecord.Conn {
+	t.Helper()
+
+	ctx := context.Background()
+	base, err := sqlite3.Connect(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: filepath.Join(t.TempDir(), "test.sqlite3"),
+	})
+	if err != nil {
+		t.Fatalf("activegraphtest: connect: %v", err)
+	}
+
+	for _, stmt := range schema {
+		if err = base.Exec(ctx, stmt); err != nil {
+			base.Close()
+			t.Fatalf("activegraphtest: schema: %v", err)
+		}
+	}
+
+	tx, err := base.BeginTransaction(ctx)
+	if err != nil {
+		base.Close()
+		t.Fatalf("activegraphtest: begin transaction: %v", err)
+	}
+
+	name := t.Name()
+	conns.Store(name, &testConn{Conn: tx, base: base})
+
+	conn, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: adapterName, Database: name,
+	})
+	if err != nil {
+		if c, ok := conns.LoadAndDelete(name); ok {
+			c.(activerecord.Conn).Close()
+		}
+		t.Fatalf("activegraphtest: establish connection: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := activerecord.RemoveConnection(primaryConnectionName); err != nil {
+			t.Errorf("activegraphtest: rollback: %v", err)
+		}
+	})
+	return conn
+}
+
+// Migrations returns statements of migrations from the directory, that
+// create the schema of the test database:
+//
+//	activegraphtest.Database(t, activegraphtest.Migrations(t, "db/migrate")...)
+func Migrations(t testing.TB, dir string) []string {
+	t.Helper()
+
+	migrations, err := migration.Load(dir)
+	if err != nil {
+		t.Fatalf("activegraphtest: load migrations: %v", err)
+	}
+
+	schema := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		schema = append(schema, m.Up)
+	}
+	return schema
+}
@@ -0,0 +1,105 @@
+package activegraphtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/actioncontroller"
+	actiongraphql "github.com/activegraph/activegraph/actioncontroller/graphql"
+	"github.com/activegraph/activegraph/actionview"
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activesupport"
+)
+
+const booksSchema = `CREATE TABLE books (id INTEGER PRIMARY KEY, title VARCHAR)`
+
+func newBooksMapper() *actiongraphql.Mapper {
+	Book := activerecord.New("book", func(r *activerecord.R) {
+		r.AttrString("title")
+	})
+	books := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Permit(Book.AttributesForInspect("title"), actioncontroller.ActionCreate)
+		c.Index(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.ViewResult(activesupport.Return(Book.WithContext(ctx).All().ToA()))
+		})
+		c.Create(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.ViewResult(activerecord.Return(
+				Book.WithContext(ctx).Create(ctx.Params.Get("book")),
+			))
+		})
+	})
+
+	var m actiongraphql.Mapper
+	m.Resources(Book, books)
+	return &m
+}
+
+func TestDatabase_Rollback(t *testing.T) {
+	for _, title := range []string{"first", "second"} {
+		title := title
+		t.Run(title, func(t *testing.T) {
+			Database(t, booksSchema)
+			client := NewMapper(t, newBooksMapper())
+
+			resp := client.Do(`mutation($title: String) { createBook(book: {title: $title}) { title } }`,
+				Variables(map[string]interface{}{"title": title}))
+			resp.AssertNoErrors()
+			resp.AssertData("createBook.title", title)
+
+			// Records of the previous test are rolled back.
+			resp = client.Do(`{ books { title } }`)
+			resp.AssertNoErrors()
+			resp.AssertData("books", []map[string]string{{"title": title}})
+		})
+	}
+
+	_, err := activerecord.PrimaryConnection()
+	assert.Error(t, err)
+}
+
+func TestDatabase_Transaction(t *testing.T) {
+	conn := Database(t, booksSchema)
+	ctx := context.Background()
+
+	err := activerecord.Transaction(ctx, func() error {
+		return conn.Exec(ctx, `INSERT INTO books (title) VALUES ('committed')`)
+	})
+	require.NoError(t, err)
+
+	err = activerecord.Transaction(ctx, func() error {
+		if err := conn.Exec(ctx, `INSERT INTO books (title) VALUES ('rolled back')`); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.EqualError(t, err, "rollback")
+
+	var titles []string
+	err = conn.ExecQuery(ctx, &activerecord.QueryOperation{
+		Text: `SELECT title FROM books`,
+	}, func(h activesupport.Hash) bool {
+		titles = append(titles, h["title"].(string))
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"committed"}, titles)
+}
+
+func TestMigrations(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "20260101000000_create_books.up.sql"), []byte(booksSchema), 0o644)
+	require.NoError(t, err)
+
+	Database(t, Migrations(t, dir)...)
+	client := NewMapper(t, newBooksMapper())
+
+	resp := client.Do(`{ books { title } }`)
+	resp.AssertNoErrors()
+	resp.AssertData("books", []string{})
+}
@@ -0,0 +1,210 @@
+package activegraphtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// UpdateGoldenEnv is the name of the environment variable, that rewrites
+// golden files with actual responses, when set to a non-empty value:
+//
+//	ACTIVEGRAPH_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "ACTIVEGRAPH_UPDATE_GOLDEN"
+
+// Error is an error of the GraphQL response.
+type Error struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Code returns the "code" extension of the error.
+func (e Error) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// Response is a GraphQL response of the executed operation.
+type Response struct {
+	StatusCode int         `json:"-"`
+	Header     http.Header `json:"-"`
+	Body       []byte      `json:"-"`
+
+	Data       interface{}            `json:"data"`
+	Errors     []Error                `json:"errors"`
+	Extensions map[string]interface{} `json:"extensions"`
+
+	client *Client
+}
+
+// lookup returns the value by the path of dot-separated keys of objects and
+// indices of lists, e.g. "posts.0.title". Empty path refers to the value
+// itself.
+func lookup(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// normalize converts the value to the representation of the decoded JSON,
+// so Go values could be compared with values of the response.
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var n interface{}
+	return n, json.Unmarshal(b, &n)
+}
+
+// Get returns the data value by the path, e.g. "posts.0.title".
+func (r *Response) Get(path string) (interface{}, bool) {
+	return lookup(r.Data, path)
+}
+
+// Decode decodes the data value by the path into the value pointed by dest.
+// The test fails when the value does not exist.
+func (r *Response) Decode(path string, dest interface{}) {
+	t := r.client.t
+	t.Helper()
+
+	v, ok := r.Get(path)
+	if !ok {
+		t.Fatalf("activegraphtest: data %q not found in %s", path, r.Body)
+	}
+	b, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(b, dest)
+	}
+	if err != nil {
+		t.Fatalf("activegraphtest: decode data %q: %v", path, err)
+	}
+}
+
+func (r *Response) assertValue(kind string, root interface{}, path string, want interface{}) bool {
+	t := r.client.t
+	t.Helper()
+
+	got, ok := lookup(root, path)
+	if !ok {
+		return assert.Fail(t, fmt.Sprintf("%s %q not found", kind, path), "response: %s", r.Body)
+	}
+	want, err := normalize(want)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("encode expected %s: %v", kind, err))
+	}
+	return assert.Equal(t, want, got, "%s %q", kind, path)
+}
+
+// AssertNoErrors asserts that the response does not contain errors.
+func (r *Response) AssertNoErrors() bool {
+	r.client.t.Helper()
+	return assert.Empty(r.client.t, r.Errors, "response: %s", r.Body)
+}
+
+// AssertData asserts that the data value by the path equals to the expected
+// value, the expected value is compared in its JSON representation.
+func (r *Response) AssertData(path string, want interface{}) bool {
+	r.client.t.Helper()
+	return r.assertValue("data", r.Data, path, want)
+}
+
+// AssertExtension asserts that the response extension by the path equals to
+// the expected value.
+func (r *Response) AssertExtension(path string, want interface{}) bool {
+	r.client.t.Helper()
+	return r.assertValue("extension", map[string]interface{}(r.Extensions), path, want)
+}
+
+// AssertErrorCode asserts that the response contains an error with the given
+// "code" extension.
+func (r *Response) AssertErrorCode(code string) bool {
+	t := r.client.t
+	t.Helper()
+
+	codes := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		if e.Code() == code {
+			return true
+		}
+		codes = append(codes, e.Code())
+	}
+	return assert.Fail(t, fmt.Sprintf("error with code %q not found", code),
+		"error codes: %q, response: %s", codes, r.Body)
+}
+
+// goldenName returns the path of the golden file of the test, the n-th
+// golden file of the test is suffixed with its number.
+func goldenName(testName string, n int) string {
+	if n > 0 {
+		testName += "_" + strconv.Itoa(n)
+	}
+	return filepath.Join("testdata", filepath.FromSlash(testName)+".golden")
+}
+
+// AssertGolden asserts that the response equals to the content of the
+// golden file "testdata/<TestName>.golden". Golden files are written, when
+// the UpdateGoldenEnv environment variable is set.
+//
+// The response is stored as an indented JSON with sorted keys, so it could
+// be reviewed along with the changes of the code.
+func (r *Response) AssertGolden() bool {
+	t := r.client.t
+	t.Helper()
+
+	var v interface{}
+	if err := json.Unmarshal(r.Body, &v); err != nil {
+		t.Fatalf("activegraphtest: decode response: %v", err)
+	}
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("activegraphtest: encode response: %v", err)
+	}
+	got = append(got, '\n')
+
+	filename := r.client.golden()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err = os.MkdirAll(filepath.Dir(filename), 0o755); err == nil {
+			err = os.WriteFile(filename, got, 0o644)
+		}
+		if err != nil {
+			t.Fatalf("activegraphtest: update golden file: %v", err)
+		}
+		return true
+	}
+
+	want, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return assert.Fail(t, fmt.Sprintf("golden file %s does not exist", filename),
+			"run tests with %s=1 to create it", UpdateGoldenEnv)
+	}
+	if err != nil {
+		t.Fatalf("activegraphtest: read golden file: %v", err)
+	}
+	return assert.Equal(t, string(want), string(got), "golden file %s", filename)
+}
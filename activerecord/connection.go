@@ -3,7 +3,9 @@ package activerecord
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/activegraph/activegraph/internal"
 	"github.com/pkg/errors"
@@ -41,13 +43,43 @@ func (e *ErrConnectionNotEstablished) Error() string {
 	return fmt.Sprintf("connection %q has not been established", e.Name)
 }
 
+// ErrReadOnlyConnection is returned on attempt to write through the
+// connection to the database replica.
+type ErrReadOnlyConnection struct {
+	Name string
+}
+
+func (e *ErrReadOnlyConnection) Error() string {
+	return fmt.Sprintf("connection %q is read-only", e.Name)
+}
+
 type DatabaseConfig struct {
-	Name     string
-	Adapter  string
-	Host     string
-	Username string
-	Password string
-	Database string
+	Name     string `yaml:"-"`
+	Adapter  string `yaml:"adapter"`
+	Host     string `yaml:"host"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Database string `yaml:"database"`
+
+	// URL is a connection URL, fields specified in the URL override the
+	// fields of the configuration, see LoadConfig for details.
+	URL string `yaml:"url"`
+
+	// Pool is the maximum number of open connections to the database,
+	// zero means unlimited.
+	Pool int `yaml:"pool"`
+
+	// IdleTimeout is the maximum amount of time a connection may be idle
+	// before being closed, zero means connections are not closed.
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+
+	// ConnectTimeout limits establishing of the connection, zero means
+	// no timeout.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+
+	// Replica marks the connection as a read-only replica, writes through
+	// such connection fail with ErrReadOnlyConnection.
+	Replica bool `yaml:"replica"`
 }
 
 type ConnectionAdapter func(DatabaseConfig) (Conn, error)
@@ -119,13 +151,19 @@ func (h *connectionHandler) Transaction(ctx context.Context, fn func() error) er
 	return conn.CommitTransaction(ctx)
 }
 
-func (h *connectionHandler) EstablishConnection(c DatabaseConfig) (Conn, error) {
+func (h *connectionHandler) EstablishConnection(c DatabaseConfig) (conn Conn, err error) {
+	if c.URL != "" {
+		if c, err = mergeDatabaseURL(c, c.URL); err != nil {
+			return nil, err
+		}
+	}
+
 	newConnection, ok := h.adapters[c.Adapter]
 	if !ok {
 		return nil, &ErrAdapterNotFound{Adapter: c.Adapter}
 	}
 
-	conn, err := newConnection(c)
+	conn, err = newConnection(c)
 	if err != nil {
 		return nil, err
 	}
@@ -133,11 +171,15 @@ func (h *connectionHandler) EstablishConnection(c DatabaseConfig) (Conn, error)
 	if c.Name == "" {
 		c.Name = primaryConnectionName
 	}
+	if c.Replica {
+		conn = &readonlyConn{Conn: conn, name: c.Name}
+	}
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	if _, dup := h.conns[c.Name]; dup {
+		conn.Close()
 		return nil, fmt.Errorf("connection %q already established", c.Name)
 	}
 
@@ -145,6 +187,36 @@ func (h *connectionHandler) EstablishConnection(c DatabaseConfig) (Conn, error)
 	return conn, nil
 }
 
+// readonlyConn is a connection to the database replica, that rejects writes.
+type readonlyConn struct {
+	Conn
+	name string
+}
+
+func (c *readonlyConn) BeginTransaction(ctx context.Context) (Conn, error) {
+	conn, err := c.Conn.BeginTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &readonlyConn{Conn: conn, name: c.name}, nil
+}
+
+func (c *readonlyConn) Ping(ctx context.Context) error {
+	return Ping(ctx, c.Conn)
+}
+
+func (c *readonlyConn) Exec(context.Context, string, ...interface{}) error {
+	return &ErrReadOnlyConnection{Name: c.name}
+}
+
+func (c *readonlyConn) ExecInsert(context.Context, *InsertOperation) (interface{}, error) {
+	return nil, &ErrReadOnlyConnection{Name: c.name}
+}
+
+func (c *readonlyConn) ExecDelete(context.Context, *DeleteOperation) error {
+	return &ErrReadOnlyConnection{Name: c.name}
+}
+
 func (h *connectionHandler) RetrieveConnection(name string) (Conn, error) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -174,6 +246,32 @@ func (h *connectionHandler) RemoveConnection(name string) error {
 	return conn.Close()
 }
 
+// RemoveAllConnections closes all established connections, the error of each
+// failed connection closing is attached to the returned error.
+func (h *connectionHandler) RemoveAllConnections() (err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names := make([]string, 0, len(h.conns))
+	for name := range h.conns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if e := h.conns[name].Close(); e != nil {
+			e = errors.WithMessagef(e, "connection %q", name)
+			if err == nil {
+				err = e
+			} else {
+				err = errors.WithMessage(err, e.Error())
+			}
+		}
+		delete(h.conns, name)
+	}
+	return err
+}
+
 func RegisterConnectionAdapter(adapter string, ca ConnectionAdapter) {
 	err := globalConnectionHandler.RegisterConnectionAdapter(adapter, ca)
 	if err != nil {
@@ -206,6 +304,38 @@ func RemoveConnection(name string) error {
 	return globalConnectionHandler.RemoveConnection(name)
 }
 
+// EstablishConnections establishes connections to the databases of the given
+// configurations, usually loaded with LoadConfig. When any of connections
+// fails, connections established before are closed and removed.
+func EstablishConnections(configs []DatabaseConfig) error {
+	names := make([]string, 0, len(configs))
+	for _, c := range configs {
+		if _, err := EstablishConnection(c); err != nil {
+			for _, name := range names {
+				RemoveConnection(name)
+			}
+			return errors.WithMessagef(err, "connection %q", c.Name)
+		}
+
+		name := c.Name
+		if name == "" {
+			name = primaryConnectionName
+		}
+		names = append(names, name)
+	}
+	return nil
+}
+
+// RemoveAllConnections closes all established connections.
+func RemoveAllConnections() error {
+	return globalConnectionHandler.RemoveAllConnections()
+}
+
+// PrimaryConnection returns the primary connection to the database.
+func PrimaryConnection() (Conn, error) {
+	return globalConnectionHandler.RetrieveConnection(primaryConnectionName)
+}
+
 // Transaction runs the given block in a database transaction, and returns the
 // result of the function.
 func Transaction(ctx context.Context, fn func() error) error {
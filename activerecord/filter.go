@@ -0,0 +1,323 @@
+package activerecord
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Filter operators and combinators.
+const (
+	FilterEq       = "eq"       // equal to the value.
+	FilterNe       = "ne"       // not equal to the value.
+	FilterIn       = "in"       // equal to one of the values.
+	FilterLt       = "lt"       // less than the value.
+	FilterGt       = "gt"       // greater than the value.
+	FilterContains = "contains" // contains the substring.
+	FilterIsNull   = "isNull"   // is null, when the value is true.
+
+	FilterAnd = "and" // all of the filters match.
+	FilterOr  = "or"  // any of the filters matches.
+	FilterNot = "not" // the filter does not match.
+)
+
+// ErrUnknownFilter is returned on attempt to filter relation by unknown
+// attribute, association or operator.
+type ErrUnknownFilter struct {
+	RecordName string
+	Filter     string
+}
+
+// Error returns a string representation of the error.
+func (e ErrUnknownFilter) Error() string {
+	return fmt.Sprintf("unknown filter %q for %s", e.Filter, e.RecordName)
+}
+
+// ErrInvalidFilter is returned, when the value of the filter is invalid.
+type ErrInvalidFilter struct {
+	RecordName string
+	Filter     string
+	Value      interface{}
+}
+
+// Error returns a string representation of the error.
+func (e ErrInvalidFilter) Error() string {
+	return fmt.Sprintf("invalid value '%v' of filter %q for %s", e.Value, e.Filter, e.RecordName)
+}
+
+// Filter returns a new relation with records matching the filter. Keys of
+// the filter are attributes with hashes of operators, associations the
+// record belongs to with nested filters, and combinators:
+//
+//	Book.Filter(map[string]interface{}{
+//		"year":   map[string]interface{}{"gt": 1900},
+//		"author": map[string]interface{}{"name": map[string]interface{}{"eq": "Herbert"}},
+//		"or": []interface{}{
+//			map[string]interface{}{"title": map[string]interface{}{"contains": "Dune"}},
+//			map[string]interface{}{"title": map[string]interface{}{"isNull": true}},
+//		},
+//	})
+//
+// Values of the filter are always passed as arguments of the query, names
+// of attributes and associations are validated against the schema.
+func (rel *Relation) Filter(filter map[string]interface{}) (*Relation, error) {
+	cond, args, err := rel.filterCondition(filter, "")
+	if err != nil {
+		return nil, err
+	}
+
+	newrel := rel.Copy()
+	if cond != "" {
+		newrel.query.Where(cond, args...)
+	}
+	return newrel, nil
+}
+
+// filterCondition returns the condition of the filter, all keys of the
+// filter must match.
+func (rel *Relation) filterCondition(filter map[string]interface{}, path string) (
+	string, []interface{}, error,
+) {
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var (
+		conds []string
+		args  []interface{}
+	)
+	for _, key := range keys {
+		var (
+			cond    string
+			argsOf  []interface{}
+			err     error
+			val     = filter[key]
+			keyPath = filterPath(path, key)
+		)
+
+		switch key {
+		case FilterAnd, FilterOr:
+			cond, argsOf, err = rel.combineCondition(key, val, keyPath)
+		case FilterNot:
+			nested, ok := toHash(val)
+			if !ok {
+				return "", nil, ErrInvalidFilter{RecordName: rel.name, Filter: keyPath, Value: val}
+			}
+			cond, argsOf, err = rel.filterCondition(nested, keyPath)
+			if cond != "" {
+				cond = "NOT (" + cond + ")"
+			}
+		default:
+			cond, argsOf, err = rel.keyCondition(key, val, keyPath)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if cond != "" {
+			conds = append(conds, cond)
+			args = append(args, argsOf...)
+		}
+	}
+
+	if len(conds) == 1 {
+		return conds[0], args, nil
+	}
+	for i := range conds {
+		conds[i] = "(" + conds[i] + ")"
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+// combineCondition joins conditions of the list of filters with the
+// logical operator of the combinator.
+//
+// An empty condition matches all records, so the disjunction with an empty
+// condition matches all records as well, while the empty disjunction does
+// not match any record.
+func (rel *Relation) combineCondition(combinator string, val interface{}, path string) (
+	string, []interface{}, error,
+) {
+	list, ok := val.([]interface{})
+	if !ok {
+		return "", nil, ErrInvalidFilter{RecordName: rel.name, Filter: path, Value: val}
+	}
+	if combinator == FilterOr && len(list) == 0 {
+		return "1 = 0", nil, nil
+	}
+
+	var (
+		conds    []string
+		args     []interface{}
+		matchAll bool
+	)
+	for i, v := range list {
+		filter, ok := toHash(v)
+		if !ok {
+			return "", nil, ErrInvalidFilter{RecordName: rel.name, Filter: path, Value: val}
+		}
+		cond, argsOf, err := rel.filterCondition(filter, filterPath(path, strconv.Itoa(i)))
+		if err != nil {
+			return "", nil, err
+		}
+		if cond == "" && combinator == FilterOr {
+			matchAll = true
+		}
+		if cond != "" {
+			conds = append(conds, "("+cond+")")
+			args = append(args, argsOf...)
+		}
+	}
+	if matchAll {
+		return "", nil, nil
+	}
+	return strings.Join(conds, " "+strings.ToUpper(combinator)+" "), args, nil
+}
+
+// keyCondition returns the condition of the attribute or association.
+func (rel *Relation) keyCondition(key string, val interface{}, path string) (
+	string, []interface{}, error,
+) {
+	filter, ok := toHash(val)
+	if !ok {
+		return "", nil, ErrInvalidFilter{RecordName: rel.name, Filter: path, Value: val}
+	}
+
+	if rel.scope.HasAttribute(key) {
+		column := fmt.Sprintf(`"%s"."%s"`, rel.TableName(), key)
+		return rel.attributeCondition(column, filter, path)
+	}
+
+	assoc := rel.ReflectOnAssociation(key)
+	if assoc == nil {
+		return "", nil, ErrUnknownFilter{RecordName: rel.name, Filter: path}
+	}
+	if _, ok := assoc.Association.(*BelongsTo); !ok {
+		return "", nil, ErrUnknownFilter{RecordName: rel.name, Filter: path}
+	}
+
+	cond, args, err := assoc.Relation.filterCondition(filter, path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, `"%s"."%s" IN (SELECT "%s"."%s" FROM "%s"`,
+		rel.TableName(), assoc.AssociationForeignKey(),
+		assoc.Relation.TableName(), assoc.Relation.PrimaryKey(), assoc.Relation.TableName(),
+	)
+	if cond != "" {
+		fmt.Fprintf(&buf, " WHERE %s", cond)
+	}
+	buf.WriteString(")")
+	return buf.String(), args, nil
+}
+
+// attributeCondition returns the condition of operators applied to the
+// column of the attribute.
+func (rel *Relation) attributeCondition(column string, ops map[string]interface{}, path string) (
+	string, []interface{}, error,
+) {
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		conds []string
+		args  []interface{}
+	)
+	for _, name := range names {
+		var (
+			val    = ops[name]
+			opPath = filterPath(path, name)
+		)
+
+		switch name {
+		case FilterEq, FilterNe:
+			switch {
+			case val == nil && name == FilterEq:
+				conds = append(conds, column+" IS NULL")
+			case val == nil:
+				conds = append(conds, column+" IS NOT NULL")
+			case name == FilterEq:
+				conds, args = append(conds, column+" = ?"), append(args, val)
+			default:
+				conds, args = append(conds, column+" <> ?"), append(args, val)
+			}
+		case FilterLt, FilterGt:
+			if val == nil {
+				return "", nil, ErrInvalidFilter{RecordName: rel.name, Filter: opPath, Value: val}
+			}
+			op := " < ?"
+			if name == FilterGt {
+				op = " > ?"
+			}
+			conds, args = append(conds, column+op), append(args, val)
+		case FilterIn:
+			list, ok := val.([]interface{})
+			if !ok {
+				return "", nil, ErrInvalidFilter{RecordName: rel.name, Filter: opPath, Value: val}
+			}
+			if len(list) == 0 {
+				conds = append(conds, "1 = 0")
+				continue
+			}
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(list)), ", ")
+			conds, args = append(conds, column+" IN ("+placeholders+")"), append(args, list...)
+		case FilterContains:
+			s, ok := val.(string)
+			if !ok {
+				return "", nil, ErrInvalidFilter{RecordName: rel.name, Filter: opPath, Value: val}
+			}
+			conds = append(conds, column+` LIKE ? ESCAPE '\'`)
+			args = append(args, "%"+escapeLike(s)+"%")
+		case FilterIsNull:
+			isNull, ok := val.(bool)
+			if !ok {
+				return "", nil, ErrInvalidFilter{RecordName: rel.name, Filter: opPath, Value: val}
+			}
+			if isNull {
+				conds = append(conds, column+" IS NULL")
+			} else {
+				conds = append(conds, column+" IS NOT NULL")
+			}
+		default:
+			return "", nil, ErrUnknownFilter{RecordName: rel.name, Filter: opPath}
+		}
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+// filterPath returns the path of the nested filter, it is used in errors.
+func filterPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// escapeLike escapes wildcards of the LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// toHash converts hashes of any named type, e.g. parameters of the
+// controller, to the plain hash.
+func toHash(val interface{}) (map[string]interface{}, bool) {
+	if filter, ok := val.(map[string]interface{}); ok {
+		return filter, true
+	}
+
+	v := reflect.ValueOf(val)
+	if !v.IsValid() || v.Kind() != reflect.Map || !v.Type().ConvertibleTo(hashType) {
+		return nil, false
+	}
+	return v.Convert(hashType).Interface().(map[string]interface{}), true
+}
+
+var hashType = reflect.TypeOf(map[string]interface{}(nil))
@@ -0,0 +1,381 @@
+// Package fixtures loads test data of activerecord models from files.
+//
+// Fixtures of a table are stored in "<table>.yml" (or ".yaml", ".json") file
+// as a mapping of fixture labels to column values:
+//
+//	# authors.yml
+//	alice:
+//	  name: Alice
+//
+//	# books.yml
+//	dune:
+//	  title: Dune
+//	  author: alice
+//
+// Primary keys of fixtures are derived from labels with Identify, unless
+// specified explicitly, so the fixture could be referenced by the label: the
+// value of BelongsTo association ("author") is replaced with the primary key
+// of the referenced fixture in the foreign key column ("author_id").
+//
+// Files are executed as text/template templates before parsing, so rows
+// could be generated:
+//
+//	{{range $i := seq 1 3}}
+//	book_{{$i}}:
+//	  title: Book {{$i}}
+//	{{end}}
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/activegraph/activegraph/activerecord"
+)
+
+// maxID is the upper bound of primary keys derived from labels.
+const maxID = 1<<30 - 1
+
+// extensions are extensions of fixture files, JSON is parsed as YAML.
+var extensions = []string{".yml", ".yaml", ".json"}
+
+// Identify returns a deterministic primary key of the fixture with the given
+// label.
+func Identify(label string) int {
+	return int(crc32.ChecksumIEEE([]byte(label)) % maxID)
+}
+
+// funcs are functions available within templates of fixture files.
+var funcs = template.FuncMap{
+	"identify": Identify,
+
+	// seq returns a sequence of integers from start to end inclusively.
+	"seq": func(start, end int) []int {
+		var seq []int
+		for i := start; i <= end; i++ {
+			seq = append(seq, i)
+		}
+		return seq
+	},
+}
+
+// Fixture is a row of the table identified by the label.
+type Fixture struct {
+	Label  string
+	Values map[string]interface{}
+}
+
+// Table is a list of fixtures of the database table.
+type Table struct {
+	Name     string
+	Fixtures []Fixture
+
+	// rel is the relation of the table, it is nil, when the relation is not
+	// registered.
+	rel *activerecord.Relation
+}
+
+func (t *Table) primaryKey() string {
+	if t.rel == nil {
+		return "id"
+	}
+	return t.rel.PrimaryKey()
+}
+
+// primaryKeyOf returns a primary key of the fixture of the relation, string
+// primary keys are labels themselves.
+func primaryKeyOf(rel *activerecord.Relation, label string) interface{} {
+	if rel != nil {
+		attr := rel.AttributeForInspect(rel.PrimaryKey())
+		if attr != nil && attr.CastType() != activerecord.Int {
+			return label
+		}
+	}
+	return Identify(label)
+}
+
+// identify assigns primary keys derived from labels to fixtures without
+// explicit primary keys.
+func (t *Table) identify() {
+	pk := t.primaryKey()
+	for _, f := range t.Fixtures {
+		if _, ok := f.Values[pk]; !ok {
+			f.Values[pk] = primaryKeyOf(t.rel, f.Label)
+		}
+	}
+}
+
+// lookup returns the primary key of the fixture with the given label.
+func (t *Table) lookup(label string) (interface{}, bool) {
+	for _, f := range t.Fixtures {
+		if f.Label == label {
+			return f.Values[t.primaryKey()], true
+		}
+	}
+	return nil, false
+}
+
+// resolve replaces references to other fixtures with foreign keys, the
+// primary key of the referenced fixture is taken from the given tables,
+// or derived from the label, when the table is not loaded.
+func (t *Table) resolve(tables map[string]*Table) error {
+	if t.rel == nil {
+		return nil
+	}
+
+	for _, f := range t.Fixtures {
+		names := make([]string, 0, len(f.Values))
+		for name := range f.Values {
+			names = append(names, name)
+		}
+
+		for _, name := range names {
+			assoc := t.rel.ReflectOnAssociation(name)
+			if assoc == nil {
+				continue
+			}
+			if _, ok := assoc.Association.(*activerecord.BelongsTo); !ok {
+				continue
+			}
+
+			label, ok := f.Values[name].(string)
+			if !ok {
+				return errors.Errorf("fixture %s.%s: reference %q must be a label", t.Name, f.Label, name)
+			}
+
+			id := primaryKeyOf(assoc.Relation, label)
+			if target, ok := tables[assoc.Relation.TableName()]; ok {
+				if id, ok = target.lookup(label); !ok {
+					return errors.Errorf("fixture %s.%s: unknown %s %q", t.Name, f.Label, name, label)
+				}
+			}
+
+			delete(f.Values, name)
+			f.Values[assoc.AssociationForeignKey()] = id
+		}
+	}
+	return nil
+}
+
+// dependencies returns names of the tables referenced by the table.
+func (t *Table) dependencies() []string {
+	if t.rel == nil {
+		return nil
+	}
+	var deps []string
+	for _, assoc := range t.rel.ReflectOnAllAssociations() {
+		if _, ok := assoc.Association.(*activerecord.BelongsTo); ok {
+			deps = append(deps, assoc.Relation.TableName())
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// parse executes the template of the fixture file and decodes fixtures
+// sorted by labels.
+func parse(filename string) ([]Fixture, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(filepath.Base(filename)).Funcs(funcs).Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+
+	var rows map[string]map[string]interface{}
+	if err = yaml.Unmarshal(buf.Bytes(), &rows); err != nil {
+		return nil, errors.WithMessage(err, filename)
+	}
+
+	fixtures := make([]Fixture, 0, len(rows))
+	for label, values := range rows {
+		if values == nil {
+			values = make(map[string]interface{})
+		}
+		fixtures = append(fixtures, Fixture{Label: label, Values: values})
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Label < fixtures[j].Label })
+	return fixtures, nil
+}
+
+// Set is a set of fixture tables, tables are ordered so referenced tables
+// precede the tables referencing them.
+type Set struct {
+	Tables []*Table
+}
+
+// Load reads fixtures from files of the directory. When table names are
+// given, only fixtures of these tables are loaded.
+func Load(dir string, tableNames ...string) (*Set, error) {
+	files := make(map[string]string)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !contains(extensions, ext) {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if _, dup := files[name]; dup {
+			return nil, errors.Errorf("multiple fixture files of table %q", name)
+		}
+		files[name] = filepath.Join(dir, entry.Name())
+	}
+
+	if len(tableNames) == 0 {
+		for name := range files {
+			tableNames = append(tableNames, name)
+		}
+	}
+
+	tables := make(map[string]*Table, len(tableNames))
+	for _, name := range tableNames {
+		filename, ok := files[name]
+		if !ok {
+			return nil, errors.Errorf("fixtures of table %q not found", name)
+		}
+		fixtures, err := parse(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		rel, _ := activerecord.ReflectOnTable(name)
+		table := &Table{Name: name, Fixtures: fixtures, rel: rel}
+		table.identify()
+		tables[name] = table
+	}
+
+	sorted := sortTables(tables)
+	for _, table := range sorted {
+		if err := table.resolve(tables); err != nil {
+			return nil, err
+		}
+	}
+	return &Set{Tables: sorted}, nil
+}
+
+func contains(ss []string, s string) bool {
+	for i := range ss {
+		if ss[i] == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sortTables sorts tables topologically by references, the order of
+// independent tables is alphabetical. Cyclic references are ignored.
+func sortTables(tables map[string]*Table) []*Table {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		sorted  = make([]*Table, 0, len(tables))
+		visited = make(map[string]bool, len(tables))
+		visit   func(name string)
+	)
+	visit = func(name string) {
+		table, ok := tables[name]
+		if !ok || visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, dep := range table.dependencies() {
+			visit(dep)
+		}
+		sorted = append(sorted, table)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return sorted
+}
+
+// Table returns fixtures of the table.
+func (s *Set) Table(name string) (*Table, bool) {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Delete deletes all rows of the fixture tables within a transaction.
+func (s *Set) Delete(ctx context.Context) error {
+	return activerecord.Transaction(ctx, func() error {
+		conn, err := activerecord.PrimaryConnection()
+		if err != nil {
+			return err
+		}
+		return s.delete(ctx, conn)
+	})
+}
+
+func (s *Set) delete(ctx context.Context, conn activerecord.Conn) error {
+	// Tables referencing other tables are deleted first.
+	for i := len(s.Tables) - 1; i >= 0; i-- {
+		name := s.Tables[i].Name
+		if err := conn.Exec(ctx, fmt.Sprintf(`DELETE FROM "%s"`, name)); err != nil {
+			return errors.WithMessagef(err, "delete fixtures of %s", name)
+		}
+	}
+	return nil
+}
+
+// Insert replaces all rows of the fixture tables with fixtures within a
+// transaction, so the set could be inserted again to reset the tables to
+// the initial state between tests.
+func (s *Set) Insert(ctx context.Context) error {
+	return activerecord.Transaction(ctx, func() error {
+		conn, err := activerecord.PrimaryConnection()
+		if err != nil {
+			return err
+		}
+		if err = s.delete(ctx, conn); err != nil {
+			return err
+		}
+
+		for _, t := range s.Tables {
+			for _, f := range t.Fixtures {
+				values := make(map[string]interface{}, len(f.Values))
+				for column, value := range f.Values {
+					// Omitted columns are NULL.
+					if value != nil {
+						values[column] = value
+					}
+				}
+
+				_, err := conn.ExecInsert(ctx, &activerecord.InsertOperation{
+					TableName: t.Name, Values: values,
+				})
+				if err != nil {
+					return errors.WithMessagef(err, "insert fixture %s.%s", t.Name, f.Label)
+				}
+			}
+		}
+		return nil
+	})
+}
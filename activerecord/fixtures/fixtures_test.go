@@ -0,0 +1,117 @@
+package fixtures
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activegraphtest"
+	"github.com/activegraph/activegraph/activerecord"
+)
+
+const schema = `
+	CREATE TABLE authors (id INTEGER NOT NULL, name VARCHAR, PRIMARY KEY(id));
+	CREATE TABLE books (id INTEGER NOT NULL, author_id INTEGER, year INTEGER, title VARCHAR, PRIMARY KEY(id));
+	CREATE TABLE tags (id INTEGER NOT NULL, name VARCHAR, PRIMARY KEY(id));
+`
+
+var (
+	Author = activerecord.New("author", func(r *activerecord.R) {
+		r.AttrString("name")
+	})
+	Book = activerecord.New("book", func(r *activerecord.R) {
+		r.AttrString("title")
+		r.AttrInt("year")
+		r.BelongsTo("author")
+	})
+)
+
+func TestIdentify(t *testing.T) {
+	assert.Equal(t, Identify("alice"), Identify("alice"))
+	assert.NotEqual(t, Identify("alice"), Identify("bob"))
+	assert.Less(t, Identify("alice"), maxID)
+}
+
+func TestLoad(t *testing.T) {
+	set, err := Load("testdata")
+	require.NoError(t, err)
+
+	var names []string
+	for _, table := range set.Tables {
+		names = append(names, table.Name)
+	}
+	assert.Equal(t, []string{"authors", "books", "tags"}, names)
+
+	books, ok := set.Table("books")
+	require.True(t, ok)
+	require.Len(t, books.Fixtures, 4)
+
+	// Fixtures are sorted by labels, templates generate rows.
+	assert.Equal(t, Fixture{Label: "book_1", Values: map[string]interface{}{
+		"id": Identify("book_1"), "title": "Book 1", "author_id": 42,
+	}}, books.Fixtures[0])
+	assert.Equal(t, Fixture{Label: "dune", Values: map[string]interface{}{
+		"id": Identify("dune"), "title": "Dune", "year": 1965, "author_id": Identify("alice"),
+	}}, books.Fixtures[3])
+
+	// Fixtures of tables without relations are loaded as is.
+	tags, ok := set.Table("tags")
+	require.True(t, ok)
+	assert.Equal(t, []Fixture{
+		{Label: "classic", Values: map[string]interface{}{"id": 7, "name": "classic"}},
+		{Label: "fiction", Values: map[string]interface{}{"id": Identify("fiction"), "name": "fiction"}},
+	}, tags.Fixtures)
+}
+
+func TestLoad_Error(t *testing.T) {
+	_, err := Load("testdata", "reviews")
+	assert.EqualError(t, err, `fixtures of table "reviews" not found`)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "authors.yml"), []byte("alice: {name: Alice}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "books.yml"), []byte("dune: {author: carol}"), 0o644))
+
+	_, err = Load(dir)
+	assert.EqualError(t, err, `fixture books.dune: unknown author "carol"`)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "books.json"), []byte("{}"), 0o644))
+	_, err = Load(dir)
+	assert.EqualError(t, err, `multiple fixture files of table "books"`)
+}
+
+func TestSet_Insert(t *testing.T) {
+	activegraphtest.Database(t, schema)
+	ctx := context.Background()
+
+	set, err := Load("testdata")
+	require.NoError(t, err)
+	require.NoError(t, set.Insert(ctx))
+
+	book := Book.Find(Identify("dune"))
+	require.NoError(t, book.Err())
+	author, err := book.UnwrapRecord().AccessAssociation("author")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", author.Attribute("name"))
+
+	books, err := Book.Where("author_id", 42).ToA()
+	require.NoError(t, err)
+	assert.Len(t, books, 3)
+
+	// Insertion of fixtures resets tables to the initial state.
+	_, err = Author.Create(map[string]interface{}{"name": "Carol"})
+	require.NoError(t, err)
+	require.NoError(t, set.Insert(ctx))
+
+	authors, err := Author.All().ToA()
+	require.NoError(t, err)
+	assert.Len(t, authors, 2)
+
+	require.NoError(t, set.Delete(ctx))
+	authors, err = Author.All().ToA()
+	require.NoError(t, err)
+	assert.Len(t, authors, 0)
+}
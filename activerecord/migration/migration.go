@@ -0,0 +1,240 @@
+// Package migration applies versioned changes of the database schema.
+//
+// Migrations are SQL files within a directory, each migration consists of
+// two files: "<version>_<name>.up.sql" that applies the change, and
+// "<version>_<name>.down.sql" that reverts it. Versions are usually UTC
+// timestamps, e.g. "20260102150405", and migrations are applied in the
+// order of versions.
+//
+// Applied versions are stored in the "schema_migrations" table.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activesupport"
+)
+
+const (
+	// TableName is the name of the table of applied versions.
+	TableName = "schema_migrations"
+
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+// ErrIrreversibleMigration is returned on rollback of the migration, that
+// does not have down statements.
+type ErrIrreversibleMigration struct {
+	Version string
+	Name    string
+}
+
+func (e *ErrIrreversibleMigration) Error() string {
+	return fmt.Sprintf("migration %s_%s is irreversible", e.Version, e.Name)
+}
+
+// Migration is a versioned change of the database schema.
+type Migration struct {
+	Version string
+	Name    string
+
+	// Up and Down are SQL statements that apply and revert the migration.
+	Up   string
+	Down string
+}
+
+// Load reads migrations from the directory, migrations are sorted by
+// versions.
+func Load(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make(map[string]*Migration)
+	for _, entry := range entries {
+		filename := entry.Name()
+
+		var basename string
+		switch {
+		case entry.IsDir():
+			continue
+		case strings.HasSuffix(filename, upSuffix):
+			basename = strings.TrimSuffix(filename, upSuffix)
+		case strings.HasSuffix(filename, downSuffix):
+			basename = strings.TrimSuffix(filename, downSuffix)
+		default:
+			continue
+		}
+
+		version, name, ok := strings.Cut(basename, "_")
+		if !ok || version == "" {
+			return nil, errors.Errorf("migration %q must be named <version>_<name>", filename)
+		}
+
+		b, err := os.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := migrations[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			migrations[version] = m
+		}
+		if m.Name != name {
+			return nil, errors.Errorf("multiple migrations with version %s", version)
+		}
+		if strings.HasSuffix(filename, upSuffix) {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// Migrator applies and reverts migrations through the connection.
+type Migrator struct {
+	Conn       activerecord.Conn
+	Migrations []Migration
+}
+
+func (m *Migrator) init(ctx context.Context) error {
+	return m.Conn.Exec(ctx, "CREATE TABLE IF NOT EXISTS "+TableName+
+		" (version VARCHAR NOT NULL, PRIMARY KEY(version))")
+}
+
+// Versions returns applied versions in the ascending order.
+func (m *Migrator) Versions(ctx context.Context) ([]string, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	err := m.Conn.ExecQuery(ctx, &activerecord.QueryOperation{
+		TableName: TableName,
+		Text:      `SELECT version FROM ` + TableName + ` ORDER BY version`,
+		Columns:   []string{"version"},
+	}, func(h activesupport.Hash) bool {
+		switch version := h["version"].(type) {
+		case []byte:
+			versions = append(versions, string(version))
+		default:
+			versions = append(versions, fmt.Sprint(version))
+		}
+		return true
+	})
+	return versions, err
+}
+
+// Pending returns migrations, that are not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	versions, err := m.Versions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies all pending migrations, each migration is applied within
+// a separate transaction. It returns applied migrations.
+func (m *Migrator) Migrate(ctx context.Context) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]Migration, 0, len(pending))
+	for _, migration := range pending {
+		err := m.transaction(ctx, func(conn activerecord.Conn) error {
+			if err := conn.Exec(ctx, migration.Up); err != nil {
+				return err
+			}
+			return conn.Exec(ctx, `INSERT INTO `+TableName+` (version) VALUES (?)`, migration.Version)
+		})
+		if err != nil {
+			return applied, errors.WithMessagef(err, "migration %s_%s", migration.Version, migration.Name)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Rollback reverts the given number of the last applied migrations. It
+// returns reverted migrations.
+func (m *Migrator) Rollback(ctx context.Context, steps int) ([]Migration, error) {
+	versions, err := m.Versions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make(map[string]Migration, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		migrations[migration.Version] = migration
+	}
+
+	var reverted []Migration
+	for i := len(versions) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration, ok := migrations[versions[i]]
+		if !ok {
+			return reverted, errors.Errorf("migration %s not found", versions[i])
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return reverted, &ErrIrreversibleMigration{Version: migration.Version, Name: migration.Name}
+		}
+
+		err := m.transaction(ctx, func(conn activerecord.Conn) error {
+			if err := conn.Exec(ctx, migration.Down); err != nil {
+				return err
+			}
+			return conn.Exec(ctx, `DELETE FROM `+TableName+` WHERE version = ?`, migration.Version)
+		})
+		if err != nil {
+			return reverted, errors.WithMessagef(err, "migration %s_%s", migration.Version, migration.Name)
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+func (m *Migrator) transaction(ctx context.Context, fn func(activerecord.Conn) error) error {
+	tx, err := m.Conn.BeginTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		if e := tx.RollbackTransaction(ctx); e != nil {
+			err = errors.WithMessage(err, e.Error())
+		}
+		return err
+	}
+	return tx.CommitTransaction(ctx)
+}
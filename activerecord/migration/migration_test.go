@@ -0,0 +1,110 @@
+package migration_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activerecord/migration"
+	"github.com/activegraph/activegraph/activerecord/sqlite3"
+)
+
+func writeMigrations(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"20260102000000_create_books.up.sql":   "CREATE TABLE books (id INTEGER);",
+		"20260102000000_create_books.down.sql": "DROP TABLE books;",
+		"20260101000000_create_authors.up.sql": "CREATE TABLE authors (id INTEGER);",
+		"README.md":                            "",
+	})
+
+	migrations, err := migration.Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []migration.Migration{
+		{Version: "20260101000000", Name: "create_authors", Up: "CREATE TABLE authors (id INTEGER);"},
+		{
+			Version: "20260102000000", Name: "create_books",
+			Up: "CREATE TABLE books (id INTEGER);", Down: "DROP TABLE books;",
+		},
+	}, migrations)
+
+	dir = writeMigrations(t, map[string]string{"create.up.sql": ""})
+	_, err = migration.Load(dir)
+	assert.EqualError(t, err, `migration "create.up.sql" must be named <version>_<name>`)
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	conn, err := sqlite3.Connect(activerecord.DatabaseConfig{
+		Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	m := migration.Migrator{Conn: conn, Migrations: []migration.Migration{
+		{Version: "1", Name: "create_authors", Up: "CREATE TABLE authors (id INTEGER);"},
+		{
+			Version: "2", Name: "create_books",
+			Up: "CREATE TABLE books (id INTEGER);", Down: "DROP TABLE books;",
+		},
+	}}
+
+	applied, err := m.Migrate(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+
+	versions, err := m.Versions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, versions)
+
+	schema, err := conn.(activerecord.SchemaDumper).DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, schema, "CREATE TABLE books (id INTEGER);\n")
+
+	// Applied migrations are skipped.
+	applied, err = m.Migrate(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := m.Rollback(ctx, 2)
+	assert.Equal(t, []migration.Migration{m.Migrations[1]}, reverted)
+	assert.EqualError(t, err, "migration 1_create_authors is irreversible")
+
+	versions, err = m.Versions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, versions)
+}
+
+func TestMigrator_Failure(t *testing.T) {
+	ctx := context.Background()
+
+	conn, err := sqlite3.Connect(activerecord.DatabaseConfig{
+		Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	m := migration.Migrator{Conn: conn, Migrations: []migration.Migration{
+		{Version: "1", Name: "invalid", Up: "CREATE TABLE;"},
+	}}
+
+	_, err = m.Migrate(ctx)
+	assert.Error(t, err)
+
+	pending, err := m.Pending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
@@ -0,0 +1,119 @@
+package activerecord
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Direction is the direction of ordering.
+type Direction string
+
+const (
+	Asc  Direction = "ASC"
+	Desc Direction = "DESC"
+)
+
+// ErrUnknownOrder is returned on attempt to order relation by unknown
+// attribute, association or direction.
+type ErrUnknownOrder struct {
+	RecordName string
+	Order      string
+}
+
+// Error returns a string representation of the error.
+func (e ErrUnknownOrder) Error() string {
+	return fmt.Sprintf("unknown order %q for %s", e.Order, e.RecordName)
+}
+
+// Order specifies the order of records, the field is either the attribute
+// or the attribute of the association the record belongs to delimited by
+// dot, e.g. "author.name".
+//
+//	Book.Order("author.name", activerecord.Asc)
+//
+// When the field or the direction is unknown, the method returns
+// ErrUnknownOrder.
+func (rel *Relation) Order(field string, direction Direction) (*Relation, error) {
+	expr, err := rel.orderExpr(field, direction)
+	if err != nil {
+		return nil, err
+	}
+
+	newrel := rel.Copy()
+	newrel.query.Order(expr)
+	return newrel, nil
+}
+
+// OrderBy returns a new relation ordered by the list of orders, each order
+// is a hash with the "field" and optional "direction" keys. The primary key
+// is appended to the orders to make the ordering stable.
+//
+//	Book.OrderBy([]interface{}{
+//		map[string]interface{}{"field": "year", "direction": "DESC"},
+//	})
+func (rel *Relation) OrderBy(orders []interface{}) (*Relation, error) {
+	if len(orders) == 0 {
+		return rel.Copy(), nil
+	}
+
+	var (
+		newrel = rel.Copy()
+		pk     = rel.PrimaryKey()
+		stable bool
+	)
+	for _, order := range orders {
+		h, ok := toHash(order)
+		if !ok {
+			return nil, ErrUnknownOrder{RecordName: rel.name, Order: fmt.Sprint(order)}
+		}
+
+		field, _ := h["field"].(string)
+		direction := Asc
+		if d, ok := h["direction"].(string); ok {
+			direction = Direction(d)
+		}
+
+		expr, err := rel.orderExpr(field, direction)
+		if err != nil {
+			return nil, err
+		}
+		newrel.query.Order(expr)
+		stable = stable || field == pk
+	}
+
+	if !stable {
+		newrel.query.Order(fmt.Sprintf(`"%s"."%s" %s`, rel.TableName(), pk, Asc))
+	}
+	return newrel, nil
+}
+
+// orderExpr returns the expression of the order, attributes of associations
+// are ordered by the scalar subquery of the association.
+func (rel *Relation) orderExpr(field string, direction Direction) (string, error) {
+	if direction != Asc && direction != Desc {
+		return "", ErrUnknownOrder{RecordName: rel.name, Order: string(direction)}
+	}
+
+	if rel.scope.HasAttribute(field) {
+		return fmt.Sprintf(`"%s"."%s" %s`, rel.TableName(), field, direction), nil
+	}
+
+	assocName, attrName, ok := strings.Cut(field, ".")
+	if !ok {
+		return "", ErrUnknownOrder{RecordName: rel.name, Order: field}
+	}
+
+	assoc := rel.ReflectOnAssociation(assocName)
+	if assoc == nil || !assoc.Relation.scope.HasAttribute(attrName) {
+		return "", ErrUnknownOrder{RecordName: rel.name, Order: field}
+	}
+	if _, ok := assoc.Association.(*BelongsTo); !ok {
+		return "", ErrUnknownOrder{RecordName: rel.name, Order: field}
+	}
+
+	return fmt.Sprintf(`(SELECT "%s"."%s" FROM "%s" WHERE "%s"."%s" = "%s"."%s") %s`,
+		assoc.Relation.TableName(), attrName, assoc.Relation.TableName(),
+		assoc.Relation.TableName(), assoc.Relation.PrimaryKey(),
+		rel.TableName(), assoc.AssociationForeignKey(), direction,
+	), nil
+}
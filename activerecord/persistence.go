@@ -33,9 +33,10 @@ type Dependency struct {
 }
 
 type QueryOperation struct {
-	Text    string
-	Args    []interface{}
-	Columns []string
+	TableName string
+	Text      string
+	Args      []interface{}
+	Columns   []string
 }
 
 type Conn interface {
@@ -51,6 +52,27 @@ type Conn interface {
 	Close() error
 }
 
+// Pinger is implemented by connections, that could verify the connection
+// to the database is still alive.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping verifies that the connection to the database is still alive, when
+// the connection does not implement Pinger, it is considered alive.
+func Ping(ctx context.Context, conn Conn) error {
+	if pinger, ok := conn.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// SchemaDumper is implemented by connections, that could dump the schema
+// of the database as SQL statements.
+type SchemaDumper interface {
+	DumpSchema(ctx context.Context) (string, error)
+}
+
 type errConn struct {
 	err error
 }
@@ -83,6 +105,10 @@ func (c *errConn) ExecQuery(context.Context, *QueryOperation, func(activesupport
 	return c.err
 }
 
+func (c *errConn) Ping(context.Context) error {
+	return c.err
+}
+
 func (c *errConn) Close() error {
 	return c.err
 }
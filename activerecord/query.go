@@ -22,6 +22,7 @@ type QueryBuilder struct {
 	selectValues []string
 	whereValues  []Predicate
 	groupValues  []string
+	orderValues  []string
 	joinValues   []join
 }
 
@@ -32,12 +33,14 @@ func (q *QueryBuilder) copy() *QueryBuilder {
 		selectValues: make([]string, len(q.selectValues)),
 		whereValues:  make([]Predicate, len(q.whereValues)),
 		groupValues:  make([]string, len(q.groupValues)),
+		orderValues:  make([]string, len(q.orderValues)),
 		joinValues:   make([]join, len(q.joinValues)),
 	}
 
 	copy(newq.selectValues, q.selectValues)
 	copy(newq.whereValues, q.whereValues)
 	copy(newq.groupValues, q.groupValues)
+	copy(newq.orderValues, q.orderValues)
 	copy(newq.joinValues, q.joinValues)
 
 	return &newq
@@ -59,6 +62,10 @@ func (q *QueryBuilder) Group(values ...string) {
 	q.groupValues = append(q.groupValues, values...)
 }
 
+func (q *QueryBuilder) Order(values ...string) {
+	q.orderValues = append(q.orderValues, values...)
+}
+
 func (q *QueryBuilder) Join(rel *Relation, assoc Association) {
 	q.joinValues = append(q.joinValues, join{rel, assoc})
 }
@@ -98,7 +105,7 @@ func (q *QueryBuilder) String() string {
 	}
 
 	for i, where := range q.whereValues {
-		if i < len(q.whereValues)-1 {
+		if i > 0 {
 			fmt.Fprintf(&buf, ` AND`)
 		}
 		fmt.Fprintf(&buf, ` (%s)`, where.Cond)
@@ -107,6 +114,9 @@ func (q *QueryBuilder) String() string {
 	if len(q.groupValues) > 0 {
 		fmt.Fprintf(&buf, ` GROUP BY %s`, strings.Join(q.groupValues, ", "))
 	}
+	if len(q.orderValues) > 0 {
+		fmt.Fprintf(&buf, ` ORDER BY %s`, strings.Join(q.orderValues, ", "))
+	}
 	if q.limit != nil {
 		fmt.Fprintf(&buf, ` LIMIT %d`, *q.limit)
 	}
@@ -124,8 +134,9 @@ func (q *QueryBuilder) Args() []interface{} {
 
 func (q *QueryBuilder) Operation() *QueryOperation {
 	return &QueryOperation{
-		Text:    q.String(),
-		Args:    q.Args(),
-		Columns: q.selectValues,
+		TableName: q.from,
+		Text:      q.String(),
+		Args:      q.Args(),
+		Columns:   q.selectValues,
 	}
 }
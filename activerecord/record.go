@@ -230,8 +230,30 @@ func (r *ActiveRecord) Insert() (*ActiveRecord, error) {
 	return r, nil
 }
 
+// Update saves values of all attributes of the record, but the primary key.
 func (r *ActiveRecord) Update() (*ActiveRecord, error) {
-	return nil, nil
+	var (
+		pk      = r.primaryKey.AttributeName()
+		columns []string
+		args    []interface{}
+	)
+	for _, attrName := range r.AttributeNames() {
+		if attrName != pk {
+			columns = append(columns, fmt.Sprintf(`"%s" = ?`, attrName))
+			args = append(args, r.attributes.values[attrName])
+		}
+	}
+	if len(columns) == 0 {
+		return r, nil
+	}
+
+	sql := fmt.Sprintf(
+		`UPDATE "%s" SET %s WHERE "%s" = ?`, r.tableName, strings.Join(columns, ", "), pk,
+	)
+	if err := r.conn.Exec(r.Context(), sql, append(args, r.ID())...); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (r *ActiveRecord) Delete() (*ActiveRecord, error) {
@@ -32,3 +32,22 @@ func (r *Reflection) Reflection(name string) (*Relation, error) {
 	}
 	return rel, nil
 }
+
+// TableReflection returns the relation registered with the given table name.
+func (r *Reflection) TableReflection(tableName string) (*Relation, error) {
+	name, ok := r.tables[tableName]
+	if !ok {
+		return nil, errors.Errorf("unknown reflection of table %q", tableName)
+	}
+	return r.Reflection(name)
+}
+
+// ReflectOn returns the relation registered with the given model name.
+func ReflectOn(name string) (*Relation, error) {
+	return globalReflection.Reflection(name)
+}
+
+// ReflectOnTable returns the relation registered with the given table name.
+func ReflectOnTable(tableName string) (*Relation, error) {
+	return globalReflection.TableReflection(tableName)
+}
@@ -0,0 +1,150 @@
+package activerecord_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activerecord"
+	_ "github.com/activegraph/activegraph/activerecord/sqlite3"
+)
+
+func TestRelation_Filter(t *testing.T) {
+	activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	Author := activerecord.New("author", func(r *activerecord.R) {
+		r.AttrString("name")
+		r.HasMany("book")
+	})
+	Book := activerecord.New("book", func(r *activerecord.R) {
+		r.AttrString("title")
+		r.AttrInt("year")
+		r.BelongsTo("author")
+	})
+
+	initAuthorTable(t, Author.Connection())
+	initBookTable(t, Book.Connection())
+
+	_, err := Author.InsertAll(Hash{"id": 1, "name": "Frank Herbert"}, Hash{"id": 2, "name": "Herman Melville"})
+	require.NoError(t, err)
+	_, err = Book.InsertAll(
+		Hash{"title": "Dune", "year": 1965, "author_id": 1},
+		Hash{"title": "Dune Messiah", "year": 1969, "author_id": 1},
+		Hash{"title": "Moby Dick", "year": 1851, "author_id": 2},
+		Hash{"title": "100%_pure", "author_id": 2},
+	)
+	require.NoError(t, err)
+
+	type H = map[string]interface{}
+
+	tests := []struct {
+		name   string
+		filter H
+		sql    string
+		titles []string
+	}{
+		{
+			name:   "Empty",
+			filter: H{},
+			sql:    `SELECT * FROM "books"`,
+			titles: []string{"Dune", "Dune Messiah", "Moby Dick", "100%_pure"},
+		},
+		{
+			name:   "Operators",
+			filter: H{"year": H{"gt": 1900, "lt": 1969}},
+			sql:    `SELECT * FROM "books" WHERE ("books"."year" > ? AND "books"."year" < ?)`,
+			titles: []string{"Dune"},
+		},
+		{
+			name:   "In",
+			filter: H{"title": H{"in": []interface{}{"Dune", "Moby Dick"}}, "year": H{"ne": 1851}},
+			sql:    `SELECT * FROM "books" WHERE (("books"."title" IN (?, ?)) AND ("books"."year" <> ?))`,
+			titles: []string{"Dune"},
+		},
+		{
+			name:   "Contains",
+			filter: H{"title": H{"contains": "%_"}},
+			sql:    `SELECT * FROM "books" WHERE ("books"."title" LIKE ? ESCAPE '\')`,
+			titles: []string{"100%_pure"},
+		},
+		{
+			name:   "IsNull",
+			filter: H{"year": H{"isNull": true}},
+			sql:    `SELECT * FROM "books" WHERE ("books"."year" IS NULL)`,
+			titles: []string{"100%_pure"},
+		},
+		{
+			name: "Combinators",
+			filter: H{
+				"or": []interface{}{
+					H{"year": H{"eq": 1851}},
+					H{"not": H{"title": H{"contains": "Dune"}}},
+				},
+			},
+			sql:    `SELECT * FROM "books" WHERE (("books"."year" = ?) OR (NOT ("books"."title" LIKE ? ESCAPE '\')))`,
+			titles: []string{"Moby Dick", "100%_pure"},
+		},
+		{
+			name:   "EmptyOr",
+			filter: H{"or": []interface{}{}},
+			sql:    `SELECT * FROM "books" WHERE (1 = 0)`,
+			titles: nil,
+		},
+		{
+			name:   "OrMatchAll",
+			filter: H{"or": []interface{}{H{}, H{"year": H{"eq": 1851}}}},
+			sql:    `SELECT * FROM "books"`,
+			titles: []string{"Dune", "Dune Messiah", "Moby Dick", "100%_pure"},
+		},
+		{
+			name:   "EmptyAnd",
+			filter: H{"and": []interface{}{}},
+			sql:    `SELECT * FROM "books"`,
+			titles: []string{"Dune", "Dune Messiah", "Moby Dick", "100%_pure"},
+		},
+		{
+			name:   "BelongsTo",
+			filter: H{"author": H{"name": H{"eq": "Frank Herbert"}}, "year": H{"gt": 1966}},
+			sql: `SELECT * FROM "books" WHERE (("books"."author_id" IN ` +
+				`(SELECT "authors"."id" FROM "authors" WHERE "authors"."name" = ?)) AND ("books"."year" > ?))`,
+			titles: []string{"Dune Messiah"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel, err := Book.Filter(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.sql, rel.ToSQL())
+
+			books, err := rel.ToA()
+			require.NoError(t, err)
+
+			var titles []string
+			for _, book := range books {
+				titles = append(titles, book.Attribute("title").(string))
+			}
+			assert.ElementsMatch(t, tt.titles, titles)
+		})
+	}
+
+	_, err = Book.Filter(H{"author": H{"email": H{"eq": "x"}}})
+	assert.EqualError(t, err, `unknown filter "author.email" for author`)
+
+	_, err = Book.Filter(H{"title": H{"like": "%"}})
+	assert.EqualError(t, err, `unknown filter "title.like" for book`)
+
+	_, err = Book.Filter(H{"title; DROP TABLE books": H{"eq": "x"}})
+	assert.EqualError(t, err, `unknown filter "title; DROP TABLE books" for book`)
+
+	_, err = Book.Filter(H{"or": H{}})
+	assert.EqualError(t, err, `invalid value 'map[]' of filter "or" for book`)
+}
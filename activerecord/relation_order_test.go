@@ -0,0 +1,118 @@
+package activerecord_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activerecord"
+	_ "github.com/activegraph/activegraph/activerecord/sqlite3"
+)
+
+func TestRelation_Order(t *testing.T) {
+	activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	Author := activerecord.New("author", func(r *activerecord.R) {
+		r.AttrString("name")
+		r.HasMany("book")
+	})
+	Book := activerecord.New("book", func(r *activerecord.R) {
+		r.AttrString("title")
+		r.AttrInt("year")
+		r.BelongsTo("author")
+	})
+
+	initAuthorTable(t, Author.Connection())
+	initBookTable(t, Book.Connection())
+
+	_, err := Author.InsertAll(Hash{"id": 1, "name": "Melville"}, Hash{"id": 2, "name": "Herbert"})
+	require.NoError(t, err)
+	_, err = Book.InsertAll(
+		Hash{"id": 1, "title": "Omoo", "year": 1847, "author_id": 1},
+		Hash{"id": 2, "title": "Dune", "year": 1965, "author_id": 2},
+		Hash{"id": 3, "title": "Moby Dick", "year": 1851, "author_id": 1},
+		Hash{"id": 4, "title": "Dune Messiah", "year": 1969, "author_id": 2},
+	)
+	require.NoError(t, err)
+
+	type H = map[string]interface{}
+
+	titles := func(t *testing.T, rel *activerecord.Relation) []string {
+		books, err := rel.ToA()
+		require.NoError(t, err)
+
+		var titles []string
+		for _, book := range books {
+			titles = append(titles, book.Attribute("title").(string))
+		}
+		return titles
+	}
+
+	rel, err := Book.Order("year", activerecord.Desc)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "books" ORDER BY "books"."year" DESC`, rel.ToSQL())
+	assert.Equal(t, []string{"Dune Messiah", "Dune", "Moby Dick", "Omoo"}, titles(t, rel))
+
+	_, err = Book.Order("rating", activerecord.Asc)
+	assert.Equal(t, activerecord.ErrUnknownOrder{RecordName: "book", Order: "rating"}, err)
+	_, err = Book.Order("year", "sideways")
+	assert.Equal(t, activerecord.ErrUnknownOrder{RecordName: "book", Order: "sideways"}, err)
+
+	tests := []struct {
+		name   string
+		orders []interface{}
+		sql    string
+		titles []string
+	}{
+		{
+			name:   "Empty",
+			sql:    `SELECT * FROM "books"`,
+			titles: []string{"Omoo", "Dune", "Moby Dick", "Dune Messiah"},
+		},
+		{
+			name:   "Tiebreak",
+			orders: []interface{}{H{"field": "author_id", "direction": "DESC"}},
+			sql:    `SELECT * FROM "books" ORDER BY "books"."author_id" DESC, "books"."id" ASC`,
+			titles: []string{"Dune", "Dune Messiah", "Omoo", "Moby Dick"},
+		},
+		{
+			name:   "PrimaryKey",
+			orders: []interface{}{H{"field": "id", "direction": "DESC"}},
+			sql:    `SELECT * FROM "books" ORDER BY "books"."id" DESC`,
+			titles: []string{"Dune Messiah", "Moby Dick", "Dune", "Omoo"},
+		},
+		{
+			name:   "BelongsTo",
+			orders: []interface{}{H{"field": "author.name"}, H{"field": "year", "direction": "DESC"}},
+			sql: `SELECT * FROM "books" ORDER BY (SELECT "authors"."name" FROM "authors" ` +
+				`WHERE "authors"."id" = "books"."author_id") ASC, "books"."year" DESC, "books"."id" ASC`,
+			titles: []string{"Dune Messiah", "Dune", "Moby Dick", "Omoo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel, err := Book.OrderBy(tt.orders)
+			require.NoError(t, err)
+			assert.Equal(t, tt.sql, rel.ToSQL())
+			assert.Equal(t, tt.titles, titles(t, rel))
+		})
+	}
+
+	_, err = Book.OrderBy([]interface{}{H{"field": "author.email"}})
+	assert.EqualError(t, err, `unknown order "author.email" for book`)
+
+	_, err = Book.OrderBy([]interface{}{H{"field": "year; DROP TABLE books"}})
+	assert.EqualError(t, err, `unknown order "year; DROP TABLE books" for book`)
+
+	_, err = Book.OrderBy([]interface{}{H{"field": "year", "direction": "DESC; --"}})
+	assert.EqualError(t, err, `unknown order "DESC; --" for book`)
+}
@@ -103,3 +103,66 @@ func TestRelation_TransactionalInsert(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, book, 1)
 }
+
+func TestRelation_WhereMultiple(t *testing.T) {
+	activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	Book := activerecord.New("book", func(r *activerecord.R) {
+		r.AttrString("title")
+		r.AttrInt("year")
+	})
+
+	initBookTable(t, Book.Connection())
+
+	_, err := Book.InsertAll(
+		Hash{"title": "Omoo", "year": 1847}, Hash{"title": "Mardi", "year": 1849},
+		Hash{"title": "Moby Dick", "year": 1851},
+	)
+	require.NoError(t, err)
+
+	rel := Book.Where("year > ?", 1847).Where("title", "Mardi")
+	require.Equal(t, `SELECT * FROM "books" WHERE (year > ?) AND (title = ?)`, rel.ToSQL())
+
+	books, err := rel.ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, "Mardi", books[0].Attribute("title"))
+}
+
+func TestActiveRecord_Update(t *testing.T) {
+	activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	Author := activerecord.New("author", func(r *activerecord.R) {
+		r.AttrString("name")
+	})
+
+	initAuthorTable(t, Author.Connection())
+
+	authors, err := Author.InsertAll(Hash{"name": "First"}, Hash{"name": "Second"})
+	require.NoError(t, err)
+
+	author := Author.Find(authors[0].ID())
+	require.NoError(t, author.Err())
+	require.NoError(t, author.UnwrapRecord().AssignAttribute("name", "Updated"))
+	require.NoError(t, author.Update().Err())
+
+	author = Author.Find(authors[0].ID())
+	require.NoError(t, author.Err())
+	require.Equal(t, "Updated", author.UnwrapRecord().Attribute("name"))
+
+	author = Author.Find(authors[1].ID())
+	require.NoError(t, author.Err())
+	require.Equal(t, "Second", author.UnwrapRecord().Attribute("name"))
+}
@@ -34,6 +34,18 @@ func Connect(conf activerecord.DatabaseConfig) (activerecord.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(conf.Pool)
+	db.SetConnMaxIdleTime(conf.IdleTimeout)
+
+	if conf.ConnectTimeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), conf.ConnectTimeout)
+		defer cancel()
+
+		if err = db.PingContext(ctx); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
 	return &Conn{db: db, querier: db}, nil
 }
 
@@ -44,6 +56,10 @@ func (c *Conn) Close() error {
 	return c.db.Close()
 }
 
+func (c *Conn) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
 func (c *Conn) BeginTransaction(ctx context.Context) (activerecord.Conn, error) {
 	tx, err := c.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -137,13 +153,22 @@ func (c *Conn) ExecQuery(
 
 	defer rws.Close()
 
+	// Columns of the result are used, when the operation does not specify
+	// them, e.g. for the raw SQL queries.
+	columns := op.Columns
+	if len(columns) == 0 {
+		if columns, err = rws.Columns(); err != nil {
+			return err
+		}
+	}
+
 	for rws.Next() {
 		var (
 			// Iterate over rows and scan one-by one.
 			row = make(activesupport.Hash)
 			// Initalize a list of interface pointer, so the Scan operation could
 			// assign the results to the each element of the list.
-			vals = make([]interface{}, len(op.Columns))
+			vals = make([]interface{}, len(columns))
 		)
 
 		for i := range vals {
@@ -153,7 +178,7 @@ func (c *Conn) ExecQuery(
 			return err
 		}
 		for i := range vals {
-			row[op.Columns[i]] = *(vals[i]).(*interface{})
+			row[columns[i]] = *(vals[i]).(*interface{})
 		}
 
 		// Terminate the querying and close the reading cursor.
@@ -164,3 +189,30 @@ func (c *Conn) ExecQuery(
 
 	return nil
 }
+
+// DumpSchema returns SQL statements, that create tables and indexes of the
+// database.
+func (c *Conn) DumpSchema(ctx context.Context) (string, error) {
+	rws, err := c.querier.QueryContext(ctx, `
+		SELECT sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+		ORDER BY CASE type WHEN 'table' THEN 0 ELSE 1 END, name
+	`)
+	if err != nil {
+		return "", err
+	}
+	defer rws.Close()
+
+	var stmts []string
+	for rws.Next() {
+		var stmt string
+		if err = rws.Scan(&stmt); err != nil {
+			return "", err
+		}
+		stmts = append(stmts, stmt+";\n")
+	}
+	if err = rws.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(stmts, "\n"), nil
+}
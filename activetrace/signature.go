@@ -0,0 +1,204 @@
+package activetrace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+	"github.com/pkg/errors"
+
+	"github.com/activegraph/activegraph"
+)
+
+// signature returns a normalized signature of the operation selected for
+// execution, so operations that differ only in literals, aliases, order of
+// fields and formatting share the same signature:
+//
+//	# Posts
+//	query Posts($first:Int){posts(first:$first,title:""){author title}}
+//
+// Literals are hidden, aliases are removed, selections, arguments and
+// directives are sorted, unused fragments are dropped and whitespace is
+// minimized. Anonymous operations are named "-".
+func signature(r *activegraph.Request) (string, error) {
+	opdef := r.OperationDefinition()
+	if opdef == nil {
+		return "", errors.New("activetrace: operation is not defined")
+	}
+
+	// Normalization modifies the document, therefore the copy is parsed
+	// from the printed source of the request document.
+	src, _ := printer.Print(r.Document()).(string)
+	doc, err := parser.Parse(parser.ParseParams{Source: src})
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		fragments = make(map[string]*ast.FragmentDefinition)
+		operation *ast.OperationDefinition
+	)
+	for i, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if r.Document().Definitions[i] == ast.Node(opdef) {
+				operation = def
+			}
+		}
+	}
+	if operation == nil {
+		return "", errors.New("activetrace: operation is not found in the document")
+	}
+
+	used := make(map[string]bool)
+	normalizeSelections(operation.SelectionSet, fragments, used)
+	normalizeVariables(operation.VariableDefinitions)
+	normalizeDirectives(operation.Directives)
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sig strings.Builder
+	sig.WriteString(minimize(printer.Print(operation)))
+	for _, name := range names {
+		sig.WriteString(minimize(printer.Print(fragments[name])))
+	}
+
+	name := "-"
+	if operation.Name != nil {
+		name = operation.Name.Value
+	}
+	return "# " + name + "\n" + sig.String(), nil
+}
+
+// normalizeSelections normalizes the selection set and marks fragments
+// spread within the selection set as used.
+func normalizeSelections(
+	selset *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, used map[string]bool,
+) {
+	if selset == nil {
+		return
+	}
+
+	for _, sel := range selset.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			sel.Alias = nil
+			normalizeArguments(sel.Arguments)
+			normalizeDirectives(sel.Directives)
+			normalizeSelections(sel.SelectionSet, fragments, used)
+		case *ast.InlineFragment:
+			normalizeDirectives(sel.Directives)
+			normalizeSelections(sel.SelectionSet, fragments, used)
+		case *ast.FragmentSpread:
+			normalizeDirectives(sel.Directives)
+
+			name := sel.Name.Value
+			if fragment, ok := fragments[name]; ok && !used[name] {
+				used[name] = true
+				normalizeDirectives(fragment.Directives)
+				normalizeSelections(fragment.SelectionSet, fragments, used)
+			}
+		}
+	}
+
+	// Fields go first, then fragment spreads and inline fragments.
+	sort.SliceStable(selset.Selections, func(i, j int) bool {
+		ki, ni := selectionKey(selset.Selections[i])
+		kj, nj := selectionKey(selset.Selections[j])
+		if ki != kj {
+			return ki < kj
+		}
+		return ni < nj
+	})
+}
+
+func selectionKey(sel ast.Selection) (kind int, name string) {
+	switch sel := sel.(type) {
+	case *ast.Field:
+		return 0, sel.Name.Value
+	case *ast.FragmentSpread:
+		return 1, sel.Name.Value
+	case *ast.InlineFragment:
+		if sel.TypeCondition != nil {
+			return 2, sel.TypeCondition.Name.Value
+		}
+		return 2, ""
+	}
+	return 3, ""
+}
+
+func normalizeArguments(args []*ast.Argument) {
+	for _, arg := range args {
+		arg.Value = hideLiteral(arg.Value)
+	}
+	sort.SliceStable(args, func(i, j int) bool {
+		return args[i].Name.Value < args[j].Name.Value
+	})
+}
+
+func normalizeDirectives(directives []*ast.Directive) {
+	for _, directive := range directives {
+		normalizeArguments(directive.Arguments)
+	}
+	sort.SliceStable(directives, func(i, j int) bool {
+		return directives[i].Name.Value < directives[j].Name.Value
+	})
+}
+
+func normalizeVariables(vars []*ast.VariableDefinition) {
+	for _, v := range vars {
+		if v.DefaultValue != nil {
+			v.DefaultValue = hideLiteral(v.DefaultValue)
+		}
+	}
+	sort.SliceStable(vars, func(i, j int) bool {
+		return vars[i].Variable.Name.Value < vars[j].Variable.Name.Value
+	})
+}
+
+// hideLiteral replaces numbers with zero, strings with an empty string,
+// lists and objects with empty ones. Variables, enums and booleans are
+// left as is, since they do not contain user data.
+func hideLiteral(value ast.Value) ast.Value {
+	switch value.(type) {
+	case *ast.IntValue, *ast.FloatValue:
+		return ast.NewIntValue(&ast.IntValue{Value: "0"})
+	case *ast.StringValue:
+		return ast.NewStringValue(&ast.StringValue{Value: ""})
+	case *ast.ListValue:
+		return ast.NewListValue(&ast.ListValue{})
+	case *ast.ObjectValue:
+		return ast.NewObjectValue(&ast.ObjectValue{})
+	}
+	return value
+}
+
+// minimize removes all whitespace of the printed node, except the
+// whitespace separating words.
+func minimize(printed interface{}) string {
+	var (
+		b      strings.Builder
+		fields = strings.Fields(fmt.Sprint(printed))
+	)
+	for i, field := range fields {
+		if i > 0 && isWord(fields[i-1][len(fields[i-1])-1]) && isWord(field[0]) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(field)
+	}
+	return b.String()
+}
+
+func isWord(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
@@ -1,37 +1,362 @@
 package activetrace
 
 import (
+	"context"
+	"encoding/json"
 	"reflect"
+	"strconv"
 	"time"
 
+	"github.com/graphql-go/graphql/gqlerrors"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/activegraph/activegraph"
+	"github.com/activegraph/activegraph/actioncontroller"
+	actiongraphql "github.com/activegraph/activegraph/actioncontroller/graphql"
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activesupport"
 )
 
-// DefineMetricsFunc retruns a closure to measure the duration of the function.
-func DefineMetricsFunc(subsystem string) activegraph.ClosureDef {
-	requestDurationHistogramVec := promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
+// defaultErrorCode is a code of errors without "code" extension.
+const defaultErrorCode = "INTERNAL_SERVER_ERROR"
+
+// otherOperation is a label of operations, which names are not allowed
+// by WithOperations option.
+const otherOperation = "other"
+
+// Metrics is a set of Prometheus collectors of the GraphQL server.
+//
+//	metrics := activetrace.NewMetrics(prometheus.DefaultRegisterer, "blog")
+//
+//	var c activegraph.Controller
+//	metrics.Instrument(&c)
+//
+// Collectors are registered only once, metrics created for the same
+// registerer and subsystem share the collectors.
+//
+// Names of operations are set by clients, so operations are counted under
+// the "other" label, unless names are allowed with WithOperations option.
+type Metrics struct {
+	operationNames map[string]bool
+
+	operations    *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	inflight      prometheus.Gauge
+	requestSize   *prometheus.HistogramVec
+	responseSize  *prometheus.HistogramVec
+	fieldDuration *prometheus.HistogramVec
+	sqlDuration   *prometheus.HistogramVec
+}
+
+// MetricsOption configures the Prometheus metrics.
+type MetricsOption func(*Metrics)
+
+// WithOperations specifies names of operations counted under their own
+// label, e.g. names of persisted operations. Other operations are counted
+// under the "other" label, so clients cannot create unbounded number of
+// metric series.
+func WithOperations(names ...string) MetricsOption {
+	return func(m *Metrics) {
+		for _, name := range names {
+			m.operationNames[name] = true
+		}
+	}
+}
+
+// NewMetrics creates collectors of the GraphQL server metrics and registers
+// them within the registerer. Method panics when any of collectors cannot be
+// registered.
+func NewMetrics(reg prometheus.Registerer, subsystem string, opts ...MetricsOption) *Metrics {
+	sizeBuckets := prometheus.ExponentialBuckets(64, 4, 8)
+
+	m := &Metrics{
+		operationNames: make(map[string]bool),
+
+		operations: register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "graphql_operations_total",
+			Help:      "Number of executed GraphQL operations.",
+		}, []string{"operation", "type"})).(*prometheus.CounterVec),
+
+		errors: register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "graphql_errors_total",
+			Help:      "Number of GraphQL errors by error code.",
+		}, []string{"code"})).(*prometheus.CounterVec),
+
+		inflight: register(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "graphql_requests_in_flight",
+			Help:      "Number of GraphQL operations being executed.",
+		})).(prometheus.Gauge),
+
+		requestSize: register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "graphql_request_size_bytes",
+			Help:      "Size of GraphQL query and variables.",
+			Buckets:   sizeBuckets,
+		}, []string{"type"})).(*prometheus.HistogramVec),
+
+		responseSize: register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "graphql_response_size_bytes",
+			Help:      "Size of GraphQL results.",
+			Buckets:   sizeBuckets,
+		}, []string{"type"})).(*prometheus.HistogramVec),
+
+		fieldDuration: register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "graphql_field_duration_seconds",
+			Help:      "Duration of GraphQL field resolvers.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"field", "error"})).(*prometheus.HistogramVec),
+
+		sqlDuration: register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Subsystem: subsystem,
-			Name:      "request_duration_seconds",
+			Name:      "sql_query_duration_seconds",
+			Help:      "Duration of SQL queries.",
 			Buckets:   prometheus.DefBuckets,
-		},
-		[]string{"query"},
-	)
+		}, []string{"table", "operation"})).(*prometheus.HistogramVec),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// register registers the collector, and returns already registered
+// collector, when it's a duplicate.
+func register(reg prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	if err := reg.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return c
+}
+
+// Instrument appends the metrics callback to queries and mutations of the
+// controller, and encloses all functions and actions of mapped resources
+// of the controller with the metrics closure.
+func (m *Metrics) Instrument(c *activegraph.Controller) {
+	c.AppendAroundOp(activegraph.OperationQuery, m.Callback())
+	c.AppendAroundOp(activegraph.OperationMutation, m.Callback())
+
+	if c.Resources != nil {
+		c.Resources.EncloseActions(m.DefineAction())
+	}
+
+	closure := m.DefineFunc()
+	for i := range c.Queries {
+		c.Queries[i] = activegraph.EncloseFunc(c.Queries[i], closure)
+	}
+	for i := range c.Mutations {
+		c.Mutations[i] = activegraph.EncloseFunc(c.Mutations[i], closure)
+	}
+	for i := range c.Types {
+		if c.Types[i].Funcs == nil {
+			continue
+		}
+		funcs := make(map[string]activegraph.FuncDef, len(c.Types[i].Funcs))
+		for name, funcdef := range c.Types[i].Funcs {
+			funcs[name] = activegraph.EncloseFunc(funcdef, closure)
+		}
+		c.Types[i].Funcs = funcs
+	}
+}
+
+// Callback returns a callback that counts operations and errors of the
+// result, and measures sizes of the request and response.
+//
+// The size of the response is the number of bytes written to the client,
+// so it is measured only for writers implementing WrittenNotifier, e.g.
+// writers of the HTTP handler.
+func (m *Metrics) Callback() activegraph.AroundCallback {
+	return func(rw activegraph.ResponseWriter, r *activegraph.Request, h activegraph.Handler) {
+		m.inflight.Inc()
+		defer m.inflight.Dec()
+
+		// The operation type could be omitted in the shorthand query.
+		op := r.Operation()
+		if op == activegraph.OperationUnknown {
+			op = activegraph.OperationQuery
+		}
+		m.operations.WithLabelValues(m.operationLabel(r), op).Inc()
+
+		size := len(r.Query)
+		if len(r.Variables) > 0 {
+			b, _ := json.Marshal(r.Variables)
+			size += len(b)
+		}
+		m.requestSize.WithLabelValues(op).Observe(float64(size))
+
+		activegraph.NotifyWritten(rw, func(n int) {
+			m.responseSize.WithLabelValues(op).Observe(float64(n))
+		})
+
+		rec := newRecorder(rw)
+		h.Serve(rec, r)
+
+		for _, err := range rec.errors() {
+			m.errors.WithLabelValues(errorCode(err)).Inc()
+		}
+	}
+}
 
+// operationLabel returns a name of the operation, when it is allowed,
+// otherwise "other".
+func (m *Metrics) operationLabel(r *activegraph.Request) string {
+	if name := operationName(r); m.operationNames[name] {
+		return name
+	}
+	return otherOperation
+}
+
+// errorCode returns a "code" extension of the GraphQL error.
+func errorCode(err error) string {
+	if e, ok := err.(gqlerrors.FormattedError); ok {
+		if code, ok := e.Extensions["code"].(string); ok && code != "" {
+			return code
+		}
+	}
+	return defaultErrorCode
+}
+
+// DefineFunc returns a closure to measure the duration of the function.
+func (m *Metrics) DefineFunc() activegraph.ClosureDef {
 	return func(funcdef activegraph.FuncDef, in []reflect.Value) []reflect.Value {
 		start := time.Now()
-		defer func() {
-			hist := requestDurationHistogramVec.WithLabelValues(funcdef.Name)
-			hist.Observe(time.Since(start).Seconds())
-		}()
+		out := funcdef.Func.Call(in)
+
+		failed := !out[len(out)-1].IsNil()
+		m.fieldDuration.WithLabelValues(funcdef.Name, strconv.FormatBool(failed)).
+			Observe(time.Since(start).Seconds())
+		return out
+	}
+}
+
+// DefineAction returns a closure to measure the duration of actions of the
+// mapped resources, durations are observed under the name of the field.
+func (m *Metrics) DefineAction() actiongraphql.ActionClosure {
+	return func(field string, action actioncontroller.Action) actioncontroller.Action {
+		return &instrumentedAction{Action: action, field: field, metrics: m}
+	}
+}
+
+// instrumentedAction is an action that measures the duration of processing
+// and execution of the action result.
+type instrumentedAction struct {
+	actioncontroller.Action
+	field   string
+	metrics *Metrics
+}
+
+func (a *instrumentedAction) ActionPermitted() *actioncontroller.StrongParameters {
+	return actioncontroller.PermittedParameters(a.Action)
+}
+
+func (a *instrumentedAction) Process(ctx *actioncontroller.Context) actioncontroller.Result {
+	return &instrumentedResult{a, time.Now(), a.Action.Process(ctx)}
+}
+
+// instrumentedResult observes the duration of the action, when the result
+// is executed.
+type instrumentedResult struct {
+	action *instrumentedAction
+	start  time.Time
+	result actioncontroller.Result
+}
+
+func (r *instrumentedResult) Execute(ctx *actioncontroller.Context) (interface{}, error) {
+	v, err := r.result.Execute(ctx)
+	r.action.metrics.fieldDuration.WithLabelValues(r.action.field, strconv.FormatBool(err != nil)).
+		Observe(time.Since(r.start).Seconds())
+	return v, err
+}
 
-		res, err := funcdef.Call(in)
-		return []reflect.Value{
-			reflect.ValueOf(res),
-			reflect.ValueOf(err),
+// ConnectionAdapter returns a connection adapter that measures the duration
+// of SQL queries executed through connections of the given adapter.
+//
+//	activerecord.RegisterConnectionAdapter("sqlite3+metrics", metrics.ConnectionAdapter(sqlite3.Connect))
+func (m *Metrics) ConnectionAdapter(ca activerecord.ConnectionAdapter) activerecord.ConnectionAdapter {
+	return func(conf activerecord.DatabaseConfig) (activerecord.Conn, error) {
+		conn, err := ca(conf)
+		if err != nil {
+			return nil, err
 		}
+		return &instrumentedConn{conn, m}, nil
+	}
+}
+
+// instrumentedConn is a connection that measures the duration of SQL queries.
+type instrumentedConn struct {
+	activerecord.Conn
+	metrics *Metrics
+}
+
+func (c *instrumentedConn) observe(table, op string, start time.Time) {
+	c.metrics.sqlDuration.WithLabelValues(table, op).Observe(time.Since(start).Seconds())
+}
+
+func (c *instrumentedConn) BeginTransaction(ctx context.Context) (activerecord.Conn, error) {
+	conn, err := c.Conn.BeginTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{conn, c.metrics}, nil
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	return activerecord.Ping(ctx, c.Conn)
+}
+
+func (c *instrumentedConn) Exec(ctx context.Context, query string, args ...interface{}) error {
+	defer c.observe("", "exec", time.Now())
+	return c.Conn.Exec(ctx, query, args...)
+}
+
+func (c *instrumentedConn) ExecInsert(
+	ctx context.Context, op *activerecord.InsertOperation,
+) (interface{}, error) {
+	defer c.observe(op.TableName, "insert", time.Now())
+	return c.Conn.ExecInsert(ctx, op)
+}
+
+func (c *instrumentedConn) ExecDelete(ctx context.Context, op *activerecord.DeleteOperation) error {
+	defer c.observe(op.TableName, "delete", time.Now())
+	return c.Conn.ExecDelete(ctx, op)
+}
+
+func (c *instrumentedConn) ExecQuery(
+	ctx context.Context, op *activerecord.QueryOperation, cb func(activesupport.Hash) bool,
+) error {
+	defer c.observe(op.TableName, "select", time.Now())
+	return c.Conn.ExecQuery(ctx, op, cb)
+}
+
+// DefineMetricsFunc retruns a closure to measure the duration of the function.
+//
+// The duration is observed by the "request_duration_seconds" histogram with
+// the "query" label, as in previous versions, so existing dashboards and
+// alerts keep working.
+//
+// Deprecated: use NewMetrics and Metrics.DefineFunc instead, which allow to
+// specify the registerer. Note, that Metrics.DefineFunc observes the duration
+// by the "graphql_field_duration_seconds" histogram with "field" and "error"
+// labels.
+func DefineMetricsFunc(subsystem string) activegraph.ClosureDef {
+	duration := register(prometheus.DefaultRegisterer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: subsystem,
+		Name:      "request_duration_seconds",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"query"})).(*prometheus.HistogramVec)
+
+	return func(funcdef activegraph.FuncDef, in []reflect.Value) []reflect.Value {
+		start := time.Now()
+		out := funcdef.Func.Call(in)
+
+		duration.WithLabelValues(funcdef.Name).Observe(time.Since(start).Seconds())
+		return out
 	}
 }
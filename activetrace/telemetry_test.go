@@ -0,0 +1,243 @@
+package activetrace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph"
+	"github.com/activegraph/activegraph/actioncontroller"
+	"github.com/activegraph/activegraph/actionview"
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activerecord/sqlite3"
+	"github.com/activegraph/activegraph/activesupport"
+)
+
+type codeError struct{}
+
+func (codeError) Error() string { return "not found" }
+
+func (codeError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "NOT_FOUND"}
+}
+
+// histogramCounts returns sample counts of the histogram by label values,
+// joined in the order of label names.
+func histogramCounts(t *testing.T, reg *prometheus.Registry, name string) map[string]uint64 {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	counts := make(map[string]uint64)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			counts[labelValues(m)] = m.GetHistogram().GetSampleCount()
+		}
+	}
+	return counts
+}
+
+func labelValues(m *dto.Metric) (s string) {
+	for i, label := range m.GetLabel() {
+		if i > 0 {
+			s += ","
+		}
+		s += label.GetName() + "=" + label.GetValue()
+	}
+	return s
+}
+
+func TestNewMetrics_Duplicate(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	m1 := NewMetrics(reg, "test")
+	m2 := NewMetrics(reg, "test")
+	assert.Same(t, m1.operations, m2.operations)
+	assert.NotPanics(t, func() { DefineMetricsFunc("test_duplicate") })
+	assert.NotPanics(t, func() { DefineMetricsFunc("test_duplicate") })
+
+	// Deprecated function keeps the name of the metric.
+	err := prometheus.DefaultRegisterer.Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "test_duplicate",
+		Name:      "request_duration_seconds",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"query"}))
+	assert.IsType(t, prometheus.AlreadyRegisteredError{}, err)
+}
+
+func TestMetrics_Instrument(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewMetrics(reg, "test", WithOperations("Posts"))
+
+	c := activegraph.Controller{
+		Queries: []activegraph.FuncDef{
+			activegraph.NewFunc("posts", func(ctx context.Context) ([]string, error) {
+				assert.Equal(t, 1.0, testutil.ToFloat64(m.inflight))
+				return []string{"First"}, nil
+			}),
+			activegraph.NewFunc("fail", func(ctx context.Context) (*string, error) {
+				return nil, errors.New("failed")
+			}),
+			activegraph.NewFunc("find", func(ctx context.Context) (*string, error) {
+				return nil, codeError{}
+			}),
+		},
+	}
+	m.Instrument(&c)
+	h := c.HandleHTTP()
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?query=query+Posts{posts,fail,find}", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	// Names of operations, that are not allowed, are counted as "other".
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?query=query+Random{posts}", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.operations.WithLabelValues("Posts", "query")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.operations.WithLabelValues("other", "query")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.operations))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.errors.WithLabelValues("INTERNAL_SERVER_ERROR")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.errors.WithLabelValues("NOT_FOUND")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.inflight))
+
+	assert.Equal(t, map[string]uint64{
+		"error=true,field=fail":   1,
+		"error=true,field=find":   1,
+		"error=false,field=posts": 2,
+	}, histogramCounts(t, reg, "test_graphql_field_duration_seconds"))
+
+	assert.Equal(t, map[string]uint64{"type=query": 2},
+		histogramCounts(t, reg, "test_graphql_request_size_bytes"))
+	assert.Equal(t, map[string]uint64{"type=query": 2},
+		histogramCounts(t, reg, "test_graphql_response_size_bytes"))
+}
+
+func TestMetrics_ResponseSize(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewMetrics(reg, "test")
+
+	c := activegraph.Controller{
+		Queries: []activegraph.FuncDef{
+			activegraph.NewFunc("posts", func(ctx context.Context) ([]string, error) {
+				return []string{"First", "Second"}, nil
+			}),
+		},
+	}
+	m.Instrument(&c)
+
+	// The type of the shorthand query is omitted.
+	rw := httptest.NewRecorder()
+	c.HandleHTTP().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?query={posts}", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.operations.WithLabelValues("other", "query")))
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	var size *dto.Histogram
+	for _, family := range families {
+		if family.GetName() == "test_graphql_response_size_bytes" {
+			size = family.GetMetric()[0].GetHistogram()
+		}
+	}
+	require.NotNil(t, size)
+	assert.Equal(t, uint64(1), size.GetSampleCount())
+	assert.Equal(t, float64(rw.Body.Len()), size.GetSampleSum())
+}
+
+func TestMetrics_InstrumentResources(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer activerecord.RemoveConnection("primary")
+
+	Tome := activerecord.New("tome", func(r *activerecord.R) {
+		r.AttrString("title")
+	})
+	err = Tome.Connection().Exec(context.Background(), `
+		CREATE TABLE tomes (id INTEGER PRIMARY KEY, title VARCHAR);
+		INSERT INTO tomes (id, title) VALUES (1, 'Moby-Dick');
+	`)
+	require.NoError(t, err)
+
+	tomes := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Index(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.ViewResult(activesupport.Return(Tome.WithContext(ctx).All().ToA()))
+		})
+		c.Show(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.ViewResult(Tome.WithContext(ctx).Find(ctx.Params["id"]))
+		})
+	})
+
+	reg := prometheus.NewRegistry()
+	m := NewMetrics(reg, "test")
+
+	var c activegraph.Controller
+	c.HandleResources(Tome, tomes)
+	m.Instrument(&c)
+
+	q := url.Values{"query": {`{tomes{title},tome(id:1){title},missing:tome(id:2){title}}`}}
+	rw := httptest.NewRecorder()
+	c.HandleHTTP().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil))
+	require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+
+	assert.Equal(t, map[string]uint64{
+		"error=false,field=tomes": 1,
+		"error=false,field=tome":  1,
+		"error=true,field=tome":   1,
+	}, histogramCounts(t, reg, "test_graphql_field_duration_seconds"))
+}
+
+func TestMetrics_ConnectionAdapter(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewMetrics(reg, "test")
+
+	activerecord.RegisterConnectionAdapter("sqlite3+metrics", m.ConnectionAdapter(sqlite3.Connect))
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3+metrics",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	Author := activerecord.New("author", func(r *activerecord.R) {
+		r.AttrString("name")
+	})
+
+	err = Author.Connection().Exec(context.TODO(), `
+		CREATE TABLE authors (id INTEGER NOT NULL, name VARCHAR, PRIMARY KEY(id));
+	`)
+	require.NoError(t, err)
+
+	_, err = Author.Create(map[string]interface{}{"name": "Herman Melville"})
+	require.NoError(t, err)
+
+	authors, err := Author.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+
+	assert.Equal(t, map[string]uint64{
+		"operation=exec,table=":          1,
+		"operation=insert,table=authors": 1,
+		"operation=select,table=authors": 1,
+	}, histogramCounts(t, reg, "test_sql_query_duration_seconds"))
+}
@@ -2,74 +2,266 @@ package activetrace
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"time"
 
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
-	"github.com/opentracing/opentracing-go/log"
+	"github.com/graphql-go/graphql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/activegraph/activegraph"
 )
 
-func DefineTracingFunc(tracer opentracing.Tracer) activegraph.ClosureDef {
-	return func(funcdef activegraph.FuncDef, in []reflect.Value) []reflect.Value {
-		var (
-			ctx context.Context = context.Background()
-		)
+// instrumentationName identifies the tracer and the meter of the package.
+const instrumentationName = "github.com/activegraph/activegraph/activetrace"
+
+// fieldNameKey is an attribute of the resolver span, GraphQL semantic
+// conventions define attributes only for operations.
+const fieldNameKey = attribute.Key("graphql.field.name")
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+}
+
+// Option configures the OpenTelemetry instrumentation.
+type Option func(*config)
+
+// WithTracerProvider specifies a tracer provider, by default the global
+// tracer provider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) { c.tracerProvider = tp }
+}
+
+// WithMeterProvider specifies a meter provider, by default the global
+// meter provider is used.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) { c.meterProvider = mp }
+}
+
+// WithPropagators specifies propagators used to extract the trace context
+// from the request headers, by default W3C Trace Context and Baggage are used.
+func WithPropagators(p propagation.TextMapPropagator) Option {
+	return func(c *config) { c.propagators = p }
+}
+
+func newConfig(opts []Option) *config {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagators: propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{}, propagation.Baggage{},
+		),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
 
-		if len(in) > 1 {
+func (c *config) tracer() trace.Tracer {
+	return c.tracerProvider.Tracer(instrumentationName)
+}
+
+func (c *config) histogram(name, description string) metric.Float64Histogram {
+	hist, err := c.meterProvider.Meter(instrumentationName).Float64Histogram(name,
+		metric.WithDescription(description),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		return noop.Float64Histogram{}
+	}
+	return hist
+}
+
+// DefineTracingFunc returns a closure that starts a span for each call of
+// the function and records the duration of the call into the
+// "graphql.server.resolver.duration" histogram.
+//
+// Errors returned by the function are recorded within the span and set as
+// the "error.type" attribute of the measurement.
+//
+//	funcdef := activegraph.EncloseFunc(
+//		activegraph.NewFunc("author", author), activetrace.DefineTracingFunc(),
+//	)
+func DefineTracingFunc(opts ...Option) activegraph.ClosureDef {
+	var (
+		c        = newConfig(opts)
+		tracer   = c.tracer()
+		duration = c.histogram("graphql.server.resolver.duration", "Duration of GraphQL resolvers.")
+	)
+
+	return func(funcdef activegraph.FuncDef, in []reflect.Value) []reflect.Value {
+		ctx := context.Background()
+		if len(in) > 0 {
 			// Retrieve the context from the list of arguments.
 			if funcCtx, ok := in[0].Interface().(context.Context); ok {
 				ctx = funcCtx
 			}
 		}
 
-		span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, tracer, funcdef.Name)
-		defer span.Finish()
-
-		res, err := funcdef.Call(in)
+		attrs := []attribute.KeyValue{fieldNameKey.String(funcdef.Name)}
+		ctx, span := tracer.Start(ctx, funcdef.Name,
+			trace.WithSpanKind(trace.SpanKindInternal),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
 
-		if err != nil {
-			ext.Error.Set(span, true)
-			span.LogFields(
-				log.String("event", "error"),
-				log.String("message", err.Error()),
-			)
+		// Pass the span to the function, so nested spans are children
+		// of the resolver span.
+		if len(in) > 0 {
+			in[0] = reflect.ValueOf(ctx)
 		}
 
-		return []reflect.Value{
-			reflect.ValueOf(res),
-			reflect.ValueOf(err),
+		start := time.Now()
+		out := funcdef.Func.Call(in)
+
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			attrs = append(attrs, semconv.ErrorTypeKey.String(fmt.Sprintf("%T", err)))
 		}
+		duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+
+		// Return values are passed as is, so nil results keep their types.
+		return out
 	}
 }
 
-// TracingCallback returns an http.Handler with opentracing context.
+// TracingCallback returns a callback that starts a server span for each
+// GraphQL operation, and records the duration of the operation into the
+// "graphql.server.operation.duration" histogram.
+//
+// The trace context is extracted from the request headers (W3C "traceparent"
+// by default), requests without trace context start a new trace. Errors of
+// the GraphQL result are recorded within the span.
 //
-// The new Handler calls h.ServeHTTP to handle each request, it open
-// span on each new request, logs query and variables, then closes
-// span when handler finishes execution.
-func TracingCallback(tracer opentracing.Tracer) activegraph.AroundCallback {
+//	var c activegraph.Controller
+//	c.AppendAroundOp(activegraph.OperationQuery, activetrace.TracingCallback())
+//	c.AppendAroundOp(activegraph.OperationMutation, activetrace.TracingCallback())
+func TracingCallback(opts ...Option) activegraph.AroundCallback {
+	var (
+		c        = newConfig(opts)
+		tracer   = c.tracer()
+		duration = c.histogram("graphql.server.operation.duration", "Duration of GraphQL operations.")
+	)
+
 	return func(rw activegraph.ResponseWriter, r *activegraph.Request, h activegraph.Handler) {
-		wireContext, err := tracer.Extract(
-			opentracing.HTTPHeaders,
-			opentracing.HTTPHeadersCarrier(r.Header),
-		)
-		if err != nil {
-			return
+		ctx := c.propagators.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		attrs := operationAttributes(r)
+		name := r.Operation()
+		if opname := operationName(r); opname != "" {
+			name += " " + opname
 		}
 
-		span := opentracing.StartSpan(r.OperationName, ext.RPCServerOption(wireContext))
-		span.LogFields(
-			log.String("query", r.Query),
-			log.Object("variables", r.Variables),
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+			trace.WithAttributes(semconv.GraphqlDocument(r.Query)),
 		)
+		defer span.End()
+
+		start := time.Now()
+		rec := newRecorder(rw)
+		h.Serve(rec, r.WithContext(ctx))
+
+		if errs := rec.errors(); len(errs) > 0 {
+			for _, err := range errs {
+				span.RecordError(err)
+			}
+			span.SetStatus(codes.Error, errs[0].Error())
+			attrs = append(attrs, semconv.ErrorTypeKey.String("graphql"))
+		}
+		duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	}
+}
+
+func operationAttributes(r *activegraph.Request) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	switch r.Operation() {
+	case activegraph.OperationQuery:
+		attrs = append(attrs, semconv.GraphqlOperationTypeQuery)
+	case activegraph.OperationMutation:
+		attrs = append(attrs, semconv.GraphqlOperationTypeMutation)
+	case activegraph.OperationSubscription:
+		attrs = append(attrs, semconv.GraphqlOperationTypeSubscription)
+	}
+	if name := operationName(r); name != "" {
+		attrs = append(attrs, semconv.GraphqlOperationName(name))
+	}
+	return attrs
+}
+
+// operationName returns a name of the executed operation, the name could be
+// omitted in the request, when the document defines a single operation.
+func operationName(r *activegraph.Request) string {
+	if opdef := r.OperationDefinition(); opdef != nil && opdef.Name != nil {
+		return opdef.Name.Value
+	}
+	return r.OperationName
+}
 
-		defer span.Finish()
+// recorder records errors of the results written by the handler.
+type recorder struct {
+	activegraph.ResponseWriter
+	errs []error
+}
 
-		ctx := opentracing.ContextWithSpan(r.Context(), span)
-		r = r.WithContext(ctx)
+// streamRecorder records errors of the incremental results.
+type streamRecorder struct {
+	*recorder
+	srw activegraph.StreamResponseWriter
+}
 
-		h.Serve(rw, r)
+func newRecorder(rw activegraph.ResponseWriter) interface {
+	activegraph.ResponseWriter
+	errors() []error
+} {
+	rec := &recorder{ResponseWriter: rw}
+	if srw, ok := rw.(activegraph.StreamResponseWriter); ok {
+		return streamRecorder{rec, srw}
+	}
+	return rec
+}
+
+func (rec *recorder) Write(res *graphql.Result) error {
+	if res != nil {
+		for _, err := range res.Errors {
+			rec.errs = append(rec.errs, err)
+		}
+	}
+	return rec.ResponseWriter.Write(res)
+}
+
+func (rec *recorder) WriteStatus(statusCode int) {
+	activegraph.WriteStatus(rec.ResponseWriter, statusCode)
+}
+
+func (rec *recorder) NotifyWritten(fn func(n int)) {
+	activegraph.NotifyWritten(rec.ResponseWriter, fn)
+}
+
+func (rec *recorder) errors() []error {
+	return rec.errs
+}
+
+func (rec streamRecorder) WriteIncremental(res *activegraph.IncrementalResult) error {
+	if res != nil {
+		for _, payload := range res.Incremental {
+			for _, err := range payload.Errors {
+				rec.errs = append(rec.errs, err)
+			}
+		}
 	}
+	return rec.srw.WriteIncremental(res)
 }
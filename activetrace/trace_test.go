@@ -0,0 +1,174 @@
+package activetrace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/activegraph/activegraph"
+)
+
+type telemetry struct {
+	spans  *tracetest.InMemoryExporter
+	reader *sdkmetric.ManualReader
+	opts   []Option
+}
+
+func newTelemetry() *telemetry {
+	var (
+		spans  = tracetest.NewInMemoryExporter()
+		reader = sdkmetric.NewManualReader()
+	)
+	return &telemetry{
+		spans:  spans,
+		reader: reader,
+		opts: []Option{
+			WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))),
+			WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		},
+	}
+}
+
+// histogram returns data points of the histogram with the given name.
+func (tm *telemetry) histogram(t *testing.T, name string) []metricdata.HistogramDataPoint[float64] {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, tm.reader.Collect(context.Background(), &rm))
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data.(metricdata.Histogram[float64]).DataPoints
+			}
+		}
+	}
+	return nil
+}
+
+func newHandler(tm *telemetry) http.Handler {
+	var (
+		posts = activegraph.NewFunc("posts", func(ctx context.Context) ([]string, error) {
+			return []string{"First"}, nil
+		})
+		fail = activegraph.NewFunc("fail", func(ctx context.Context) (*string, error) {
+			return nil, errors.New("failed")
+		})
+	)
+
+	c := activegraph.Controller{
+		Queries: []activegraph.FuncDef{
+			activegraph.EncloseFunc(posts, DefineTracingFunc(tm.opts...)),
+			activegraph.EncloseFunc(fail, DefineTracingFunc(tm.opts...)),
+		},
+	}
+	c.AppendAroundOp(activegraph.OperationQuery, TracingCallback(tm.opts...))
+	return c.HandleHTTP()
+}
+
+func TestTracingCallback(t *testing.T) {
+	tm := newTelemetry()
+	h := newHandler(tm)
+
+	const (
+		traceID      = "4bf92f3577b34da6a736ce0e4736a0ab"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/graphql?query=query+Posts{posts}", nil)
+	r.Header.Set("Traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	spans := tm.spans.GetSpans()
+	require.Len(t, spans, 2)
+
+	resolver, operation := spans[0], spans[1]
+	assert.Equal(t, "query Posts", operation.Name)
+	assert.Equal(t, trace.SpanKindServer, operation.SpanKind)
+	assert.Equal(t, traceID, operation.SpanContext.TraceID().String())
+	assert.Equal(t, parentSpanID, operation.Parent.SpanID().String())
+	assert.True(t, operation.Parent.IsRemote())
+	assert.Contains(t, operation.Attributes, semconv.GraphqlOperationTypeQuery)
+	assert.Contains(t, operation.Attributes, semconv.GraphqlOperationName("Posts"))
+	assert.Contains(t, operation.Attributes, semconv.GraphqlDocument("query Posts{posts}"))
+
+	assert.Equal(t, "posts", resolver.Name)
+	assert.Equal(t, operation.SpanContext.SpanID(), resolver.Parent.SpanID())
+	assert.Contains(t, resolver.Attributes, attribute.String("graphql.field.name", "posts"))
+	assert.Equal(t, codes.Unset, resolver.Status.Code)
+}
+
+func TestTracingCallback_NoTraceContext(t *testing.T) {
+	tm := newTelemetry()
+	h := newHandler(tm)
+
+	// Requests without the trace context start a new trace.
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?query={posts}", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+	assert.JSONEq(t, `{"data":{"posts":["First"]}}`, rw.Body.String())
+
+	spans := tm.spans.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "query", spans[1].Name)
+	assert.False(t, spans[1].Parent.IsValid())
+	assert.True(t, spans[1].SpanContext.IsValid())
+}
+
+func TestTracingCallback_Errors(t *testing.T) {
+	tm := newTelemetry()
+	h := newHandler(tm)
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?query={posts,fail}", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	spans := tm.spans.GetSpans()
+	require.Len(t, spans, 3)
+
+	operation := spans[2]
+	assert.Equal(t, codes.Error, operation.Status.Code)
+	assert.Equal(t, "failed", operation.Status.Description)
+	require.Len(t, operation.Events, 1)
+	assert.Equal(t, semconv.ExceptionEventName, operation.Events[0].Name)
+
+	var resolver sdktrace.ReadOnlySpan
+	for _, span := range tm.spans.GetSpans().Snapshots() {
+		if span.Name() == "fail" {
+			resolver = span
+		}
+	}
+	require.NotNil(t, resolver)
+	assert.Equal(t, codes.Error, resolver.Status().Code)
+
+	// Measurements of failed calls are marked with the error type.
+	points := tm.histogram(t, "graphql.server.resolver.duration")
+	require.Len(t, points, 2)
+
+	errorTypes := make(map[string]string)
+	for _, point := range points {
+		name, _ := point.Attributes.Value("graphql.field.name")
+		errorType, _ := point.Attributes.Value(semconv.ErrorTypeKey)
+		errorTypes[name.AsString()] = errorType.AsString()
+	}
+	assert.Equal(t, map[string]string{"posts": "", "fail": "*errors.fundamental"}, errorTypes)
+
+	points = tm.histogram(t, "graphql.server.operation.duration")
+	require.Len(t, points, 1)
+	assert.Equal(t, uint64(1), points[0].Count)
+	assert.Contains(t, points[0].Attributes.ToSlice(), semconv.ErrorTypeKey.String("graphql"))
+}
@@ -0,0 +1,361 @@
+package activetrace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+
+	"github.com/activegraph/activegraph"
+)
+
+// TracingHeader is a request header, that enables timings of resolvers in
+// the "tracing" extension of the response, when the tracing is enabled.
+const TracingHeader = "X-Graphql-Tracing"
+
+const (
+	// DefaultMaxOperations is a default number of distinct operations, which
+	// statistics are collected separately.
+	DefaultMaxOperations = 1000
+
+	// OtherOperations is a signature of operations recorded after reaching
+	// the maximum number of distinct operations.
+	OtherOperations = "other"
+)
+
+// Usage collects timings of resolvers and usage statistics of fields for
+// each executed operation. Statistics are aggregated by the normalized
+// signature of the operation.
+//
+//	var usage activetrace.Usage
+//	usage.Instrument(&c)
+//
+//	// Export the usage statistics as JSON.
+//	http.Handle("/usage", &usage)
+//
+// When the tracing is enabled, requests with TracingHeader receive timings
+// of resolvers within the "tracing" extension of the response in the Apollo
+// tracing format.
+//
+// The zero value for Usage is ready to use.
+type Usage struct {
+	// Tracing enables timings of resolvers for requests with TracingHeader.
+	// Timings reveal internals of the server, so the tracing should be
+	// enabled only in development.
+	Tracing bool
+
+	// MaxOperations limits the number of distinct operations, statistics of
+	// operations above the limit are aggregated under OtherOperations. When
+	// zero, DefaultMaxOperations is used.
+	MaxOperations int
+
+	mu         sync.Mutex
+	operations map[string]*OperationUsage
+}
+
+// OperationUsage is usage statistics of the operation.
+type OperationUsage struct {
+	// Count is a number of executions of the operation.
+	Count int64 `json:"count"`
+
+	// Fields are statistics of the resolved fields identified by the
+	// schema coordinate, e.g. "Post.title".
+	Fields map[string]FieldUsage `json:"fields"`
+}
+
+// FieldUsage is usage statistics of the field.
+type FieldUsage struct {
+	ReturnType string `json:"returnType"`
+
+	// Count is a number of resolutions of the field, Errors is a number
+	// of resolutions that returned an error.
+	Count  int64 `json:"count"`
+	Errors int64 `json:"errors"`
+
+	// Duration is a total duration of the field resolutions in nanoseconds.
+	Duration time.Duration `json:"duration"`
+}
+
+// Instrument registers the usage extension in the controller and appends
+// the usage callback to queries and mutations of the controller.
+func (u *Usage) Instrument(c *activegraph.Controller) {
+	c.Extensions = append(c.Extensions, u.Extension())
+	c.AppendAroundOp(activegraph.OperationQuery, u.Callback())
+	c.AppendAroundOp(activegraph.OperationMutation, u.Callback())
+}
+
+// Extension returns a GraphQL extension that measures resolvers of the
+// operations served by the usage callback.
+//
+// Resolvers of concurrent functions return thunks, so they are measured by
+// the usage callback, when the function returns, see WithResolvedFunc.
+func (u *Usage) Extension() graphql.Extension {
+	return usageExtension{}
+}
+
+// Callback returns a callback that records usage statistics of the executed
+// operation, and writes timings of resolvers into the response, when the
+// tracing is enabled and the request contains TracingHeader.
+func (u *Usage) Callback() activegraph.AroundCallback {
+	return func(rw activegraph.ResponseWriter, r *activegraph.Request, h activegraph.Handler) {
+		t := operationTrace{start: time.Now()}
+		ctx := context.WithValue(r.Context(), operationTraceKey{}, &t)
+		ctx = activegraph.WithResolvedFunc(ctx, t.append)
+
+		if u.Tracing && r.Header.Get(TracingHeader) != "" {
+			rw = newTracingWriter(rw, &t)
+		}
+		h.Serve(rw, r.WithContext(ctx))
+
+		u.record(r, &t)
+	}
+}
+
+func (u *Usage) record(r *activegraph.Request, t *operationTrace) {
+	sig, err := signature(r)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.operations == nil {
+		u.operations = make(map[string]*OperationUsage)
+	}
+	op, ok := u.operations[sig]
+	if !ok && len(u.operations) >= u.maxOperations() {
+		sig = OtherOperations
+		op, ok = u.operations[sig]
+	}
+	if !ok {
+		op = &OperationUsage{Fields: make(map[string]FieldUsage)}
+		u.operations[sig] = op
+	}
+
+	op.Count++
+	for _, res := range t.resolvers {
+		coord := res.ParentType + "." + res.FieldName
+
+		field := op.Fields[coord]
+		field.ReturnType = res.ReturnType
+		field.Count++
+		field.Duration += res.Duration
+		if res.failed {
+			field.Errors++
+		}
+		op.Fields[coord] = field
+	}
+}
+
+func (u *Usage) maxOperations() int {
+	if u.MaxOperations > 0 {
+		return u.MaxOperations
+	}
+	return DefaultMaxOperations
+}
+
+// Operations returns a copy of usage statistics by signatures of operations.
+func (u *Usage) Operations() map[string]OperationUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	ops := make(map[string]OperationUsage, len(u.operations))
+	for sig, op := range u.operations {
+		fields := make(map[string]FieldUsage, len(op.Fields))
+		for coord, field := range op.Fields {
+			fields[coord] = field
+		}
+		ops[sig] = OperationUsage{Count: op.Count, Fields: fields}
+	}
+	return ops
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (u *Usage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Operations map[string]OperationUsage `json:"operations"`
+	}{u.Operations()})
+}
+
+// ServeHTTP writes usage statistics as JSON.
+func (u *Usage) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(u)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(b)
+}
+
+type operationTraceKey struct{}
+
+// resolverTrace is a trace of the resolver in the Apollo tracing format.
+type resolverTrace struct {
+	Path        []interface{} `json:"path"`
+	ParentType  string        `json:"parentType"`
+	FieldName   string        `json:"fieldName"`
+	ReturnType  string        `json:"returnType"`
+	StartOffset time.Duration `json:"startOffset"`
+	Duration    time.Duration `json:"duration"`
+
+	failed bool
+}
+
+// operationTrace collects traces of resolvers executed within the operation.
+type operationTrace struct {
+	start     time.Time
+	mu        sync.Mutex
+	resolvers []resolverTrace
+}
+
+// append records the trace of the resolver started at the given time.
+func (t *operationTrace) append(info graphql.ResolveInfo, start time.Time, err error) {
+	res := resolverTrace{
+		Path:        info.Path.AsArray(),
+		ParentType:  info.ParentType.Name(),
+		FieldName:   info.FieldName,
+		ReturnType:  info.ReturnType.String(),
+		StartOffset: start.Sub(t.start),
+		Duration:    time.Since(start),
+		failed:      err != nil,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resolvers = append(t.resolvers, res)
+}
+
+// tracing returns a value of the "tracing" extension.
+func (t *operationTrace) tracing() interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	type execution struct {
+		Resolvers []resolverTrace `json:"resolvers"`
+	}
+
+	end := time.Now()
+	return struct {
+		Version   int           `json:"version"`
+		StartTime time.Time     `json:"startTime"`
+		EndTime   time.Time     `json:"endTime"`
+		Duration  time.Duration `json:"duration"`
+		Execution execution     `json:"execution"`
+	}{
+		Version:   1,
+		StartTime: t.start.UTC(),
+		EndTime:   end.UTC(),
+		Duration:  end.Sub(t.start),
+		Execution: execution{append([]resolverTrace{}, t.resolvers...)},
+	}
+}
+
+// usageExtension traces resolvers of operations, the trace is passed
+// through the context by the usage callback.
+type usageExtension struct{}
+
+func (usageExtension) Init(ctx context.Context, _ *graphql.Params) context.Context {
+	return ctx
+}
+
+func (usageExtension) Name() string {
+	return "usage"
+}
+
+func (usageExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (usageExtension) ValidationDidStart(
+	ctx context.Context,
+) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (usageExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {}
+}
+
+func (usageExtension) ResolveFieldDidStart(
+	ctx context.Context, info *graphql.ResolveInfo,
+) (context.Context, graphql.ResolveFieldFinishFunc) {
+	t, ok := ctx.Value(operationTraceKey{}).(*operationTrace)
+	if !ok {
+		return ctx, func(interface{}, error) {}
+	}
+
+	start := time.Now()
+	return ctx, func(value interface{}, err error) {
+		// Thunks of concurrent resolvers are recorded on completion.
+		if _, ok := value.(func() (interface{}, error)); ok && err == nil {
+			return
+		}
+		t.append(*info, start, err)
+	}
+}
+
+func (usageExtension) HasResult() bool {
+	return false
+}
+
+func (usageExtension) GetResult(context.Context) interface{} {
+	return nil
+}
+
+// tracingWriter writes timings of resolvers into the "tracing" extension
+// of the result.
+type tracingWriter struct {
+	activegraph.ResponseWriter
+	trace *operationTrace
+}
+
+// streamTracingWriter writes incremental results as is, timings of resolvers
+// are written only within the initial result.
+type streamTracingWriter struct {
+	*tracingWriter
+	srw activegraph.StreamResponseWriter
+}
+
+func newTracingWriter(rw activegraph.ResponseWriter, t *operationTrace) activegraph.ResponseWriter {
+	tw := &tracingWriter{ResponseWriter: rw, trace: t}
+	if srw, ok := rw.(activegraph.StreamResponseWriter); ok {
+		return streamTracingWriter{tw, srw}
+	}
+	return tw
+}
+
+func (tw *tracingWriter) Write(res *graphql.Result) error {
+	if res != nil {
+		extensions := make(map[string]interface{}, len(res.Extensions)+1)
+		for name, ext := range res.Extensions {
+			extensions[name] = ext
+		}
+		extensions["tracing"] = tw.trace.tracing()
+
+		traced := *res
+		traced.Extensions = extensions
+		res = &traced
+	}
+	return tw.ResponseWriter.Write(res)
+}
+
+func (tw *tracingWriter) WriteStatus(statusCode int) {
+	activegraph.WriteStatus(tw.ResponseWriter, statusCode)
+}
+
+func (tw *tracingWriter) NotifyWritten(fn func(n int)) {
+	activegraph.NotifyWritten(tw.ResponseWriter, fn)
+}
+
+func (tw streamTracingWriter) WriteIncremental(res *activegraph.IncrementalResult) error {
+	return tw.srw.WriteIncremental(res)
+}
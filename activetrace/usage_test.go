@@ -0,0 +1,270 @@
+package activetrace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph"
+)
+
+type Post struct {
+	Title  string `json:"title"`
+	Author string `json:"author"`
+}
+
+func newUsageController() activegraph.Controller {
+	return activegraph.Controller{
+		Types: []activegraph.TypeDef{activegraph.NewType(Post{}, nil)},
+		Queries: []activegraph.FuncDef{
+			activegraph.NewFunc("posts", func(ctx context.Context, args struct {
+				First *int    `json:"first"`
+				Title *string `json:"title"`
+			}) ([]Post, error) {
+				return []Post{{"First", "Herman"}, {"Second", "Ishmael"}}, nil
+			}),
+			activegraph.NewFunc("fail", func(ctx context.Context) (*string, error) {
+				return nil, errors.New("failed")
+			}),
+		},
+	}
+}
+
+func TestSignature(t *testing.T) {
+	c := newUsageController()
+	schema, err := c.CreateSchema()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		query     string
+		opname    string
+		signature string
+	}{
+		{
+			name:      "Anonymous",
+			query:     `{ fail }`,
+			signature: "# -\n{fail}",
+		},
+		{
+			name: "Literals",
+			query: `query Posts {
+				p: posts(title: "Moby Dick", first: 10) { title, a: author }
+			}`,
+			signature: "# Posts\nquery Posts{posts(first:0,title:\"\"){author title}}",
+		},
+		{
+			name: "Fragments",
+			query: `query Posts($first: Int = 5) { posts(first: $first) { ...P ...on Post { title } } }
+				fragment P on Post { author }
+				fragment Unused on Post { title }`,
+			signature: "# Posts\nquery Posts($first:Int=0){posts(first:$first){...P...on Post{title}}}" +
+				"fragment P on Post{author}",
+		},
+		{
+			name:      "Operations",
+			query:     `query A { fail } query B { posts { title } }`,
+			opname:    "B",
+			signature: "# B\nquery B{posts{title}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{"query": {tt.query}, "operationName": {tt.opname}}
+			r, err := activegraph.ParseRequest(httptest.NewRequest(http.MethodGet, "/?"+q.Encode(), nil), &schema)
+			require.NoError(t, err)
+
+			sig, err := signature(r)
+			require.NoError(t, err)
+			assert.Equal(t, tt.signature, sig)
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var usage Usage
+
+	c := newUsageController()
+	usage.Instrument(&c)
+	h := c.HandleHTTP()
+
+	// Queries that differ only in literals and aliases are aggregated
+	// under the same signature.
+	queries := []string{
+		`query Posts { posts(first: 1) { title } fail }`,
+		`query Posts { fail, all: posts(first: 2) { t: title } }`,
+	}
+	for _, query := range queries {
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(query), nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		var res map[string]interface{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &res))
+		assert.NotContains(t, res, "extensions")
+	}
+
+	ops := usage.Operations()
+	require.Len(t, ops, 1)
+	require.Contains(t, ops, "# Posts\nquery Posts{fail posts(first:0){title}}")
+
+	op := ops["# Posts\nquery Posts{fail posts(first:0){title}}"]
+	assert.Equal(t, int64(2), op.Count)
+	require.Len(t, op.Fields, 3)
+
+	assert.Equal(t, int64(2), op.Fields["Query.posts"].Count)
+	assert.Equal(t, "[Post!]!", op.Fields["Query.posts"].ReturnType)
+	assert.Equal(t, int64(0), op.Fields["Query.posts"].Errors)
+	assert.Equal(t, int64(4), op.Fields["Post.title"].Count)
+	assert.Equal(t, int64(2), op.Fields["Query.fail"].Errors)
+
+	b, err := json.Marshal(&usage)
+	require.NoError(t, err)
+
+	var exported struct {
+		Operations map[string]OperationUsage `json:"operations"`
+	}
+	require.NoError(t, json.Unmarshal(b, &exported))
+	assert.Equal(t, ops, exported.Operations)
+}
+
+func TestUsage_MaxOperations(t *testing.T) {
+	usage := Usage{MaxOperations: 1}
+
+	c := newUsageController()
+	usage.Instrument(&c)
+	h := c.HandleHTTP()
+
+	queries := []string{`{posts{title}}`, `{fail}`, `{posts{title}}`, `{posts(first:1){title}}`}
+	for _, query := range queries {
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(query), nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+	}
+
+	// Operations above the limit are aggregated under the same signature.
+	ops := usage.Operations()
+	require.Len(t, ops, 2)
+	assert.Equal(t, int64(2), ops["# -\n{posts{title}}"].Count)
+	assert.Equal(t, int64(2), ops[OtherOperations].Count)
+}
+
+func TestUsage_Tracing(t *testing.T) {
+	var usage Usage
+
+	c := newUsageController()
+	usage.Instrument(&c)
+	h := c.HandleHTTP()
+
+	serve := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/graphql?query={posts{title}}", nil)
+		r.Header.Set(TracingHeader, "1")
+
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		require.Equal(t, http.StatusOK, rw.Code)
+		return rw
+	}
+
+	// Timings are not written, until the tracing is enabled.
+	rw := serve()
+	assert.NotContains(t, rw.Body.String(), "extensions")
+
+	usage.Tracing = true
+	rw = serve()
+
+	var res struct {
+		Extensions struct {
+			Tracing struct {
+				Version   int    `json:"version"`
+				StartTime string `json:"startTime"`
+				EndTime   string `json:"endTime"`
+				Duration  int64  `json:"duration"`
+				Execution struct {
+					Resolvers []map[string]interface{} `json:"resolvers"`
+				} `json:"execution"`
+			} `json:"tracing"`
+		} `json:"extensions"`
+	}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &res))
+
+	tracing := res.Extensions.Tracing
+	assert.Equal(t, 1, tracing.Version)
+	assert.NotEmpty(t, tracing.StartTime)
+	assert.NotEmpty(t, tracing.EndTime)
+
+	resolvers := tracing.Execution.Resolvers
+	require.Len(t, resolvers, 3)
+	assert.Equal(t, []interface{}{"posts"}, resolvers[0]["path"])
+	assert.Equal(t, "Query", resolvers[0]["parentType"])
+	assert.Equal(t, "posts", resolvers[0]["fieldName"])
+	assert.Equal(t, "[Post!]!", resolvers[0]["returnType"])
+	assert.Equal(t, []interface{}{"posts", 1.0, "title"}, resolvers[2]["path"])
+	assert.Equal(t, "Post", resolvers[2]["parentType"])
+}
+
+func TestUsage_Concurrent(t *testing.T) {
+	usage := Usage{Tracing: true}
+
+	slow := activegraph.NewFunc("slow", func(ctx context.Context) (string, error) {
+		time.Sleep(50 * time.Millisecond)
+		return "done", nil
+	})
+	fail := activegraph.NewFunc("fail", func(ctx context.Context) (*string, error) {
+		return nil, errors.New("failed")
+	})
+	slow.Concurrent, fail.Concurrent = true, true
+
+	c := activegraph.Controller{
+		Queries:        []activegraph.FuncDef{slow, fail},
+		MaxConcurrency: 2,
+	}
+	usage.Instrument(&c)
+
+	r := httptest.NewRequest(http.MethodGet, "/graphql?query={slow,fail}", nil)
+	r.Header.Set(TracingHeader, "1")
+
+	rw := httptest.NewRecorder()
+	c.HandleHTTP().ServeHTTP(rw, r)
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	// Concurrent resolvers are measured until the function returns.
+	ops := usage.Operations()
+	require.Contains(t, ops, "# -\n{fail slow}")
+
+	fields := ops["# -\n{fail slow}"].Fields
+	require.Len(t, fields, 2)
+	assert.Equal(t, int64(1), fields["Query.slow"].Count)
+	assert.GreaterOrEqual(t, fields["Query.slow"].Duration, 50*time.Millisecond)
+	assert.Equal(t, int64(1), fields["Query.fail"].Errors)
+
+	var res struct {
+		Extensions struct {
+			Tracing struct {
+				Execution struct {
+					Resolvers []struct {
+						FieldName string `json:"fieldName"`
+						Duration  int64  `json:"duration"`
+					} `json:"resolvers"`
+				} `json:"execution"`
+			} `json:"tracing"`
+		} `json:"extensions"`
+	}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &res))
+
+	durations := make(map[string]time.Duration)
+	for _, resolver := range res.Extensions.Tracing.Execution.Resolvers {
+		durations[resolver.FieldName] = time.Duration(resolver.Duration)
+	}
+	require.Len(t, durations, 2)
+	assert.GreaterOrEqual(t, durations["slow"], 50*time.Millisecond)
+}
@@ -1,18 +1,89 @@
 package activegraph
 
 import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
 
 	"github.com/activegraph/activegraph/actioncontroller"
-	"github.com/activegraph/activegraph/actioncontroller/graphql"
+	actiongraphql "github.com/activegraph/activegraph/actioncontroller/graphql"
+	"github.com/activegraph/activegraph/activerecord"
+)
+
+const (
+	// DefaultAddr is the address the application listens on, when neither
+	// address nor listener is specified.
+	DefaultAddr = ":3000"
+
+	// DefaultShutdownTimeout is the time given to in-flight requests to
+	// complete on the graceful shutdown.
+	DefaultShutdownTimeout = 30 * time.Second
+
+	// DefaultEnv is the environment of the application, when neither
+	// environment nor ACTIVEGRAPH_ENV variable is specified.
+	DefaultEnv = "development"
+
+	// graphqlPath is the path of the GraphQL endpoint.
+	graphqlPath = "/graphql"
 )
 
 type A struct {
 	actioncontroller.Mapper
+
+	// Controller defines functions, callbacks and settings of the GraphQL
+	// endpoint. Resources mapped by the default mapper are served by the
+	// controller along with its functions within a single schema.
+	Controller Controller
+
+	// Addr is a TCP address to listen on, DefaultAddr is used when empty.
+	Addr string
+
+	// Listener is used to accept connections instead of listening on Addr.
+	Listener net.Listener
+
+	// TLSConfig enables serving of HTTPS requests, certificates are either
+	// specified in the configuration, or loaded from the CertFile and KeyFile.
+	TLSConfig *tls.Config
+	CertFile  string
+	KeyFile   string
+
+	// Timeouts of the HTTP server, zero means no timeout.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// ShutdownTimeout limits the graceful shutdown, DefaultShutdownTimeout
+	// is used when zero.
+	ShutdownTimeout time.Duration
+
+	// Env is the environment of the application, e.g. "production". When
+	// empty, the value of ACTIVEGRAPH_ENV variable or DefaultEnv is used.
+	Env string
+
+	// DatabaseFile is a path to the database configuration file, connections
+	// of the environment are established on the application boot. See
+	// activerecord.LoadConfig for details.
+	DatabaseFile string
 }
 
 type Application struct {
 	mapper actioncontroller.Mapper
+	config A
+
+	mu       sync.Mutex
+	server   *http.Server
+	draining int32
 }
 
 func New(init func(*A)) *Application {
@@ -24,17 +95,228 @@ func New(init func(*A)) *Application {
 }
 
 func Initialize(init func(*A)) (*Application, error) {
-	a := A{Mapper: new(graphql.Mapper)}
+	a := A{Mapper: new(actiongraphql.Mapper)}
 	init(&a)
 
-	return &Application{mapper: a.Mapper}, nil
+	if a.Addr != "" && a.Listener != nil {
+		return nil, errors.New("activegraph: both address and listener are specified")
+	}
+	if (a.CertFile == "") != (a.KeyFile == "") {
+		return nil, errors.New("activegraph: both certificate and key files must be specified")
+	}
+	if a.Addr == "" {
+		a.Addr = DefaultAddr
+	}
+	if a.ShutdownTimeout == 0 {
+		a.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if a.Env == "" {
+		a.Env = os.Getenv("ACTIVEGRAPH_ENV")
+	}
+	if a.Env == "" {
+		a.Env = DefaultEnv
+	}
+
+	if a.DatabaseFile != "" {
+		configs, err := activerecord.LoadConfig(a.DatabaseFile, a.Env)
+		if err != nil {
+			return nil, err
+		}
+		if err = activerecord.EstablishConnections(configs); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Application{mapper: a.Mapper, config: a}, nil
+}
+
+// controller returns the controller serving resources of the mapper, it
+// returns false, when resources are mapped by the custom mapper.
+func (a *Application) controller() (*Controller, bool) {
+	mapper, ok := a.mapper.(*actiongraphql.Mapper)
+	if !ok {
+		return nil, false
+	}
+	c := a.config.Controller
+	c.Resources = mapper
+	return &c, true
+}
+
+// Handler returns the HTTP handler of the application, it serves the mapped
+// resources at "/graphql" along with "/healthz" and "/readyz" endpoints.
+func (a *Application) Handler() (handler http.Handler, err error) {
+	mux := http.NewServeMux()
+	if c, ok := a.controller(); ok {
+		if handler, err = c.Handler(); err != nil {
+			return nil, err
+		}
+		// Assets of the playground are served from the sub-path of the
+		// GraphQL endpoint.
+		mux.Handle(graphqlPath, handler)
+		mux.Handle(graphqlPath+"/", handler)
+	} else {
+		if handler, err = a.mapper.Map(); err != nil {
+			return nil, err
+		}
+		mux.Handle("/", handler)
+	}
+	mux.HandleFunc("/healthz", a.healthz)
+	mux.HandleFunc("/readyz", a.readyz)
+	return mux, nil
+}
+
+// healthz reports that the process of the application is alive, it does
+// not depend on the database, so the failure of the database does not
+// restart the application.
+func (a *Application) healthz(rw http.ResponseWriter, r *http.Request) {
+	rw.Write([]byte("ok\n"))
+}
+
+// readyz reports that the application accepts requests, the application
+// is not ready from the moment of shutdown, or when the primary database
+// connection is established, but does not respond.
+func (a *Application) readyz(rw http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&a.draining) != 0 {
+		http.Error(rw, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if err := pingPrimary(r.Context()); err != nil {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	rw.Write([]byte("ok\n"))
+}
+
+// pingPrimary verifies the primary database connection, applications
+// without the database are always ready.
+func pingPrimary(ctx context.Context) error {
+	conn, err := activerecord.PrimaryConnection()
+	if _, ok := err.(*activerecord.ErrConnectionNotEstablished); ok {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return activerecord.Ping(ctx, conn)
+}
+
+// Schema returns the GraphQL schema of the application.
+func (a *Application) Schema() (schema graphql.Schema, err error) {
+	if c, ok := a.controller(); ok {
+		return c.CreateSchema()
+	}
+	mapper, ok := a.mapper.(interface {
+		Schema() (graphql.Schema, error)
+	})
+	if !ok {
+		return schema, errors.Errorf("activegraph: mapper %T does not expose schema", a.mapper)
+	}
+	return mapper.Schema()
+}
+
+// Run runs the command of the application. Without arguments, or with
+// "server" command the application is served, "schema:print" command prints
+// the GraphQL schema of the application to the standard output.
+func (a *Application) Run(args []string) error {
+	if len(args) == 0 {
+		return a.ListenAndServe()
+	}
+
+	switch args[0] {
+	case "server":
+		return a.ListenAndServe()
+	case "schema:print":
+		schema, err := a.Schema()
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(os.Stdout, PrintSchema(schema))
+		return err
+	default:
+		return errors.Errorf("activegraph: unknown command %q", args[0])
+	}
 }
 
+// ListenAndServe serves the application until SIGTERM or interrupt signal
+// is received, then the application is gracefully shut down.
 func (a *Application) ListenAndServe() error {
-	handler, err := a.mapper.Map()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	return a.Serve(ctx)
+}
+
+// Serve serves the application until the context is done, then the
+// application is gracefully shut down: in-flight requests are drained and
+// all database connections are closed.
+func (a *Application) Serve(ctx context.Context) error {
+	handler, err := a.Handler()
 	if err != nil {
 		return err
 	}
 
-	return http.ListenAndServe(":3000", handler)
+	ln := a.config.Listener
+	if ln == nil {
+		if ln, err = net.Listen("tcp", a.config.Addr); err != nil {
+			return err
+		}
+	}
+
+	server := &http.Server{
+		Handler:      handler,
+		TLSConfig:    a.config.TLSConfig,
+		ReadTimeout:  a.config.ReadTimeout,
+		WriteTimeout: a.config.WriteTimeout,
+		IdleTimeout:  a.config.IdleTimeout,
+	}
+
+	a.mu.Lock()
+	a.server = server
+	a.mu.Unlock()
+
+	errc := make(chan error, 1)
+	go func() {
+		if a.config.TLSConfig != nil || a.config.CertFile != "" {
+			errc <- server.ServeTLS(ln, a.config.CertFile, a.config.KeyFile)
+		} else {
+			errc <- server.Serve(ln)
+		}
+	}()
+
+	select {
+	case err = <-errc:
+		if err != http.ErrServerClosed {
+			activerecord.RemoveAllConnections()
+			return err
+		}
+		// The server is closed by the explicit shutdown.
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.config.ShutdownTimeout)
+	defer cancel()
+	return a.Shutdown(shutdownCtx)
+}
+
+// Shutdown gracefully shuts down the application, the application stops
+// accepting new requests and waits for in-flight requests to complete, after
+// that all database connections are closed.
+func (a *Application) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&a.draining, 1)
+
+	a.mu.Lock()
+	server := a.server
+	a.mu.Unlock()
+
+	var err error
+	if server != nil {
+		err = server.Shutdown(ctx)
+	}
+	if e := activerecord.RemoveAllConnections(); e != nil {
+		if err == nil {
+			return e
+		}
+		err = errors.WithMessage(err, e.Error())
+	}
+	return err
 }
@@ -0,0 +1,205 @@
+package activegraph
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/actioncontroller"
+	"github.com/activegraph/activegraph/actionview"
+	"github.com/activegraph/activegraph/activerecord"
+	_ "github.com/activegraph/activegraph/activerecord/sqlite3"
+)
+
+func newTestApplication(t *testing.T, ln net.Listener) *Application {
+	Book := activerecord.New("book", func(r *activerecord.R) {
+		r.AttrString("title")
+	})
+	books := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Show(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.ViewResult(Book.Find(ctx.Params["id"]))
+		})
+	})
+
+	app, err := Initialize(func(a *A) {
+		a.Listener = ln
+		a.ReadTimeout = time.Second
+		a.Resources(Book, books)
+	})
+	require.NoError(t, err)
+	return app
+}
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(b)
+}
+
+func TestApplication_Serve(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	app := newTestApplication(t, ln)
+	url := "http://" + ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- app.Serve(ctx) }()
+
+	// Applications without the database are healthy and ready.
+	code, body := get(t, url+"/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok\n", body)
+
+	code, _ = get(t, url+"/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	_, err = activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: ":memory:",
+	})
+	require.NoError(t, err)
+
+	code, body = get(t, url+"/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok\n", body)
+
+	code, _ = get(t, url+"/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = get(t, url+"/graphql?query={__typename}")
+	assert.Equal(t, http.StatusOK, code)
+
+	cancel()
+	require.NoError(t, <-errc)
+
+	// All connections are closed on shutdown.
+	_, err = activerecord.PrimaryConnection()
+	assert.Error(t, err)
+
+	_, err = http.Get(url + "/healthz")
+	assert.Error(t, err)
+}
+
+func TestApplication_Playground(t *testing.T) {
+	app := newTestApplication(t, nil)
+	h, err := app.Handler()
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go http.Serve(ln, h)
+
+	code, body := get(t, "http://"+ln.Addr().String()+"/graphql/_playground/playground.js")
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, body)
+}
+
+func TestApplication_Readyz(t *testing.T) {
+	app := newTestApplication(t, nil)
+
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: ":memory:",
+	})
+	require.NoError(t, err)
+
+	h, err := app.Handler()
+	require.NoError(t, err)
+
+	require.NoError(t, app.Shutdown(context.Background()))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go http.Serve(ln, h)
+
+	code, body := get(t, "http://"+ln.Addr().String()+"/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting down\n", body)
+}
+
+// unreachableConn is a connection to the database, that does not respond.
+type unreachableConn struct {
+	activerecord.Conn
+}
+
+func (c *unreachableConn) Ping(context.Context) error {
+	return errors.New("database is unreachable")
+}
+
+func (c *unreachableConn) Close() error {
+	return nil
+}
+
+func TestApplication_ReadyzDatabase(t *testing.T) {
+	activerecord.RegisterConnectionAdapter("unreachable", func(activerecord.DatabaseConfig) (activerecord.Conn, error) {
+		return &unreachableConn{}, nil
+	})
+
+	app := newTestApplication(t, nil)
+	h, err := app.Handler()
+	require.NoError(t, err)
+
+	_, err = activerecord.EstablishConnection(activerecord.DatabaseConfig{Adapter: "unreachable"})
+	require.NoError(t, err)
+	defer activerecord.RemoveConnection("primary")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go http.Serve(ln, h)
+
+	// The process is alive, but it does not accept requests.
+	code, _ := get(t, "http://"+ln.Addr().String()+"/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body := get(t, "http://"+ln.Addr().String()+"/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "database is unreachable\n", body)
+}
+
+func TestInitialize_Error(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	_, err = Initialize(func(a *A) {
+		a.Addr = ":8080"
+		a.Listener = ln
+	})
+	assert.EqualError(t, err, "activegraph: both address and listener are specified")
+
+	_, err = Initialize(func(a *A) {
+		a.CertFile = "cert.pem"
+	})
+	assert.EqualError(t, err, "activegraph: both certificate and key files must be specified")
+}
+
+func TestInitialize_DatabaseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "database.yml")
+	require.NoError(t, os.WriteFile(path, []byte("test:\n  adapter: sqlite3\n  database: \":memory:\"\n"), 0o644))
+
+	_, err := Initialize(func(a *A) {
+		a.Env = "test"
+		a.DatabaseFile = path
+	})
+	require.NoError(t, err)
+	defer activerecord.RemoveAllConnections()
+
+	conn, err := activerecord.PrimaryConnection()
+	require.NoError(t, err)
+	assert.NoError(t, activerecord.Ping(context.Background(), conn))
+}
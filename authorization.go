@@ -0,0 +1,129 @@
+package activegraph
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Principal is an authenticated client of the server.
+type Principal interface {
+	HasRole(role string) bool
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context with the principal, all
+// authorization rules are evaluated against this principal.
+//
+// Usually, the principal is put into the context by the "around" callback:
+//
+//	c.AppendAroundOp(OperationQuery, func(rw ResponseWriter, r *Request, h Handler) {
+//		user := authenticate(r.Header.Get("Authorization"))
+//		h.Serve(rw, r.WithContext(WithPrincipal(r.Context(), user)))
+//	})
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in the context.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok && p != nil
+}
+
+// ErrForbidden is returned when the principal is not allowed to access
+// the field.
+type ErrForbidden struct {
+	Field string
+}
+
+// Error returns a string representation of the error.
+func (e *ErrForbidden) Error() string {
+	return "access to " + e.Field + " is forbidden"
+}
+
+// Extensions returns extensions of the GraphQL error.
+func (e *ErrForbidden) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "FORBIDDEN"}
+}
+
+// Rule is an authorization rule of the function. Principal is nil, when
+// the request is not authenticated.
+type Rule func(ctx context.Context, p Principal) bool
+
+// RequireRole returns a rule that allows access only to principals with
+// at least one of the specified roles.
+func RequireRole(roles ...string) Rule {
+	return func(ctx context.Context, p Principal) bool {
+		if p == nil {
+			return false
+		}
+		for _, role := range roles {
+			if p.HasRole(role) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RequirePrincipal returns a rule that allows access only to authenticated
+// principals.
+func RequirePrincipal() Rule {
+	return func(ctx context.Context, p Principal) bool {
+		return p != nil
+	}
+}
+
+// Policy returns a rule that allows access, when the given function
+// returns true.
+func Policy(fn func(ctx context.Context, p Principal) bool) Rule {
+	if fn == nil {
+		panic("nil policy")
+	}
+	return Rule(fn)
+}
+
+// Authorize returns a copy of the function definition with the authorization
+// rules appended, access to the function is allowed only when all rules pass.
+//
+//	typedef := NewType(User{}, nil)
+//	typedef.Funcs["email"] = Authorize(NewFunc("email", userEmail), RequireRole("admin"))
+func Authorize(funcdef FuncDef, rules ...Rule) FuncDef {
+	funcdef.Rules = append(funcdef.Rules[:len(funcdef.Rules):len(funcdef.Rules)], rules...)
+	return funcdef
+}
+
+// newAuthorizedFunc wraps the field resolve function to evaluate authorization
+// rules of the function definition before the function execution.
+//
+// Response of the authorized function depends on the principal, therefore
+// it's never cached by shared caches.
+func newAuthorizedFunc(funcdef FuncDef, fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	if len(funcdef.Rules) == 0 {
+		return fn
+	}
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if policy, ok := p.Context.Value(cachePolicyKey{}).(*cachePolicy); ok {
+			policy.restrictScope(CachePrivate)
+		}
+
+		principal, _ := PrincipalFromContext(p.Context)
+		for _, rule := range funcdef.Rules {
+			if !rule(p.Context, principal) {
+				return nil, &ErrForbidden{Field: p.Info.FieldName}
+			}
+		}
+		return fn(p)
+	}
+}
+
+// newAuthorizedType returns a nullable type for the functions with
+// authorization rules, so the forbidden field does not invalidate its parent.
+func newAuthorizedType(funcdef FuncDef, gqltype graphql.Type) graphql.Type {
+	if len(funcdef.Rules) == 0 {
+		return gqltype
+	}
+	return graphql.GetNullable(gqltype).(graphql.Type)
+}
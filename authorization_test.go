@@ -0,0 +1,102 @@
+package activegraph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPrincipal []string
+
+func (p testPrincipal) HasRole(role string) bool {
+	for i := range p {
+		if p[i] == role {
+			return true
+		}
+	}
+	return false
+}
+
+func TestController_Authorize(t *testing.T) {
+	type User struct {
+		Name string `json:"name"`
+	}
+
+	typedef := NewType(User{}, Funcs{
+		"email": func(ctx context.Context, u User) (string, error) {
+			return "steve@apple.com", nil
+		},
+	})
+	typedef.Funcs["email"] = Authorize(typedef.Funcs["email"], RequireRole("admin"))
+
+	s := Controller{
+		Types: []TypeDef{typedef},
+		Queries: []FuncDef{
+			NewFunc("user", func(ctx context.Context) (User, error) {
+				return User{Name: "Steve"}, nil
+			}),
+			Authorize(NewFunc("secret", func(ctx context.Context) (string, error) {
+				return "42", nil
+			}), RequirePrincipal(), Policy(func(ctx context.Context, p Principal) bool {
+				return p.HasRole("owner")
+			})),
+		},
+	}
+	s.AppendAroundOp(OperationQuery, func(rw ResponseWriter, r *Request, h Handler) {
+		principal := testPrincipal(r.Header["X-Role"])
+		h.Serve(rw, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+
+	type Error struct {
+		Message    string                 `json:"message"`
+		Path       []interface{}          `json:"path"`
+		Extensions map[string]interface{} `json:"extensions"`
+	}
+
+	tests := []struct {
+		roles  []string
+		data   string
+		errors []Error
+	}{
+		{
+			roles: []string{"admin"},
+			data:  `{"user":{"name":"Steve","email":"steve@apple.com"},"secret":null}`,
+			errors: []Error{{
+				Message:    "access to secret is forbidden",
+				Path:       []interface{}{"secret"},
+				Extensions: map[string]interface{}{"code": "FORBIDDEN"},
+			}},
+		},
+		{
+			roles: []string{"owner"},
+			data:  `{"user":{"name":"Steve","email":null},"secret":"42"}`,
+			errors: []Error{{
+				Message:    "access to email is forbidden",
+				Path:       []interface{}{"user", "email"},
+				Extensions: map[string]interface{}{"code": "FORBIDDEN"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/graphql?query={user{name,email},secret}", nil)
+		r.Header["X-Role"] = tt.roles
+
+		rw := httptest.NewRecorder()
+		s.HandleHTTP().ServeHTTP(rw, r)
+
+		var body struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []Error         `json:"errors"`
+		}
+
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &body))
+		assert.JSONEq(t, tt.data, string(body.Data))
+		assert.Equal(t, tt.errors, body.Errors)
+	}
+}
@@ -0,0 +1,242 @@
+package activegraph
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// CacheScope defines who is allowed to cache the response.
+type CacheScope int
+
+const (
+	// CachePublic allows to cache response by shared caches.
+	CachePublic CacheScope = iota
+	// CachePrivate allows to cache response only by the client.
+	CachePrivate
+)
+
+// String returns a Cache-Control directive of the scope.
+func (s CacheScope) String() string {
+	if s == CachePrivate {
+		return "private"
+	}
+	return "public"
+}
+
+// CacheHint defines for how long and by whom the value of the field could
+// be cached.
+//
+// The cache policy of the response is calculated as the most restrictive
+// hint of all resolved fields: the lowest max age and the private scope, when
+// at least one of the fields is private. Query fields without hints make the
+// whole response uncacheable, while nested fields without hints inherit the
+// policy of the parent.
+type CacheHint struct {
+	MaxAge time.Duration
+	Scope  CacheScope
+}
+
+// String returns a value of the Cache-Control header for the hint.
+func (h CacheHint) String() string {
+	return fmt.Sprintf("max-age=%d, %s", int(h.MaxAge.Seconds()), h.Scope)
+}
+
+// cachePolicy accumulates cache hints of the resolved fields of a single
+// request. Fields could be resolved concurrently, therefore the access to
+// the policy is guarded with a mutex.
+type cachePolicy struct {
+	hint       CacheHint
+	restricted bool
+	mu         sync.Mutex
+}
+
+func (p *cachePolicy) restrict(hint CacheHint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.restricted || hint.MaxAge < p.hint.MaxAge {
+		p.hint.MaxAge = hint.MaxAge
+	}
+	if hint.Scope == CachePrivate {
+		p.hint.Scope = CachePrivate
+	}
+	p.restricted = true
+}
+
+// restrictScope restricts the scope of the response without changing
+// the max age.
+func (p *cachePolicy) restrictScope(scope CacheScope) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if scope == CachePrivate {
+		p.hint.Scope = CachePrivate
+	}
+}
+
+// Hint returns the overall cache hint of the response, and false when the
+// response must not be cached.
+func (p *cachePolicy) Hint() (CacheHint, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.hint, p.restricted && p.hint.MaxAge > 0
+}
+
+type cachePolicyKey struct{}
+
+// newCacheFunc wraps the field resolve function to record the cache hint
+// of the field into the policy of the request.
+func newCacheFunc(hint *CacheHint, root bool, fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	if hint == nil && !root {
+		return fn
+	}
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if policy, ok := p.Context.Value(cachePolicyKey{}).(*cachePolicy); ok {
+			if hint != nil {
+				policy.restrict(*hint)
+			} else {
+				policy.restrict(CacheHint{})
+			}
+		}
+		return fn(p)
+	}
+}
+
+// ResponseCache is used by the GraphQL handler to store responses of the
+// public queries and serve repeated queries without executing resolvers.
+type ResponseCache interface {
+	// Get returns a cached response and the time left until it expires.
+	Get(ctx context.Context, key string) (b []byte, ttl time.Duration, ok bool)
+
+	// Set puts the response in the cache for the given time.
+	Set(ctx context.Context, key string, b []byte, ttl time.Duration)
+}
+
+type memoryCacheEntry struct {
+	b       []byte
+	expires time.Time
+}
+
+// MemoryCache is an in-memory implementation of the ResponseCache.
+//
+// The zero value for MemoryCache is an empty cache ready to use.
+type MemoryCache struct {
+	entries map[string]memoryCacheEntry
+	mu      sync.RWMutex
+}
+
+// Get returns a cached response, when it is not expired yet.
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, time.Duration, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	ttl := time.Until(entry.expires)
+	if !ok || ttl <= 0 {
+		return nil, 0, false
+	}
+	return entry.b, ttl, true
+}
+
+// Set puts the response in the cache, all expired responses are evicted
+// from the cache within this call.
+func (c *MemoryCache) Set(ctx context.Context, key string, b []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]memoryCacheEntry)
+	}
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if !entry.expires.After(now) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = memoryCacheEntry{b: b, expires: now.Add(ttl)}
+}
+
+// cacheLookup is a lookup of the response in the cache. The GraphQL handler
+// puts the lookup into the context of the request, and the lookup is performed
+// by the innermost handler, so callbacks are executed for cached responses.
+type cacheLookup struct {
+	cache ResponseCache
+	key   string
+
+	// hit is true, when the response was served from the cache, ttl is the
+	// time left until the cached response expires.
+	hit bool
+	ttl time.Duration
+}
+
+type cacheLookupKey struct{}
+
+// cacheHandler serves the request from the response cache, when the request
+// context contains the cache lookup and the response is in the cache.
+type cacheHandler struct {
+	handler Handler
+}
+
+func (h *cacheHandler) Serve(rw ResponseWriter, r *Request) {
+	lookup, ok := r.Context().Value(cacheLookupKey{}).(*cacheLookup)
+	if !ok {
+		h.handler.Serve(rw, r)
+		return
+	}
+
+	b, ttl, ok := lookup.cache.Get(r.Context(), lookup.key)
+	if !ok {
+		h.handler.Serve(rw, r)
+		return
+	}
+
+	// Numbers are decoded as is to write the same response, as was cached.
+	var result graphql.Result
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
+		h.handler.Serve(rw, r)
+		return
+	}
+
+	lookup.hit, lookup.ttl = true, ttl
+	rw.Write(&result)
+}
+
+// requestCacheKey returns a key of the request used to store the response
+// in the cache.
+func requestCacheKey(r *Request) string {
+	vars, _ := json.Marshal(r.Variables)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", r.OperationName, r.Query, vars)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// etag returns a strong entity tag of the response body.
+func etag(b []byte) string {
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matchETag returns true when the value of If-None-Match header contains
+// the given entity tag.
+func matchETag(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
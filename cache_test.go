@@ -0,0 +1,88 @@
+package activegraph
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestController_CacheControl(t *testing.T) {
+	type Post struct {
+		Title string `json:"title"`
+	}
+
+	posts := NewFunc("posts", func(ctx context.Context) ([]Post, error) {
+		return []Post{{Title: "First"}}, nil
+	})
+	author := NewFunc("author", func(ctx context.Context) (string, error) {
+		return "Herman Melville", nil
+	})
+	author.Cache = &CacheHint{MaxAge: 30 * time.Second, Scope: CachePrivate}
+
+	typedef := NewType(Post{}, nil)
+	typedef.Cache = &CacheHint{MaxAge: time.Minute}
+
+	s := Controller{Types: []TypeDef{typedef}, Queries: []FuncDef{posts, author}}
+	h := s.HandleHTTP()
+
+	tests := []struct {
+		query        string
+		cacheControl string
+	}{
+		{"{posts{title}}", "max-age=60, public"},
+		{"{posts{title},author}", "max-age=30, private"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?query="+tt.query, nil))
+
+			require.Equal(t, http.StatusOK, rw.Code)
+			assert.Equal(t, tt.cacheControl, rw.Header().Get("Cache-Control"))
+
+			etag := rw.Header().Get("ETag")
+			require.NotEmpty(t, etag)
+
+			r := httptest.NewRequest(http.MethodGet, "/graphql?query="+tt.query, nil)
+			r.Header.Set("If-None-Match", etag)
+
+			rw = httptest.NewRecorder()
+			h.ServeHTTP(rw, r)
+			assert.Equal(t, http.StatusNotModified, rw.Code)
+			assert.Empty(t, rw.Body.String())
+		})
+	}
+}
+
+func TestController_ResponseCache(t *testing.T) {
+	var numCalls int
+
+	posts := NewFunc("posts", func(ctx context.Context) ([]string, error) {
+		numCalls++
+		return []string{"First"}, nil
+	})
+	posts.Cache = &CacheHint{MaxAge: time.Minute}
+
+	users := NewFunc("users", func(ctx context.Context) ([]string, error) {
+		numCalls++
+		return []string{"Steve"}, nil
+	})
+
+	s := Controller{Queries: []FuncDef{posts, users}, Cache: new(MemoryCache)}
+	h := s.HandleHTTP()
+
+	for _, query := range []string{"{posts}", "{posts}", "{users}", "{users}"} {
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?query="+query, nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+	}
+
+	// Only public query with a cache hint is served from the cache.
+	assert.Equal(t, 3, numCalls)
+}
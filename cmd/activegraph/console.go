@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activesupport"
+)
+
+const consolePrompt = "activegraph> "
+
+// isQuery returns true, when the statement returns rows.
+func isQuery(stmt string) bool {
+	fields := strings.Fields(stmt)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH", "PRAGMA", "EXPLAIN", "VALUES":
+		return true
+	}
+	return false
+}
+
+// execStatement executes the statement and prints returned rows, columns
+// of the rows are printed in the alphabetical order.
+func execStatement(ctx context.Context, conn activerecord.Conn, stmt string, out io.Writer) error {
+	if !isQuery(stmt) {
+		if err := conn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "OK")
+		return nil
+	}
+
+	var (
+		columns []string
+		count   int
+	)
+	err := conn.ExecQuery(ctx, &activerecord.QueryOperation{Text: stmt}, func(h activesupport.Hash) bool {
+		if columns == nil {
+			for column := range h {
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+			fmt.Fprintln(out, strings.Join(columns, "\t"))
+		}
+
+		values := make([]string, 0, len(columns))
+		for _, column := range columns {
+			switch v := h[column].(type) {
+			case nil:
+				values = append(values, "NULL")
+			case []byte:
+				values = append(values, string(v))
+			default:
+				values = append(values, fmt.Sprint(v))
+			}
+		}
+		fmt.Fprintln(out, strings.Join(values, "\t"))
+		count++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "(%d rows)\n", count)
+	return nil
+}
+
+// console reads SQL statements terminated with semicolon from the input,
+// and executes them through the connection. Failed statements are reported
+// without termination of the console.
+func console(ctx context.Context, conn activerecord.Conn, in io.Reader, out io.Writer) error {
+	var (
+		scanner = bufio.NewScanner(in)
+		stmt    strings.Builder
+	)
+
+	fmt.Fprint(out, consolePrompt)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "exit" || line == "quit" {
+			return nil
+		}
+
+		stmt.WriteString(line + "\n")
+		if !strings.HasSuffix(line, ";") {
+			if strings.TrimSpace(stmt.String()) == "" {
+				stmt.Reset()
+				fmt.Fprint(out, consolePrompt)
+			}
+			continue
+		}
+
+		if err := execStatement(ctx, conn, stmt.String(), out); err != nil {
+			fmt.Fprintln(out, "error:", err)
+		}
+		stmt.Reset()
+		fmt.Fprint(out, consolePrompt)
+	}
+	return scanner.Err()
+}
+
+func runConsole(args []string) error {
+	var flags dbFlags
+
+	fs := newFlagSet("console")
+	flags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	conn, err := flags.connect()
+	if err != nil {
+		return err
+	}
+	defer activerecord.RemoveAllConnections()
+
+	return console(context.Background(), conn, stdin, stdout)
+}
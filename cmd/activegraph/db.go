@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activerecord/migration"
+	_ "github.com/activegraph/activegraph/activerecord/sqlite3"
+)
+
+// dbFlags are flags of the commands, that connect to the database.
+type dbFlags struct {
+	config string
+	env    string
+}
+
+func (f *dbFlags) register(fs *flag.FlagSet) {
+	env := os.Getenv("ACTIVEGRAPH_ENV")
+	if env == "" {
+		env = "development"
+	}
+	fs.StringVar(&f.config, "config", filepath.Join("config", "database.yml"), "database configuration file")
+	fs.StringVar(&f.env, "env", env, "environment of the application")
+}
+
+// connect establishes the primary connection of the configured environment.
+func (f *dbFlags) connect() (activerecord.Conn, error) {
+	configs, err := activerecord.LoadConfig(f.config, f.env)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range configs {
+		if c.Name == "primary" {
+			return activerecord.EstablishConnection(c)
+		}
+	}
+	return nil, errors.Errorf("primary connection of %q environment is not configured", f.env)
+}
+
+func printMigrations(verb string, migrations []migration.Migration) {
+	for _, m := range migrations {
+		fmt.Fprintf(stdout, "%s %s_%s\n", verb, m.Version, m.Name)
+	}
+}
+
+// newMigrator parses flags of the migration commands and returns a
+// migrator connected to the database.
+func newMigrator(name string, args []string, steps *int) (*migration.Migrator, error) {
+	var flags dbFlags
+
+	fs := newFlagSet(name)
+	flags.register(fs)
+	dir := fs.String("dir", filepath.Join("db", "migrate"), "directory of migrations")
+	if steps != nil {
+		fs.IntVar(steps, "steps", 1, "number of migrations to revert")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	migrations, err := migration.Load(*dir)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := flags.connect()
+	if err != nil {
+		return nil, err
+	}
+	return &migration.Migrator{Conn: conn, Migrations: migrations}, nil
+}
+
+func runMigrate(args []string) error {
+	m, err := newMigrator("db:migrate", args, nil)
+	if err != nil {
+		return err
+	}
+	defer activerecord.RemoveAllConnections()
+
+	applied, err := m.Migrate(context.Background())
+	printMigrations("migrated", applied)
+	return err
+}
+
+func runRollback(args []string) error {
+	var steps int
+	m, err := newMigrator("db:rollback", args, &steps)
+	if err != nil {
+		return err
+	}
+	defer activerecord.RemoveAllConnections()
+
+	reverted, err := m.Rollback(context.Background(), steps)
+	printMigrations("reverted", reverted)
+	return err
+}
+
+func runSchemaDump(args []string) error {
+	var flags dbFlags
+
+	fs := newFlagSet("db:schema:dump")
+	flags.register(fs)
+	out := fs.String("out", filepath.Join("db", "schema.sql"), "output file, \"-\" for the standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	conn, err := flags.connect()
+	if err != nil {
+		return err
+	}
+	defer activerecord.RemoveAllConnections()
+
+	dumper, ok := conn.(activerecord.SchemaDumper)
+	if !ok {
+		return errors.Errorf("connection %T does not support schema dump", conn)
+	}
+	schema, err := dumper.DumpSchema(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" {
+		_, err = fmt.Fprint(stdout, schema)
+		return err
+	}
+	if err = os.WriteFile(*out, []byte(schema), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "dump", *out)
+	return nil
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// now returns the current time, it's replaced in tests to produce
+// deterministic migration versions.
+var now = time.Now
+
+// field is an attribute of the generated model.
+type field struct {
+	Name string
+
+	// Attr is a method of the record builder, that defines the attribute.
+	Attr string
+	// Column is the name and SQL type of the table column.
+	Column string
+	// Permitted is the name of the attribute accepted by the controller.
+	Permitted string
+}
+
+// resource describes names of the generated model and controller.
+type resource struct {
+	Module     string
+	Model      string // e.g. "blog_post"
+	Type       string // e.g. "BlogPost"
+	Table      string // e.g. "blog_posts"
+	Controller string // e.g. "BlogPosts"
+	Fields     []field
+}
+
+func parseField(spec string) (f field, err error) {
+	name, kind, _ := strings.Cut(spec, ":")
+	name = underscore(name)
+	if name == "" {
+		return f, errors.Errorf("invalid field %q", spec)
+	}
+
+	switch kind {
+	case "", "string":
+		return field{name, fmt.Sprintf("r.AttrString(%q)", name), name + " VARCHAR", name}, nil
+	case "text":
+		return field{name, fmt.Sprintf("r.AttrString(%q)", name), name + " TEXT", name}, nil
+	case "int", "integer":
+		return field{name, fmt.Sprintf("r.AttrInt(%q)", name), name + " INTEGER", name}, nil
+	case "references", "belongs_to":
+		return field{name, fmt.Sprintf("r.BelongsTo(%q)", name), name + "_id INTEGER", name + "_id"}, nil
+	default:
+		return f, errors.Errorf("unsupported type %q of field %q", kind, name)
+	}
+}
+
+func newResource(name string, specs []string) (res resource, err error) {
+	model := underscore(name)
+	if !regexp.MustCompile(`^[a-z][a-z0-9_]*$`).MatchString(model) {
+		return res, errors.Errorf("invalid name %q", name)
+	}
+
+	res = resource{
+		Model:      model,
+		Type:       camelize(model),
+		Table:      pluralize(model),
+		Controller: camelize(pluralize(model)),
+	}
+	for _, spec := range specs {
+		f, err := parseField(spec)
+		if err != nil {
+			return res, err
+		}
+		res.Fields = append(res.Fields, f)
+	}
+	return res, nil
+}
+
+// DefaultTable returns true, when the table name matches the name, that
+// activerecord derives from the model name.
+func (r resource) DefaultTable() bool {
+	return r.Table == r.Model+"s"
+}
+
+func (r resource) Permitted() string {
+	names := make([]string, 0, len(r.Fields))
+	for _, f := range r.Fields {
+		names = append(names, fmt.Sprintf("%q", f.Permitted))
+	}
+	return strings.Join(names, ", ")
+}
+
+var modelTemplate = template.Must(template.New("model").Parse(`package models
+
+import (
+	"github.com/activegraph/activegraph/activerecord"
+)
+
+// {{.Type}} is a model of the "{{.Table}}" table.
+var {{.Type}} = activerecord.New("{{.Model}}", func(r *activerecord.R) {
+	{{- if not .DefaultTable}}
+	r.TableName("{{.Table}}")
+	{{- end}}
+	{{- range .Fields}}
+	{{.Attr}}
+	{{- end}}
+})
+`))
+
+var migrationUpTemplate = template.Must(template.New("up").Parse(`CREATE TABLE {{.Table}} (
+	id INTEGER NOT NULL,
+	{{- range .Fields}}
+	{{.Column}},
+	{{- end}}
+
+	PRIMARY KEY(id)
+);
+`))
+
+var migrationDownTemplate = template.Must(template.New("down").Parse(`DROP TABLE {{.Table}};
+`))
+
+var controllerTemplate = template.Must(template.New("controller").Parse(`package controllers
+
+import (
+	"github.com/activegraph/activegraph/actioncontroller"
+	"github.com/activegraph/activegraph/actionview"
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activesupport"
+
+	"{{.Module}}/app/models"
+)
+
+// {{.Controller}} is a controller of the "{{.Model}}" resources.
+var {{.Controller}} = actioncontroller.New(func(c *actioncontroller.C) {
+	{{- if .Fields}}
+	c.Permit(
+		models.{{.Type}}.AttributesForInspect({{.Permitted}}),
+		actioncontroller.ActionCreate, actioncontroller.ActionUpdate,
+	)
+	{{- end}}
+
+	c.Index(func(ctx *actioncontroller.Context) actioncontroller.Result {
+		return actionview.ViewResult(activesupport.Return(models.{{.Type}}.WithContext(ctx).All().ToA()))
+	})
+
+	c.Show(func(ctx *actioncontroller.Context) actioncontroller.Result {
+		return actionview.ViewResult(models.{{.Type}}.WithContext(ctx).Find(ctx.Params["id"]))
+	})
+
+	c.Create(func(ctx *actioncontroller.Context) actioncontroller.Result {
+		return actionview.ViewResult(activerecord.Return(
+			models.{{.Type}}.WithContext(ctx).Create(ctx.Params.Get("{{.Model}}")),
+		))
+	})
+
+	c.Update(func(ctx *actioncontroller.Context) actioncontroller.Result {
+		record := models.{{.Type}}.WithContext(ctx).Find(ctx.Params["id"])
+		return actionview.ViewResult(record.AndThen(func(v interface{}) activesupport.Result {
+			rec := v.(*activerecord.ActiveRecord)
+			if err := rec.AssignAttributes(ctx.Params.Get("{{.Model}}")); err != nil {
+				return activesupport.Err(err)
+			}
+			return activesupport.Return(rec.Update())
+		}))
+	})
+
+	c.Destroy(func(ctx *actioncontroller.Context) actioncontroller.Result {
+		return actionview.ViewResult(models.{{.Type}}.WithContext(ctx).Find(ctx.Params["id"]).Delete())
+	})
+})
+`))
+
+// writeFile renders the template into the file, Go sources are formatted.
+// Existing files are never overwritten.
+func writeFile(path string, tmpl *template.Template, data interface{}) error {
+	if _, err := os.Stat(path); err == nil {
+		return errors.Errorf("%s already exists", path)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	b := buf.Bytes()
+	if filepath.Ext(path) == ".go" {
+		formatted, err := format.Source(b)
+		if err != nil {
+			return errors.Wrapf(err, "format %s", path)
+		}
+		b = formatted
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "create", path)
+	return nil
+}
+
+// modulePath returns the module path of the application in the current
+// directory.
+func modulePath() (string, error) {
+	b, err := os.ReadFile("go.mod")
+	if err != nil {
+		return "", errors.Wrap(err, "run the command from the application root")
+	}
+	m := regexp.MustCompile(`(?m)^module\s+(\S+)`).FindSubmatch(b)
+	if m == nil {
+		return "", errors.New("go.mod is missing module directive")
+	}
+	return strings.Trim(string(m[1]), `"`), nil
+}
+
+func generateModel(res resource) error {
+	err := writeFile(filepath.Join("app", "models", res.Model+".go"), modelTemplate, res)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join("db", "migrate")
+	version, err := nextVersion(dir)
+	if err != nil {
+		return err
+	}
+
+	base := filepath.Join(dir, version+"_create_"+res.Table)
+	if err = writeFile(base+".up.sql", migrationUpTemplate, res); err != nil {
+		return err
+	}
+	return writeFile(base+".down.sql", migrationDownTemplate, res)
+}
+
+// nextVersion returns the version of a new migration, the version is the
+// current UTC time, it's advanced when the migration with the same version
+// already exists.
+func nextVersion(dir string) (string, error) {
+	const layout = "20060102150405"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	t := now().UTC()
+	for _, entry := range entries {
+		version, _, _ := strings.Cut(entry.Name(), "_")
+		if v, err := time.Parse(layout, version); err == nil && !v.Before(t.Truncate(time.Second)) {
+			t = v.Add(time.Second)
+		}
+	}
+	return t.Format(layout), nil
+}
+
+func generateController(res resource) error {
+	path := filepath.Join("app", "controllers", res.Table+"_controller.go")
+	return writeFile(path, controllerTemplate, res)
+}
+
+// resourcesMarker marks the place of the resources registration within
+// the application main file.
+const resourcesMarker = "// activegraph:resources"
+
+// registerResource adds the resource to the application main file.
+func registerResource(res resource) error {
+	const path = "main.go"
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	src := string(b)
+
+	i := strings.Index(src, resourcesMarker)
+	if i < 0 {
+		return errors.Errorf("%s is missing %q marker", path, resourcesMarker)
+	}
+	lineStart := strings.LastIndex(src[:i], "\n") + 1
+	indent := src[lineStart:i]
+
+	registration := fmt.Sprintf("a.Resources(models.%s, controllers.%s)", res.Type, res.Controller)
+	if strings.Contains(src, registration) {
+		return nil
+	}
+	src = src[:lineStart] + indent + registration + "\n" + src[lineStart:]
+
+	// Packages of the application are imported within a separate group at
+	// the end of the import declaration.
+	for _, pkg := range []string{"app/controllers", "app/models"} {
+		importPath := fmt.Sprintf("%q", res.Module+"/"+pkg)
+		if strings.Contains(src, importPath) {
+			continue
+		}
+
+		start := strings.Index(src, "import (\n")
+		if start < 0 {
+			return errors.Errorf("%s is missing import declaration", path)
+		}
+		end := start + strings.Index(src[start:], "\n)\n")
+
+		group := "\n"
+		if strings.Contains(src[start:end], `"`+res.Module+"/") {
+			group = ""
+		}
+		src = src[:end] + "\n" + group + "\t" + importPath + src[end:]
+	}
+
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return errors.Wrapf(err, "format %s", path)
+	}
+	if err = os.WriteFile(path, formatted, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "update", path)
+	return nil
+}
+
+func runGenerate(args []string) error {
+	fs := newFlagSet("generate")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	res, err := newResource(fs.Arg(1), fs.Args()[2:])
+	if err != nil {
+		return err
+	}
+
+	switch generator := fs.Arg(0); generator {
+	case "model":
+		return generateModel(res)
+	case "controller", "scaffold":
+		if res.Module, err = modulePath(); err != nil {
+			return err
+		}
+		if generator == "controller" {
+			return generateController(res)
+		}
+		if err = generateModel(res); err != nil {
+			return err
+		}
+		if err = generateController(res); err != nil {
+			return err
+		}
+		return registerResource(res)
+	default:
+		return errors.Errorf("unknown generator %q", generator)
+	}
+}
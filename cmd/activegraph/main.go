@@ -0,0 +1,115 @@
+// Command activegraph creates and manages ActiveGraph applications.
+//
+// Usage:
+//
+//	activegraph <command> [arguments]
+//
+// The commands are:
+//
+//	new              create a new application skeleton
+//	generate         generate a model, controller or scaffold
+//	db:migrate       apply pending database migrations
+//	db:rollback      revert the last applied database migrations
+//	db:schema:dump   dump the database schema
+//	schema:print     print the GraphQL schema of the application
+//	schema:diff      compare the GraphQL schema with the previous one
+//	console          run SQL statements through the configured connection
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// stdout and stdin are replaced in tests.
+var (
+	stdout io.Writer = os.Stdout
+	stdin  io.Reader = os.Stdin
+)
+
+var (
+	// errUsage is returned, when the command is invoked incorrectly.
+	errUsage = errors.New("invalid usage")
+
+	// errSilent is returned by commands, that already reported the failure.
+	errSilent = errors.New("silent failure")
+)
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"new", "[-module path] name", "create a new application skeleton", runNew},
+		{"generate", "model|controller|scaffold name [field:type...]", "generate a model, controller or scaffold", runGenerate},
+		{"db:migrate", "[-config file] [-env env] [-dir dir]", "apply pending database migrations", runMigrate},
+		{"db:rollback", "[-config file] [-env env] [-dir dir] [-steps n]", "revert the last applied database migrations", runRollback},
+		{"db:schema:dump", "[-config file] [-env env] [-out file]", "dump the database schema", runSchemaDump},
+		{"schema:print", "[-pkg package]", "print the GraphQL schema of the application", runSchemaPrint},
+		{"schema:diff", "[-pkg package] old [new]", "compare the GraphQL schema with the previous one", runSchemaDiff},
+		{"console", "[-config file] [-env env]", "run SQL statements through the configured connection", runConsole},
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage:\n\n\tactivegraph <command> [arguments]\n\nThe commands are:\n\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-16s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+// newFlagSet returns a flag set of the command, that prints usage of the
+// command on parsing failures.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		for _, cmd := range commands {
+			if cmd.name == name {
+				fmt.Fprintf(fs.Output(), "Usage: activegraph %s %s\n", cmd.name, cmd.args)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return errUsage
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+	if strings.HasPrefix(args[0], "-") || args[0] == "help" {
+		usage()
+		return nil
+	}
+	return fmt.Errorf("unknown command %q, run 'activegraph help' for usage", args[0])
+}
+
+func main() {
+	switch err := run(os.Args[1:]); err {
+	case nil:
+	case errUsage, flag.ErrHelp:
+		os.Exit(2)
+	case errSilent:
+		os.Exit(1)
+	default:
+		fmt.Fprintln(os.Stderr, "activegraph:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activerecord/sqlite3"
+)
+
+// chdir changes the working directory for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func readFile(t *testing.T, path string) string {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		name, underscore, camelize, pluralize string
+	}{
+		{"Post", "post", "Post", "posts"},
+		{"BlogPost", "blog_post", "BlogPost", "blog_posts"},
+		{"HTTPRequest", "http_request", "HttpRequest", "http_requests"},
+		{"category", "category", "Category", "categories"},
+		{"box", "box", "Box", "boxes"},
+		{"day", "day", "Day", "days"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.underscore, underscore(tt.name), tt.name)
+		assert.Equal(t, tt.camelize, camelize(tt.name), tt.name)
+		assert.Equal(t, tt.pluralize, pluralize(underscore(tt.name)), tt.name)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	defer func() { stdout = os.Stdout }()
+
+	now = func() time.Time { return time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	require.NoError(t, run([]string{"new", "-module", "example.com/blog", "blog"}))
+	chdir(t, filepath.Join(dir, "blog"))
+
+	require.NoError(t, run([]string{"generate", "scaffold", "BlogPost", "title", "views:int", "author:references"}))
+	require.NoError(t, run([]string{"generate", "scaffold", "Category", "name:text"}))
+
+	assert.Equal(t, `package models
+
+import (
+	"github.com/activegraph/activegraph/activerecord"
+)
+
+// BlogPost is a model of the "blog_posts" table.
+var BlogPost = activerecord.New("blog_post", func(r *activerecord.R) {
+	r.AttrString("title")
+	r.AttrInt("views")
+	r.BelongsTo("author")
+})
+`, readFile(t, "app/models/blog_post.go"))
+
+	assert.Contains(t, readFile(t, "app/models/category.go"), `r.TableName("categories")`)
+
+	assert.Equal(t, `CREATE TABLE blog_posts (
+	id INTEGER NOT NULL,
+	title VARCHAR,
+	views INTEGER,
+	author_id INTEGER,
+
+	PRIMARY KEY(id)
+);
+`, readFile(t, "db/migrate/20260102150405_create_blog_posts.up.sql"))
+
+	// Versions of migrations generated at the same time are advanced.
+	assert.Equal(t, "DROP TABLE categories;\n",
+		readFile(t, "db/migrate/20260102150406_create_categories.down.sql"))
+
+	controller := readFile(t, "app/controllers/blog_posts_controller.go")
+	assert.Contains(t, controller, `var BlogPosts = actioncontroller.New(`)
+	assert.Contains(t, controller, `models.BlogPost.AttributesForInspect("title", "views", "author_id")`)
+	assert.Contains(t, controller, `ctx.Params.Get("blog_post")`)
+
+	main := readFile(t, "main.go")
+	assert.Contains(t, main, "\n\n\t\"example.com/blog/app/controllers\"\n\t\"example.com/blog/app/models\"\n)")
+	assert.Contains(t, main, "\t\ta.Resources(models.BlogPost, controllers.BlogPosts)\n"+
+		"\t\ta.Resources(models.Category, controllers.Categories)\n\t\t// activegraph:resources\n")
+
+	err := run([]string{"generate", "model", "Category"})
+	assert.EqualError(t, err, filepath.Join("app", "models", "category.go")+" already exists")
+
+	err = run([]string{"generate", "model", "Tag", "name:float"})
+	assert.EqualError(t, err, `unsupported type "float" of field "name"`)
+}
+
+func TestDiffLines(t *testing.T) {
+	assert.Empty(t, diffLines("type A {\n  a: Int\n}\n", "type A {\n  a: Int\n}\n"))
+	assert.Equal(t, "-  a: Int\n+  a: Int!\n+  b: String\n",
+		diffLines("type A {\n  a: Int\n}\n", "type A {\n  a: Int!\n  b: String\n}\n"))
+}
+
+func TestConsole(t *testing.T) {
+	conn, err := sqlite3.Connect(activerecord.DatabaseConfig{
+		Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	in := strings.NewReader(strings.Join([]string{
+		"CREATE TABLE books (id INTEGER, title VARCHAR);",
+		"INSERT INTO books VALUES (1, 'Moby Dick'),",
+		"  (2, NULL);",
+		"SELECT * FROM books ORDER BY id;",
+		"SELECT * FROM missing;",
+		"exit",
+		"SELECT 1;",
+	}, "\n"))
+
+	var out bytes.Buffer
+	require.NoError(t, console(context.Background(), conn, in, &out))
+	assert.Equal(t, strings.Join([]string{
+		"activegraph> OK",
+		"activegraph> OK",
+		"activegraph> id\ttitle",
+		"1\tMoby Dick",
+		"2\tNULL",
+		"(2 rows)",
+		"activegraph> error: no such table: missing",
+		"activegraph> ",
+	}, "\n"), out.String())
+}
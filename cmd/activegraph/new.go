@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// project describes the generated application skeleton.
+type project struct {
+	Name   string
+	Module string
+}
+
+var skeleton = map[string]*template.Template{
+	"go.mod": template.Must(template.New("go.mod").Parse(`module {{.Module}}
+
+go 1.21
+`)),
+
+	"main.go": template.Must(template.New("main.go").Parse(`package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/activegraph/activegraph"
+	_ "github.com/activegraph/activegraph/activerecord/sqlite3"
+)
+
+func main() {
+	app := activegraph.New(func(a *activegraph.A) {
+		a.DatabaseFile = "config/database.yml"
+
+		` + resourcesMarker + `
+	})
+
+	if err := app.Run(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+`)),
+
+	"config/database.yml": template.Must(template.New("database.yml").Parse(`# Connections of the environment are established on the application boot,
+# DATABASE_URL environment variable overrides the primary connection.
+development:
+  adapter: sqlite3
+  database: db/development.sqlite3
+
+test:
+  adapter: sqlite3
+  database: db/test.sqlite3
+
+production:
+  adapter: sqlite3
+  database: ${DATABASE_PATH:-db/production.sqlite3}
+  pool: 5
+`)),
+
+	"app/models/models.go": template.Must(template.New("models.go").Parse(`// Package models contains models of the {{.Name}} application.
+package models
+`)),
+
+	"app/controllers/controllers.go": template.Must(template.New("controllers.go").Parse(`// Package controllers contains controllers of the {{.Name}} application.
+package controllers
+`)),
+
+	"db/migrate/.keep": template.Must(template.New(".keep").Parse(``)),
+
+	".gitignore": template.Must(template.New(".gitignore").Parse(`*.sqlite3
+`)),
+}
+
+func runNew(args []string) error {
+	fs := newFlagSet("new")
+	module := fs.String("module", "", "module path of the application, the name by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	p := project{Name: filepath.Base(fs.Arg(0)), Module: *module}
+	if p.Module == "" {
+		p.Module = p.Name
+	}
+
+	dir := fs.Arg(0)
+	if _, err := os.Stat(dir); err == nil {
+		return errors.Errorf("%s already exists", dir)
+	}
+
+	for name, tmpl := range skeleton {
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(name)), tmpl, p); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(stdout, "\nRun 'cd %s && go mod tidy' to install dependencies.\n", dir)
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// printSchema builds and runs the application package with "schema:print"
+// command, and returns the printed GraphQL schema.
+func printSchema(pkg string) (string, error) {
+	var out bytes.Buffer
+
+	cmd := exec.Command("go", "run", pkg, "schema:print")
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("print schema of %s: %w", pkg, err)
+	}
+	return out.String(), nil
+}
+
+func runSchemaPrint(args []string) error {
+	fs := newFlagSet("schema:print")
+	pkg := fs.String("pkg", ".", "main package of the application")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	schema, err := printSchema(*pkg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(stdout, schema)
+	return err
+}
+
+func runSchemaDiff(args []string) error {
+	fs := newFlagSet("schema:diff")
+	pkg := fs.String("pkg", ".", "main package of the application, when the new schema is omitted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	oldSchema, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var newSchema string
+	if fs.NArg() == 2 {
+		b, err := os.ReadFile(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		newSchema = string(b)
+	} else if newSchema, err = printSchema(*pkg); err != nil {
+		return err
+	}
+
+	diff := diffLines(string(oldSchema), newSchema)
+	if diff == "" {
+		return nil
+	}
+	fmt.Fprint(stdout, diff)
+	return errSilent
+}
+
+// diffLines returns removed and added lines prefixed with "-" and "+", the
+// lines are compared using the longest common subsequence. It returns an
+// empty string when texts are equal.
+func diffLines(a, b string) string {
+	x := strings.SplitAfter(a, "\n")
+	y := strings.SplitAfter(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	line := func(prefix, s string) {
+		if s == "" {
+			return
+		}
+		diff.WriteString(prefix + strings.TrimSuffix(s, "\n") + "\n")
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			line("-", x[i])
+			i++
+		default:
+			line("+", y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		line("-", x[i])
+	}
+	for ; j < len(y); j++ {
+		line("+", y[j])
+	}
+	return diff.String()
+}
@@ -0,0 +1,124 @@
+package activegraph
+
+import (
+	"context"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	qlast "github.com/graphql-go/graphql/language/ast"
+	"github.com/pkg/errors"
+)
+
+// workerPool limits the number of resolvers executed concurrently within
+// a single server.
+type workerPool struct {
+	sem chan struct{}
+}
+
+func newWorkerPool(size int) *workerPool {
+	if size < 1 {
+		panic("worker pool size must be positive")
+	}
+	return &workerPool{sem: make(chan struct{}, size)}
+}
+
+// Go schedules execution of the function in the pool and returns a thunk
+// that blocks until the function returns. GraphQL executor calls thunks only
+// after all sibling fields have been resolved.
+//
+// Panics of the function are converted to errors, so the failed resolver
+// does not bring the whole server down.
+func (p *workerPool) Go(
+	ctx context.Context, fn func() (interface{}, error),
+) func() (interface{}, error) {
+	type outcome struct {
+		res interface{}
+		err error
+	}
+
+	done := make(chan outcome, 1)
+
+	go func() {
+		select {
+		case p.sem <- struct{}{}:
+		case <-ctx.Done():
+			done <- outcome{err: ctx.Err()}
+			return
+		}
+
+		defer func() { <-p.sem }()
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: errors.Errorf("activegraph: resolver panic: %v", r)}
+			}
+		}()
+
+		res, err := fn()
+		done <- outcome{res, err}
+	}()
+
+	return func() (interface{}, error) {
+		out := <-done
+		return out.res, out.err
+	}
+}
+
+type workerPoolKey struct{}
+
+// ResolvedFunc is called when the resolver of the field executed concurrently
+// returns. GraphQL extensions are notified only about scheduling of such
+// resolvers, so the function allows to observe the actual resolution.
+type ResolvedFunc func(info graphql.ResolveInfo, start time.Time, err error)
+
+type resolvedFuncKey struct{}
+
+// WithResolvedFunc returns a copy of the context, concurrent resolvers of
+// the request with the returned context call the function on completion.
+func WithResolvedFunc(ctx context.Context, fn ResolvedFunc) context.Context {
+	return context.WithValue(ctx, resolvedFuncKey{}, fn)
+}
+
+// poolHandler puts the worker pool into the request context, so resolvers
+// marked as concurrent could be executed within the pool.
+type poolHandler struct {
+	handler Handler
+	pool    *workerPool
+}
+
+func (h *poolHandler) Serve(rw ResponseWriter, r *Request) {
+	ctx := context.WithValue(r.Context(), workerPoolKey{}, h.pool)
+	h.handler.Serve(rw, r.WithContext(ctx))
+}
+
+// newConcurrentFunc wraps the field resolve function of the function definition
+// to execute it in the worker pool, when the function is marked as concurrent.
+//
+// Only fields of the query operations are resolved concurrently, mutation
+// fields must be executed serially according to the GraphQL specification.
+func newConcurrentFunc(funcdef FuncDef, fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	if !funcdef.Concurrent {
+		return fn
+	}
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		pool, ok := p.Context.Value(workerPoolKey{}).(*workerPool)
+		if !ok {
+			return fn(p)
+		}
+
+		opdef, ok := p.Info.Operation.(*qlast.OperationDefinition)
+		if !ok || opdef.Operation != OperationQuery {
+			return fn(p)
+		}
+
+		start := time.Now()
+		resolved, _ := p.Context.Value(resolvedFuncKey{}).(ResolvedFunc)
+
+		return pool.Go(p.Context, func() (interface{}, error) {
+			res, err := fn(p)
+			if resolved != nil {
+				resolved(p.Info, start, err)
+			}
+			return res, err
+		}), nil
+	}
+}
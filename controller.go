@@ -7,21 +7,26 @@ import (
 	"mime"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
 	qlast "github.com/graphql-go/graphql/language/ast"
 	qlexpr "github.com/graphql-go/graphql/language/parser"
 	qlsrc "github.com/graphql-go/graphql/language/source"
 	"github.com/pkg/errors"
+
+	"github.com/activegraph/activegraph/actioncontroller"
+	actiongraphql "github.com/activegraph/activegraph/actioncontroller/graphql"
 )
 
 // textHandler creates an HTTP handler that writes the given string
 // and status as a response.
 func textHandler(status int, text string) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		rw.WriteHeader(status)
-		rw.Header().Add("Content-Type", "text/plain")
 		rw.Write([]byte(text))
 	}
 }
@@ -87,6 +92,9 @@ type Request struct {
 	// These headers must be provided by the underlying HTTP request.
 	Header http.Header
 
+	// RemoteAddr is the network address of the client sent the request.
+	RemoteAddr string
+
 	// ctx represents the execution context of the request.
 	ctx context.Context
 
@@ -95,19 +103,45 @@ type Request struct {
 	document *qlast.Document `json:"-"`
 }
 
+// Document returns the parsed GraphQL document of the request.
+func (r *Request) Document() *qlast.Document {
+	return r.document
+}
+
+// Operation returns the type of the operation selected for execution, or
+// OperationUnknown when the document does not define the operation.
 func (r *Request) Operation() string {
-	if r.document == nil {
+	opdef := r.OperationDefinition()
+	if opdef == nil {
 		return OperationUnknown
 	}
-	if len(r.document.Definitions) < 1 {
-		return OperationUnknown
+	return opdef.Operation
+}
+
+// OperationDefinition returns the definition of the operation selected by
+// the operation name. Name could be omitted only when the document contains
+// a single operation.
+func (r *Request) OperationDefinition() *qlast.OperationDefinition {
+	if r.document == nil {
+		return nil
 	}
 
-	opdef, ok := r.document.Definitions[0].(*qlast.OperationDefinition)
-	if !ok {
-		return OperationUnknown
+	var selected *qlast.OperationDefinition
+	for _, def := range r.document.Definitions {
+		opdef, ok := def.(*qlast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if r.OperationName == "" {
+			if selected != nil {
+				return nil
+			}
+			selected = opdef
+		} else if opdef.Name != nil && opdef.Name.Value == r.OperationName {
+			return opdef
+		}
 	}
-	return opdef.Operation
+	return selected
 }
 
 // Context returns the request's context.
@@ -156,7 +190,7 @@ func parsePost(r *http.Request) (gr *Request, err error) {
 // as part of form request.
 //
 // Method ensures that query contains a valid GraphQL document and returns an error
-// if it's not true.
+// if it's not true. Syntax errors of the document are returned as *gqlerrors.Error.
 func ParseRequest(r *http.Request, schema *graphql.Schema) (gr *Request, err error) {
 	// Parse URL only when request is submitted with "GET" verb.
 	switch r.Method {
@@ -167,6 +201,9 @@ func ParseRequest(r *http.Request, schema *graphql.Schema) (gr *Request, err err
 	default:
 		return gr, errors.Errorf("%s or %s verb is expected", http.MethodPost, http.MethodGet)
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	src := qlsrc.NewSource(&qlsrc.Source{
 		Body: []byte(gr.Query), Name: "Request Query",
@@ -179,6 +216,7 @@ func ParseRequest(r *http.Request, schema *graphql.Schema) (gr *Request, err err
 
 	// Copy the context of the HTTP request.
 	gr.Header = r.Header.Clone()
+	gr.RemoteAddr = r.RemoteAddr
 	gr.ctx = r.Context()
 	gr.schema = schema
 
@@ -187,13 +225,74 @@ func ParseRequest(r *http.Request, schema *graphql.Schema) (gr *Request, err err
 
 // ResponseWriter interface is used by a GraphQL handler to construct a response.
 type ResponseWriter interface {
+	// Header returns the header map of the underlying HTTP response, changes
+	// of the header after writing the result have no effect.
+	Header() http.Header
+
 	Write(res *graphql.Result) error
 
 	IsWritten() bool
 }
 
+// StatusWriter is implemented by response writers of HTTP requests, that
+// allow callbacks to set the status code of the response, e.g. to reject
+// the request with 429 Too Many Requests.
+type StatusWriter interface {
+	WriteStatus(statusCode int)
+}
+
+// WriteStatus sets the status code of the HTTP response, the status is not
+// set, when the writer does not implement StatusWriter.
+func WriteStatus(rw ResponseWriter, statusCode int) {
+	if sw, ok := rw.(StatusWriter); ok {
+		sw.WriteStatus(statusCode)
+	}
+}
+
+// WrittenNotifier is implemented by response writers of HTTP requests, that
+// report the number of bytes of the response body written to the client,
+// e.g. to measure sizes of responses.
+type WrittenNotifier interface {
+	NotifyWritten(fn func(n int))
+}
+
+// NotifyWritten registers the function called with the number of bytes
+// written to the client, when the response is complete. The function is
+// never called, when the writer does not implement WrittenNotifier.
+func NotifyWritten(rw ResponseWriter, fn func(n int)) {
+	if wn, ok := rw.(WrittenNotifier); ok {
+		wn.NotifyWritten(fn)
+	}
+}
+
+// writtenNotifier keeps functions registered with NotifyWritten.
+type writtenNotifier struct {
+	fns []func(n int)
+}
+
+func (wn *writtenNotifier) NotifyWritten(fn func(n int)) {
+	wn.fns = append(wn.fns, fn)
+}
+
+func (wn *writtenNotifier) notify(n int) {
+	for _, fn := range wn.fns {
+		fn(n)
+	}
+}
+
 type responseWriter struct {
+	writtenNotifier
+
+	header http.Header
 	result *graphql.Result
+	status int
+}
+
+func (rw *responseWriter) Header() http.Header {
+	if rw.header == nil {
+		rw.header = make(http.Header)
+	}
+	return rw.header
 }
 
 func (rw *responseWriter) Write(res *graphql.Result) error {
@@ -205,6 +304,24 @@ func (rw *responseWriter) IsWritten() bool {
 	return rw.result != nil
 }
 
+func (rw *responseWriter) WriteStatus(statusCode int) {
+	rw.status = statusCode
+}
+
+// StreamResponseWriter is a ResponseWriter that delivers the result of the
+// query incrementally, the initial result is written with Write method and
+// deferred fragments and streamed lists are written with WriteIncremental.
+//
+// The GraphQL handler uses StreamResponseWriter for queries with @defer and
+// @stream directives, when the client accepts "multipart/mixed" responses.
+type StreamResponseWriter interface {
+	ResponseWriter
+
+	// WriteIncremental writes a subsequent payload of the result, the
+	// payload with HasNext set to false completes the response.
+	WriteIncremental(res *IncrementalResult) error
+}
+
 // Handler responds to a GraphQL request.
 //
 // Serve would write the reply to the ResponseWriter and then returns. Returning
@@ -220,7 +337,17 @@ func (fn HandlerFunc) Serve(rw ResponseWriter, r *Request) {
 }
 
 // DefaultHandler is a default handler used by GraphQLhandler.
+//
+// When the response writer is a StreamResponseWriter, the query with @defer
+// and @stream directives is delivered incrementally.
 func DefaultHandler(rw ResponseWriter, r *Request) {
+	if srw, ok := rw.(StreamResponseWriter); ok {
+		if op := newIncrementalOperation(r); op != nil {
+			executeIncremental(srw, r, op)
+			return
+		}
+	}
+
 	result := graphql.Execute(graphql.ExecuteParams{
 		Schema:        *r.schema,
 		AST:           r.document,
@@ -245,6 +372,40 @@ type Controller struct {
 	Queries   []FuncDef
 	Mutations []FuncDef
 
+	// Resources are models mapped to actions of controllers, queries and
+	// mutations of the resources are served along with the functions, and
+	// share types with them. See HandleResources.
+	Resources *actiongraphql.Mapper
+
+	// Federation exposes the schema as an Apollo Federation v2 subgraph
+	// with "_service" and "_entities" queries. Federation is enabled
+	// implicitly, when any type declares keys, see TypeDef.Keys.
+	Federation bool
+
+	// Extensions are notified about the execution of each operation and
+	// resolution of each field, see graphql.Extension for details.
+	Extensions []graphql.Extension
+
+	// MaxConcurrency limits the number of functions executed concurrently
+	// across all requests. Only functions marked as Concurrent are executed
+	// in parallel, when the value is zero, all fields are resolved serially.
+	MaxConcurrency int
+
+	// Cache is used to store responses of the public queries. When the cache
+	// is not specified, queries are executed on each request.
+	Cache ResponseCache
+
+	// Playground defines settings of the in-browser IDE.
+	Playground Playground
+
+	// Profile defines security settings of the server, by default the
+	// development profile is used. See Profile for details.
+	Profile Profile
+
+	// MaxBodySize limits the size of the request body, it overrides the
+	// limit of the profile.
+	MaxBodySize int64
+
 	callbacksAround []callbackAround
 	callbacksBefore []callback
 	callbacksAfter  []callback
@@ -323,8 +484,8 @@ func (s *Controller) AppendAroundOp(op string, cb AroundCallback) {
 //	var s activegraph.Controller
 //
 //	// Since HTTP middleware does not differenciate different GrapQL operations
-//	// there is no other way of limiting access to specific queries other than
-//	// accessing GraphQL requests.
+//	// the whole operation could be limited only by accessing GraphQL requests.
+//	// Use Authorize to limit access to specific queries and fields.
 //	s.AppendBeforeOp(OperationMutation, func(rw rs.ResponseWriter, r *rs.Request) {
 //		if !isQueryAuthorized(rw, r) {
 //			rw.Write(&graphql.Result{
@@ -382,17 +543,48 @@ func (c *Controller) HandleMutation(name string, fn interface{}) *Controller {
 	return c
 }
 
+// HandleResources maps the model to actions of the controller, see
+// actiongraphql.Mapper for details.
+func (c *Controller) HandleResources(
+	model actioncontroller.AbstractModel, controller actioncontroller.AbstractController,
+) *Controller {
+	if c.Resources == nil {
+		c.Resources = new(actiongraphql.Mapper)
+	}
+	c.Resources.Resources(model, controller)
+	return c
+}
+
 // CreateSchema returns compiled GraphQL schema from type and function
-// definitions.
+// definitions, and mapped resources.
 func (c *Controller) CreateSchema() (schema graphql.Schema, err error) {
 	var graphql GraphQL
 
-	// Register all defined types and functions within a GraphQL compiler.
+	// Register all defined types and functions within a GraphQL compiler,
+	// nodes are registered first, since other types could reference them.
+	for _, typedef := range c.Types {
+		if typedef.Node == nil {
+			continue
+		}
+		if err = graphql.AddType(typedef); err != nil {
+			return schema, err
+		}
+	}
 	for _, typedef := range c.Types {
+		if typedef.Node != nil {
+			continue
+		}
 		if err = graphql.AddType(typedef); err != nil {
 			return schema, err
 		}
 	}
+	// Resources are registered before functions, so functions could return
+	// types of the resources.
+	if c.Resources != nil {
+		if err = graphql.AddResources(c.Resources); err != nil {
+			return schema, err
+		}
+	}
 	for _, funcdef := range c.Queries {
 		if err = graphql.AddQuery(funcdef); err != nil {
 			return schema, err
@@ -403,37 +595,310 @@ func (c *Controller) CreateSchema() (schema graphql.Schema, err error) {
 			return schema, err
 		}
 	}
+	for _, ext := range c.Extensions {
+		graphql.AddExtension(ext)
+	}
+	if c.Federation {
+		graphql.EnableFederation()
+	}
 	return graphql.CreateSchema()
 }
 
-func graphqlHandler(h Handler, schema graphql.Schema) http.HandlerFunc {
+const (
+	// Media types of the GraphQL response.
+	mediaTypeJSON            = "application/json"
+	mediaTypeGraphQLResponse = "application/graphql-response+json"
+	mediaTypeMultipart       = "multipart/mixed"
+)
+
+// negotiateMediaType returns a media type of the response accepted by the
+// client, or an empty string when none of the supported types is acceptable.
+//
+// According to the GraphQL-over-HTTP specification, requests without Accept
+// header are served with "application/json" for the compatibility with the
+// legacy clients, the same type is used for wildcards.
+func negotiateMediaType(accept string) string {
+	if accept == "" {
+		return mediaTypeJSON
+	}
+
+	var (
+		mediaType string
+		quality   = -1.0
+	)
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mt {
+		case mediaTypeGraphQLResponse:
+		case mediaTypeJSON, "application/*", "*/*":
+			mt = mediaTypeJSON
+		default:
+			continue
+		}
+
+		// The new media type is preferred, when both types are accepted
+		// with the same quality.
+		if q > 0 && (q > quality || (q == quality && mt == mediaTypeGraphQLResponse)) {
+			mediaType, quality = mt, q
+		}
+	}
+	return mediaType
+}
+
+// requestErrorStatus returns a status code of the response to the request
+// that could not be executed, e.g. due to parse or validation errors.
+//
+// Legacy "application/json" responses are always written with 200 status,
+// so clients could read errors from the body.
+func requestErrorStatus(mediaType string) int {
+	if mediaType == mediaTypeGraphQLResponse {
+		return http.StatusBadRequest
+	}
+	return http.StatusOK
+}
+
+// resultHandler creates an HTTP handler that writes the given GraphQL result
+// and status as a response.
+func resultHandler(status int, mediaType string, res *graphql.Result) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		b, err := json.Marshal(res)
+		if err != nil {
+			h := textHandler(http.StatusInternalServerError, err.Error())
+			h.ServeHTTP(rw, r)
+			return
+		}
+
+		rw.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+		rw.WriteHeader(status)
+		rw.Write(b)
+	}
+}
+
+// errorsHandler creates an HTTP handler that writes the given errors as
+// a GraphQL result and status as a response.
+func errorsHandler(status int, mediaType string, errs ...error) http.HandlerFunc {
+	res := graphql.Result{Errors: make([]gqlerrors.FormattedError, 0, len(errs))}
+	for _, err := range errs {
+		res.Errors = append(res.Errors, gqlerrors.FormatError(err))
+	}
+	return resultHandler(status, mediaType, &res)
+}
+
+// handlerOptions are optional settings of the GraphQL HTTP handler.
+type handlerOptions struct {
+	cache ResponseCache
+
+	// playground enables in-browser IDE for browser requests.
+	playground bool
+	ide        Playground
+
+	// introspection enables __schema and __type queries.
+	introspection bool
+
+	// maskErrors replaces error messages with a generic message.
+	maskErrors bool
+
+	// maxBodySize limits the size of the request body, no limit when zero.
+	maxBodySize int64
+}
+
+// graphqlHandler serves GraphQL requests according to the GraphQL-over-HTTP
+// specification: the media type of the response is negotiated with Accept
+// header, and mutations are accepted only through POST requests.
+func graphqlHandler(h Handler, schema graphql.Schema, opts handlerOptions) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
+		if opts.playground && handlePlaygroundAssets(rw, r, opts.ide) {
+			return
+		}
+
 		acceptHeader := r.Header.Get("Accept")
-		if _, ok := r.URL.Query()["raw"]; !ok && strings.Contains(acceptHeader, "text/html") {
-			handlePlayground(rw, r)
+		_, raw := r.URL.Query()["raw"]
+		if opts.playground && !raw && strings.Contains(acceptHeader, "text/html") {
+			handlePlayground(rw, r, opts.ide)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			rw.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+			h := textHandler(http.StatusMethodNotAllowed, "method not allowed")
+			h.ServeHTTP(rw, r)
 			return
 		}
 
+		// Clients accepting incremental delivery receive JSON responses, when
+		// the incremental delivery is not applicable.
+		mediaType := negotiateMediaType(acceptHeader)
+		multipart := acceptsMultipart(acceptHeader)
+		if mediaType == "" && multipart {
+			mediaType = mediaTypeJSON
+		}
+		if mediaType == "" {
+			h := textHandler(http.StatusNotAcceptable,
+				"supported media types: "+mediaTypeGraphQLResponse+", "+mediaTypeJSON)
+			h.ServeHTTP(rw, r)
+			return
+		}
+
+		var body *limitedBody
+		if opts.maxBodySize > 0 && r.Body != nil {
+			if r.ContentLength > opts.maxBodySize {
+				h := errorsHandler(http.StatusRequestEntityTooLarge, mediaType, errBodyTooLarge)
+				h.ServeHTTP(rw, r)
+				return
+			}
+			body = &limitedBody{ReadCloser: r.Body, remaining: opts.maxBodySize}
+			r.Body = body
+		}
+
 		gr, err := ParseRequest(r, &schema)
+		if body != nil && body.exceeded {
+			h := errorsHandler(http.StatusRequestEntityTooLarge, mediaType, errBodyTooLarge)
+			h.ServeHTTP(rw, r)
+			return
+		}
 		if err != nil {
-			h := textHandler(http.StatusBadRequest, err.Error())
+			// Syntax errors of the document are GraphQL request errors, while
+			// the rest are errors of the HTTP request.
+			status := http.StatusBadRequest
+			if _, ok := err.(*gqlerrors.Error); ok {
+				status = requestErrorStatus(mediaType)
+			}
+			h := errorsHandler(status, mediaType, err)
+			h.ServeHTTP(rw, r)
+			return
+		}
+
+		if r.Method != http.MethodPost && gr.Operation() == OperationMutation {
+			rw.Header().Set("Allow", http.MethodPost)
+			h := errorsHandler(http.StatusMethodNotAllowed, mediaType,
+				errors.New("mutations are accepted only via POST"))
+			h.ServeHTTP(rw, r)
+			return
+		}
+		if !opts.introspection && hasIntrospection(gr.document) {
+			h := errorsHandler(requestErrorStatus(mediaType), mediaType,
+				errors.New("introspection is disabled"))
 			h.ServeHTTP(rw, r)
 			return
 		}
 
+		if vr := graphql.ValidateDocument(&schema, gr.document, nil); !vr.IsValid {
+			h := resultHandler(requestErrorStatus(mediaType), mediaType, &graphql.Result{Errors: vr.Errors})
+			h.ServeHTTP(rw, r)
+			return
+		}
+		if gr.OperationDefinition() == nil {
+			err := errors.New("must provide operation name if query contains multiple operations")
+			if gr.OperationName != "" {
+				err = errors.Errorf("unknown operation named %q", gr.OperationName)
+			}
+			h := errorsHandler(requestErrorStatus(mediaType), mediaType, err)
+			h.ServeHTTP(rw, r)
+			return
+		}
+
+		// Incremental results are never cached, since they are written
+		// directly to the client.
+		if multipart && newIncrementalOperation(gr) != nil {
+			mw := multipartWriter{rw: rw, maskErrors: opts.maskErrors}
+			h.Serve(&mw, gr)
+			mw.Close()
+			mw.notify(mw.size)
+			return
+		}
+
+		var (
+			b         []byte
+			hint      CacheHint
+			cacheable bool
+			lookup    *cacheLookup
+			status    = http.StatusOK
+			ctx       = gr.Context()
+		)
+
+		// Public query results are served from the cache by the innermost
+		// handler, so callbacks are executed for the cached responses as well,
+		// and only resolvers are skipped.
+		isQuery := gr.Operation() == OperationQuery
+		if opts.cache != nil && isQuery {
+			lookup = &cacheLookup{cache: opts.cache, key: requestCacheKey(gr)}
+			ctx = context.WithValue(ctx, cacheLookupKey{}, lookup)
+		}
+
 		// Serve the GraphQL request and write the result through HTTP.
-		var grw responseWriter
-		h.Serve(&grw, gr)
+		var (
+			grw    = responseWriter{header: rw.Header()}
+			policy cachePolicy
+		)
+		h.Serve(&grw, gr.WithContext(context.WithValue(ctx, cachePolicyKey{}, &policy)))
+
+		if opts.maskErrors && grw.result != nil {
+			maskErrors(grw.result.Errors)
+		}
 
-		b, err := json.Marshal(grw.result)
+		b, err = json.Marshal(grw.result)
 		if err != nil {
 			h := textHandler(http.StatusInternalServerError, err.Error())
 			h.ServeHTTP(rw, r)
 			return
 		}
 
-		rw.WriteHeader(http.StatusOK)
-		rw.Write(b)
+		// Result without data is a result of the request error, e.g.
+		// invalid variables or rejection by the callback.
+		if grw.result == nil || (grw.result.Data == nil && grw.result.HasErrors()) {
+			status = requestErrorStatus(mediaType)
+		}
+		if grw.status != 0 {
+			status = grw.status
+		}
+
+		hint, cacheable = policy.Hint()
+		if lookup != nil && lookup.hit {
+			hint, cacheable = CacheHint{MaxAge: lookup.ttl, Scope: CachePublic}, true
+		}
+
+		// Responses with errors are never cached, since errors could be
+		// caused by the temporary failures. Extensions of the result are
+		// specific to the execution, e.g. tracing of resolvers.
+		cacheable = cacheable && isQuery && grw.result != nil && !grw.result.HasErrors() &&
+			len(grw.result.Extensions) == 0
+
+		if cacheable && lookup != nil && !lookup.hit && hint.Scope == CachePublic {
+			opts.cache.Set(gr.Context(), lookup.key, b, hint.MaxAge)
+		}
+
+		rw.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+		rw.Header().Add("Vary", "Accept")
+
+		if r.Method == http.MethodGet && isQuery && status == http.StatusOK {
+			if cacheable {
+				rw.Header().Set("Cache-Control", hint.String())
+			}
+
+			tag := etag(b)
+			rw.Header().Set("ETag", tag)
+
+			if matchETag(r.Header.Get("If-None-Match"), tag) {
+				rw.WriteHeader(http.StatusNotModified)
+				grw.notify(0)
+				return
+			}
+		}
+
+		rw.WriteHeader(status)
+		n, _ := rw.Write(b)
+		grw.notify(n)
 	}
 }
 
@@ -441,10 +906,10 @@ func graphqlHandler(h Handler, schema graphql.Schema) http.HandlerFunc {
 // request from URL, body, or form and executes request using the specifies
 // schema.
 //
-// On failed request parsing and execution method writes plain error message
+// On failed request parsing and validation method writes GraphQL errors
 // as a response.
 func GraphQLHandler(schema graphql.Schema) http.HandlerFunc {
-	return graphqlHandler(HandlerFunc(DefaultHandler), schema)
+	return graphqlHandler(HandlerFunc(DefaultHandler), schema, Development.options())
 }
 
 type callbackHandler struct {
@@ -455,9 +920,7 @@ type callbackHandler struct {
 
 func (ch *callbackHandler) Serve(rw ResponseWriter, r *Request) {
 	for i := range ch.before {
-		if !rw.IsWritten() {
-			ch.before[i].Serve(rw, r)
-		} else {
+		if ch.before[i].Serve(rw, r); rw.IsWritten() {
 			return
 		}
 	}
@@ -469,6 +932,16 @@ func (ch *callbackHandler) Serve(rw ResponseWriter, r *Request) {
 	}
 }
 
+// actionRequestHandler puts the request of actions into the request context.
+type actionRequestHandler struct {
+	handler Handler
+}
+
+func (h *actionRequestHandler) Serve(rw ResponseWriter, r *Request) {
+	ctx := actioncontroller.WithRequest(r.Context(), &actioncontroller.Request{Header: r.Header})
+	h.handler.Serve(rw, r.WithContext(ctx))
+}
+
 // HandleHTTP creates a new HTTP handler used to process GraphQL requests.
 //
 // This function registers all type and function definitions in the GraphQL
@@ -477,14 +950,27 @@ func (ch *callbackHandler) Serve(rw ResponseWriter, r *Request) {
 // On duplicate types, queries or mutations, function panics.
 func (c *Controller) HandleHTTP() http.Handler {
 	// There is no reason to create a server that always returns errors.
-	schema, err := c.CreateSchema()
+	h, err := c.Handler()
 	if err != nil {
 		panic(err)
 	}
+	return h
+}
+
+// Handler returns the HTTP handler of the controller, it returns an error
+// when the schema could not be created.
+func (c *Controller) Handler() (http.Handler, error) {
+	schema, err := c.CreateSchema()
+	if err != nil {
+		return nil, err
+	}
 
 	// Wrap all registered AroundCallbacks to execute them in order: the latest
 	// registered callback should be executed last.
-	var h Handler = HandlerFunc(DefaultHandler)
+	//
+	// The response cache is the innermost handler, so cached responses are
+	// still processed by all callbacks, e.g. rate limits and tracing.
+	var h Handler = &cacheHandler{HandlerFunc(DefaultHandler)}
 	for i := range c.callbacksAround {
 		h = c.callbacksAround[i].createHandler(h)
 	}
@@ -498,5 +984,24 @@ func (c *Controller) HandleHTTP() http.Handler {
 	copy(after, c.callbacksAfter)
 
 	h = &callbackHandler{h, before, after}
-	return graphqlHandler(h, schema)
+
+	// Put the worker pool into the context of each request, so it will be
+	// available to the concurrent resolvers and all registered callbacks.
+	if c.MaxConcurrency > 0 {
+		h = &poolHandler{h, newWorkerPool(c.MaxConcurrency)}
+	}
+
+	// Put the request into the context, so matchers of the mapped resources
+	// could choose actions by the request.
+	if c.Resources != nil {
+		h = &actionRequestHandler{h}
+	}
+
+	opts := c.Profile.options()
+	opts.cache = c.Cache
+	opts.ide = c.Playground
+	if c.MaxBodySize > 0 {
+		opts.maxBodySize = c.MaxBodySize
+	}
+	return graphqlHandler(h, schema, opts), nil
 }
@@ -6,11 +6,16 @@ import (
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"testing/quick"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestServer_ServeHTTPBasic(t *testing.T) {
@@ -69,3 +74,156 @@ func TestServer_ServeHTTPBasic(t *testing.T) {
 	err := quick.Check(test, nil)
 	assert.NoError(t, err)
 }
+
+func TestController_ConcurrentQueries(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Each function waits for its sibling, so the request completes only when
+	// both functions are executed concurrently.
+	barrier := func(ctx context.Context) (string, error) {
+		wg.Done()
+
+		done := make(chan struct{})
+		go func() { wg.Wait(); close(done) }()
+
+		select {
+		case <-done:
+			return "ok", nil
+		case <-time.After(5 * time.Second):
+			return "", errors.New("sibling is not executed concurrently")
+		}
+	}
+
+	first, second := NewFunc("first", barrier), NewFunc("second", barrier)
+	first.Concurrent, second.Concurrent = true, true
+
+	s := Controller{MaxConcurrency: 2, Queries: []FuncDef{first, second}}
+
+	var (
+		rw = httptest.NewRecorder()
+		r  = httptest.NewRequest(http.MethodGet, "/graphql?query={first,second}", nil)
+	)
+
+	s.HandleHTTP().ServeHTTP(rw, r)
+	require.Equal(t, http.StatusOK, rw.Code)
+	assert.JSONEq(t, `{"data":{"first":"ok","second":"ok"}}`, rw.Body.String())
+}
+
+func TestController_ConcurrentQueriesError(t *testing.T) {
+	fail := NewFunc("fail", func(ctx context.Context) (*string, error) {
+		return nil, errors.New("failed")
+	})
+	crash := NewFunc("crash", func(ctx context.Context) (*string, error) {
+		panic("crashed")
+	})
+	ok := NewFunc("ok", func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	fail.Concurrent, crash.Concurrent, ok.Concurrent = true, true, true
+
+	s := Controller{MaxConcurrency: 1, Queries: []FuncDef{fail, crash, ok}}
+
+	var (
+		rw = httptest.NewRecorder()
+		r  = httptest.NewRequest(http.MethodGet, "/graphql?query={fail,crash,ok}", nil)
+	)
+
+	s.HandleHTTP().ServeHTTP(rw, r)
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	var body struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []struct {
+			Message string        `json:"message"`
+			Path    []interface{} `json:"path"`
+		} `json:"errors"`
+	}
+
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &body))
+	assert.Equal(t, map[string]interface{}{"fail": nil, "crash": nil, "ok": "ok"}, body.Data)
+	assert.Len(t, body.Errors, 2)
+}
+
+func TestController_GraphQLOverHTTP(t *testing.T) {
+	type Input struct {
+		Name string `json:"name"`
+	}
+
+	s := Controller{
+		Queries: []FuncDef{
+			NewFunc("posts", func(ctx context.Context) ([]string, error) {
+				return []string{"First"}, nil
+			}),
+		},
+		Mutations: []FuncDef{
+			NewFunc("rename", func(ctx context.Context, in Input) (string, error) {
+				return in.Name, nil
+			}),
+		},
+	}
+	h := s.HandleHTTP()
+
+	const (
+		legacy = "application/json"
+		strict = "application/graphql-response+json"
+	)
+
+	get := func(accept, query string, params ...string) *http.Request {
+		values := url.Values{"query": {query}}
+		for i := 0; i+1 < len(params); i += 2 {
+			values.Set(params[i], params[i+1])
+		}
+		r := httptest.NewRequest(http.MethodGet, "/graphql?"+values.Encode(), nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		return r
+	}
+	post := func(accept, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", accept)
+		return r
+	}
+
+	const multi = `query A{posts} mutation B{rename(input:{name:"x"})}`
+
+	tests := []struct {
+		name        string
+		r           *http.Request
+		status      int
+		contentType string
+		body        string
+	}{
+		{"NoAccept", get("", "{posts}"), http.StatusOK, legacy, `"posts":["First"]`},
+		{"Wildcard", get("*/*", "{posts}"), http.StatusOK, legacy, `"posts":["First"]`},
+		{"Strict", get(strict, "{posts}"), http.StatusOK, strict, `"posts":["First"]`},
+		{"Preferred", get(legacy+", "+strict, "{posts}"), http.StatusOK, strict, `"posts":["First"]`},
+		{"Quality", get(legacy+", "+strict+";q=0.5", "{posts}"), http.StatusOK, legacy, `"posts":["First"]`},
+		{"NotAcceptable", get("text/xml", "{posts}"), http.StatusNotAcceptable, "text/plain", "application/json"},
+		{"ParseError", get(strict, "{posts"), http.StatusBadRequest, strict, `"locations"`},
+		{"ParseErrorLegacy", get(legacy, "{posts"), http.StatusOK, legacy, `"locations"`},
+		{"ValidationError", get(strict, "{comments}"), http.StatusBadRequest, strict, `comments`},
+		{"ValidationErrorLegacy", get(legacy, "{comments}"), http.StatusOK, legacy, `comments`},
+		{"InvalidBody", post(strict, "{"), http.StatusBadRequest, strict, `"errors"`},
+		{"GetMutation", get(strict, `mutation{rename(input:{name:"x"})}`), http.StatusMethodNotAllowed, strict, "POST"},
+		{"SelectQuery", get(strict, multi, "operationName", "A"), http.StatusOK, strict, `"posts":["First"]`},
+		{"SelectMutation", get(strict, multi, "operationName", "B"), http.StatusMethodNotAllowed, strict, "POST"},
+		{"PostMutation", post(strict, `{"query":"`+strings.ReplaceAll(multi, `"`, `\"`)+`","operationName":"B"}`), http.StatusOK, strict, `"rename":"x"`},
+		{"NoOperationName", get(strict, multi), http.StatusBadRequest, strict, "operation name"},
+		{"UnknownOperation", get(strict, multi, "operationName", "C"), http.StatusBadRequest, strict, `\"C\"`},
+		{"Method", httptest.NewRequest(http.MethodPut, "/graphql", nil), http.StatusMethodNotAllowed, "text/plain", "not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, tt.r)
+
+			assert.Equal(t, tt.status, rw.Code)
+			assert.Contains(t, rw.Header().Get("Content-Type"), tt.contentType)
+			assert.Contains(t, rw.Body.String(), tt.body)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package activegraph
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Product struct {
+	UPC   string `json:"upc"`
+	Title string `json:"title"`
+}
+
+func newFederatedController() Controller {
+	products := map[string]Product{
+		"1": {UPC: "1", Title: "Table"},
+		"2": {UPC: "2", Title: "Chair"},
+	}
+
+	product := NewType(Product{}, nil)
+	product.Keys = []KeyDef{
+		NewKey("upc", func(ctx context.Context, key struct {
+			UPC string `json:"upc"`
+		}) (*Product, error) {
+			if p, ok := products[key.UPC]; ok {
+				return &p, nil
+			}
+			return nil, nil
+		}),
+	}
+
+	return Controller{
+		Types: []TypeDef{product},
+		Queries: []FuncDef{
+			NewFunc("products", func(ctx context.Context) ([]Product, error) {
+				return []Product{products["1"], products["2"]}, nil
+			}),
+		},
+	}
+}
+
+func TestController_Federation(t *testing.T) {
+	c := newFederatedController()
+	h := c.HandleHTTP()
+
+	tests := []struct {
+		name  string
+		query string
+		vars  string
+		want  string
+	}{
+		{
+			name:  "Service",
+			query: `{_service{sdl}}`,
+			want: `{"data":{"_service":{"sdl":` +
+				`"extend schema @link(url: \"https://specs.apollo.dev/federation/v2.0\", import: [\"@key\"])\n\n` +
+				`type Product @key(fields: \"upc\") {\n  title: String!\n  upc: String!\n}\n\n` +
+				`type Query {\n  products: [Product!]!\n}\n"}}}`,
+		},
+		{
+			name:  "Entities",
+			query: `query($r:[_Any!]!){_entities(representations:$r){...on Product{upc,title}}}`,
+			vars:  `{"r":[{"__typename":"Product","upc":"2"},{"__typename":"Product","upc":"3"}]}`,
+			want:  `{"data":{"_entities":[{"upc":"2","title":"Chair"},null]}}`,
+		},
+		{
+			name:  "EntitiesLiteral",
+			query: `{_entities(representations:[{__typename:"Product",upc:"1"}]){...on Product{title}}}`,
+			want:  `{"data":{"_entities":[{"title":"Table"}]}}`,
+		},
+		{
+			name: "EntitiesErrors",
+			query: `{_entities(representations:[
+				{__typename:"Product",upc:"1"},{__typename:"Review",id:"1"},{__typename:"Product"}
+			]){...on Product{title}}}`,
+			want: `{"data":{"_entities":[{"title":"Table"},null,null]},"errors":[
+				{"message":"unknown entity type \"Review\"","locations":[{"line":1,"column":2}],"path":["_entities",1]},
+				{"message":"representation of Product does not match any key","locations":[{"line":1,"column":2}],"path":["_entities",2]}
+			]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{"query": {tt.query}}
+			if tt.vars != "" {
+				q.Set("variables", tt.vars)
+			}
+
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil))
+			require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+			assert.JSONEq(t, tt.want, rw.Body.String())
+		})
+	}
+}
+
+func TestController_FederationWithoutEntities(t *testing.T) {
+	c := Controller{
+		Federation: true,
+		Queries: []FuncDef{NewFunc("version", func(ctx context.Context) (string, error) {
+			return "1", nil
+		})},
+	}
+
+	schema, err := c.CreateSchema()
+	require.NoError(t, err)
+	assert.Contains(t, schema.QueryType().Fields(), "_service")
+	assert.NotContains(t, schema.QueryType().Fields(), "_entities")
+}
+
+func TestDefineKey_Error(t *testing.T) {
+	_, err := DefineKey("upc", func(ctx context.Context) (*Product, error) {
+		return nil, nil
+	})
+	assert.Error(t, err)
+
+	c := Controller{Types: []TypeDef{NewType(Product{}, nil)}}
+	c.Types[0].Keys = []KeyDef{NewKey("upc", func(ctx context.Context, key struct{ UPC string }) (*string, error) {
+		return nil, nil
+	})}
+	_, err = c.CreateSchema()
+	assert.EqualError(t, err, `activegraph: key "upc" of Product must return activegraph.Product`)
+}
+
+func TestPrintSchema(t *testing.T) {
+	c := newFederatedController()
+	c.Types[0].Keys = nil
+
+	schema, err := c.CreateSchema()
+	require.NoError(t, err)
+
+	sdl := PrintSchema(schema)
+	assert.Contains(t, sdl, "directive @defer(if: Boolean = true, label: String) on FRAGMENT_SPREAD | INLINE_FRAGMENT\n")
+	assert.Contains(t, sdl, "type Product {\n  title: String!\n  upc: String!\n}\n\ntype Query {\n  products: [Product!]!\n}\n")
+}
@@ -0,0 +1,1012 @@
+package activegraph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	qlast "github.com/graphql-go/graphql/language/ast"
+	"github.com/pkg/errors"
+)
+
+var (
+	// deferDirective defers delivery of the fragment, the fragment is sent
+	// as a subsequent payload after the initial result.
+	deferDirective = graphql.NewDirective(graphql.DirectiveConfig{
+		Name: "defer",
+		Description: "Directs the executor to deliver this fragment in a subsequent " +
+			"payload, when the `if` argument is true.",
+		Locations: []string{
+			graphql.DirectiveLocationFragmentSpread,
+			graphql.DirectiveLocationInlineFragment,
+		},
+		Args: graphql.FieldConfigArgument{
+			"if": &graphql.ArgumentConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: true,
+				Description:  "Deferred when true.",
+			},
+			"label": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Identifies the payload of the fragment.",
+			},
+		},
+	})
+
+	// streamDirective streams items of the list field, only initialCount
+	// items are sent within the initial result.
+	streamDirective = graphql.NewDirective(graphql.DirectiveConfig{
+		Name: "stream",
+		Description: "Directs the executor to deliver items of this list field in " +
+			"subsequent payloads, when the `if` argument is true.",
+		Locations: []string{
+			graphql.DirectiveLocationField,
+		},
+		Args: graphql.FieldConfigArgument{
+			"if": &graphql.ArgumentConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: true,
+				Description:  "Streamed when true.",
+			},
+			"label": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Identifies the payloads of the field.",
+			},
+			"initialCount": &graphql.ArgumentConfig{
+				Type:         graphql.Int,
+				DefaultValue: 0,
+				Description:  "Number of items delivered within the initial result.",
+			},
+		},
+	})
+)
+
+// IncrementalPayload is a deferred fragment or streamed items of the list
+// delivered after the initial result.
+type IncrementalPayload struct {
+	// Data is a result of the deferred fragment.
+	Data map[string]interface{} `json:"data,omitempty"`
+
+	// Items are the streamed items of the list.
+	Items []interface{} `json:"items,omitempty"`
+
+	// Path is a path to the object of the deferred fragment, or a path to
+	// the first streamed item.
+	Path   []interface{}              `json:"path"`
+	Label  string                     `json:"label,omitempty"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+// IncrementalResult is a subsequent result of the incremental delivery.
+type IncrementalResult struct {
+	Incremental []IncrementalPayload `json:"incremental,omitempty"`
+	HasNext     bool                 `json:"hasNext"`
+}
+
+type incrementalKind int
+
+const (
+	deferKind incrementalKind = iota
+	streamKind
+)
+
+// incrementalPart is a deferred fragment or a streamed field of the operation.
+type incrementalPart struct {
+	kind         incrementalKind
+	label        string
+	initialCount int
+
+	// path is a list of response keys to the object of the deferred fragment,
+	// or to the streamed field.
+	path []string
+
+	// parent is the closest part containing this part, payloads of the part
+	// are delivered only after the payloads of the parent.
+	parent *incrementalPart
+
+	// selections are the nodes from the operation root to the part node.
+	selections []qlast.Selection
+}
+
+// included returns true when the part is executed within the execution of
+// the given part (nil for the initial result), this is possible only for the
+// streams with non-zero initial count.
+func (p *incrementalPart) included(x *incrementalPart) bool {
+	for parent := p.parent; parent != x; parent = parent.parent {
+		if parent == nil || parent.kind != streamKind || parent.initialCount == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// incrementalOperation is an operation with deferred fragments and streamed
+// fields. Each part is executed as a separate operation that selects only
+// the path to the part, fields on the path resume from the values resolved
+// by the executions of the parents, so resolvers are not executed again.
+type incrementalOperation struct {
+	opdef *qlast.OperationDefinition
+	vars  map[string]interface{}
+	parts map[qlast.Selection]*incrementalPart
+
+	// order lists parts in the order of appearance within the document.
+	order []*incrementalPart
+}
+
+// newIncrementalOperation returns an incremental operation of the request,
+// or nil when the request is not a query or it does not use @defer and
+// @stream directives.
+//
+// Parts of the operation are resolved by executing the path to the part,
+// mutations are therefore always executed as a whole.
+func newIncrementalOperation(r *Request) *incrementalOperation {
+	opdef := r.OperationDefinition()
+	if opdef == nil || opdef.Operation != OperationQuery {
+		return nil
+	}
+
+	fragments := make(map[string]*qlast.FragmentDefinition)
+	for _, def := range r.document.Definitions {
+		if fragdef, ok := def.(*qlast.FragmentDefinition); ok && fragdef.Name != nil {
+			fragments[fragdef.Name.Value] = fragdef
+		}
+	}
+
+	op := incrementalOperation{
+		vars:  r.Variables,
+		parts: make(map[qlast.Selection]*incrementalPart),
+	}
+
+	// Fragment spreads are replaced with inline fragments, so each node
+	// of the operation has a single path.
+	inlined := *opdef
+	inlined.SelectionSet = inlineFragments(opdef.SelectionSet, fragments)
+	op.opdef = &inlined
+
+	op.collect(inlined.SelectionSet, nil, nil, nil)
+	if len(op.order) == 0 {
+		return nil
+	}
+	return &op
+}
+
+func inlineFragments(set *qlast.SelectionSet, fragments map[string]*qlast.FragmentDefinition) *qlast.SelectionSet {
+	if set == nil {
+		return nil
+	}
+
+	inlined := *set
+	inlined.Selections = make([]qlast.Selection, 0, len(set.Selections))
+
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *qlast.Field:
+			field := *sel
+			field.SelectionSet = inlineFragments(sel.SelectionSet, fragments)
+			inlined.Selections = append(inlined.Selections, &field)
+		case *qlast.InlineFragment:
+			frag := *sel
+			frag.SelectionSet = inlineFragments(sel.SelectionSet, fragments)
+			inlined.Selections = append(inlined.Selections, &frag)
+		case *qlast.FragmentSpread:
+			fragdef, ok := fragments[sel.Name.Value]
+			if !ok {
+				continue
+			}
+			inlined.Selections = append(inlined.Selections, qlast.NewInlineFragment(&qlast.InlineFragment{
+				Loc:           sel.Loc,
+				TypeCondition: fragdef.TypeCondition,
+				Directives:    sel.Directives,
+				SelectionSet:  inlineFragments(fragdef.SelectionSet, fragments),
+			}))
+		}
+	}
+	return &inlined
+}
+
+func (op *incrementalOperation) collect(
+	set *qlast.SelectionSet, path []string, selections []qlast.Selection, parent *incrementalPart,
+) {
+	if set == nil {
+		return
+	}
+	for _, sel := range set.Selections {
+		var (
+			sels = append(selections[:len(selections):len(selections)], sel)
+			part = parent
+		)
+
+		switch sel := sel.(type) {
+		case *qlast.Field:
+			key := sel.Name.Value
+			if sel.Alias != nil {
+				key = sel.Alias.Value
+			}
+			fieldPath := append(path[:len(path):len(path)], key)
+
+			if args, ok := op.directive(sel.Directives, streamDirective.Name); ok {
+				part = &incrementalPart{
+					kind:         streamKind,
+					label:        args.label,
+					initialCount: args.initialCount,
+					path:         fieldPath,
+					parent:       parent,
+					selections:   sels,
+				}
+				op.parts[sel] = part
+				op.order = append(op.order, part)
+			}
+			op.collect(sel.SelectionSet, fieldPath, sels, part)
+		case *qlast.InlineFragment:
+			if args, ok := op.directive(sel.Directives, deferDirective.Name); ok {
+				part = &incrementalPart{
+					kind:       deferKind,
+					label:      args.label,
+					path:       path,
+					parent:     parent,
+					selections: sels,
+				}
+				op.parts[sel] = part
+				op.order = append(op.order, part)
+			}
+			op.collect(sel.SelectionSet, path, sels, part)
+		}
+	}
+}
+
+type incrementalArgs struct {
+	label        string
+	initialCount int
+}
+
+// directive returns arguments of the incremental directive, and false when
+// the directive is not specified or disabled with "if" argument.
+func (op *incrementalOperation) directive(directives []*qlast.Directive, name string) (
+	args incrementalArgs, ok bool,
+) {
+	for _, dir := range directives {
+		if dir.Name == nil || dir.Name.Value != name {
+			continue
+		}
+
+		ok = true
+		for _, arg := range dir.Arguments {
+			switch v := op.value(arg.Value).(type) {
+			case bool:
+				if arg.Name.Value == "if" {
+					ok = v
+				}
+			case string:
+				if arg.Name.Value == "label" {
+					args.label = v
+				}
+			case int:
+				if arg.Name.Value == "initialCount" && v > 0 {
+					args.initialCount = v
+				}
+			}
+		}
+		return args, ok
+	}
+	return args, false
+}
+
+// value returns a value of the directive argument, numbers are converted
+// to integers.
+func (op *incrementalOperation) value(val qlast.Value) interface{} {
+	var v interface{}
+	switch val := val.(type) {
+	case *qlast.Variable:
+		v = op.vars[val.Name.Value]
+	case *qlast.IntValue:
+		v, _ = strconv.Atoi(val.Value)
+	default:
+		v = val.GetValue()
+	}
+
+	if f, ok := v.(float64); ok {
+		return int(f)
+	}
+	return v
+}
+
+// document returns a document executing the part (nil for the initial
+// result). Ancestors of the part are narrowed to the path of the part.
+func (op *incrementalOperation) document(x *incrementalPart) *qlast.Document {
+	opdef := *op.opdef
+	if x == nil {
+		opdef.SelectionSet = op.prune(op.opdef.SelectionSet)
+	} else {
+		opdef.SelectionSet = op.narrow(op.opdef.SelectionSet, x, 0)
+	}
+	return qlast.NewDocument(&qlast.Document{Definitions: []qlast.Node{&opdef}})
+}
+
+func (op *incrementalOperation) narrow(set *qlast.SelectionSet, x *incrementalPart, depth int) *qlast.SelectionSet {
+	var (
+		narrowed = *set
+		sel      = x.selections[depth]
+	)
+	if depth == len(x.selections)-1 {
+		narrowed.Selections = []qlast.Selection{withSelectionSet(sel, op.prune(sel.GetSelectionSet()))}
+	} else {
+		narrowed.Selections = []qlast.Selection{withSelectionSet(sel, op.narrow(sel.GetSelectionSet(), x, depth+1))}
+	}
+	return &narrowed
+}
+
+// prune removes the nested parts from the selection set, except streams
+// with non-zero initial count, since the initial items are delivered
+// within the execution of the parent.
+func (op *incrementalOperation) prune(set *qlast.SelectionSet) *qlast.SelectionSet {
+	if set == nil {
+		return nil
+	}
+
+	pruned := *set
+	pruned.Selections = make([]qlast.Selection, 0, len(set.Selections))
+
+	for _, sel := range set.Selections {
+		part, ok := op.parts[sel]
+		if ok && (part.kind == deferKind || part.initialCount == 0) {
+			continue
+		}
+		pruned.Selections = append(pruned.Selections, withSelectionSet(sel, op.prune(sel.GetSelectionSet())))
+	}
+	return &pruned
+}
+
+func withSelectionSet(sel qlast.Selection, set *qlast.SelectionSet) qlast.Selection {
+	switch sel := sel.(type) {
+	case *qlast.Field:
+		field := *sel
+		field.SelectionSet = set
+		return &field
+	case *qlast.InlineFragment:
+		frag := *sel
+		frag.SelectionSet = set
+		return &frag
+	}
+	return sel
+}
+
+// execute executes the part (nil for the initial result), nested streams
+// with zero initial count are delivered as empty lists.
+func (op *incrementalOperation) execute(ctx context.Context, r *Request, exec *incrementalExecution) *graphql.Result {
+	res := graphql.Execute(graphql.ExecuteParams{
+		Schema:        *r.schema,
+		AST:           op.document(exec.part),
+		OperationName: r.OperationName,
+		Args:          r.Variables,
+		Context:       context.WithValue(ctx, incrementalExecutionKey{}, exec),
+	})
+
+	for _, part := range op.order {
+		if part == exec.part || part.kind != streamKind || part.initialCount > 0 || !part.included(exec.part) {
+			continue
+		}
+
+		key := part.path[len(part.path)-1]
+		visitObjects(res.Data, part.path[:len(part.path)-1], nil, func(obj map[string]interface{}, _ []interface{}) {
+			obj[key] = []interface{}{}
+		})
+	}
+	return res
+}
+
+// ancestor returns true, when the field with the given response keys is on
+// the path to any of the parts.
+func (op *incrementalOperation) ancestor(keys []string) bool {
+	for _, part := range op.order {
+		if len(keys) <= len(part.path) && equalKeys(keys, part.path[:len(keys)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// stream returns the streamed part of the field, or nil when the field is
+// not streamed.
+func (op *incrementalOperation) stream(keys []string, fields []*qlast.Field) *incrementalPart {
+	for _, part := range op.order {
+		if part.kind != streamKind || !equalKeys(keys, part.path) {
+			continue
+		}
+		for _, field := range fields {
+			if _, ok := op.directive(field.Directives, streamDirective.Name); ok {
+				return part
+			}
+		}
+	}
+	return nil
+}
+
+func equalKeys(keys1, keys2 []string) bool {
+	if len(keys1) != len(keys2) {
+		return false
+	}
+	for i := range keys1 {
+		if keys1[i] != keys2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type resolvedValue struct {
+	value interface{}
+	err   error
+}
+
+// incrementalState keeps values of fields on the paths to the parts of the
+// operation, values are shared by all executions of the operation.
+type incrementalState struct {
+	op     *incrementalOperation
+	values map[string]resolvedValue
+	mu     sync.Mutex
+}
+
+func newIncrementalState(op *incrementalOperation) *incrementalState {
+	return &incrementalState{op: op, values: make(map[string]resolvedValue)}
+}
+
+func (s *incrementalState) load(path []interface{}) (resolvedValue, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[pathKey(path)]
+	return v, ok
+}
+
+func (s *incrementalState) store(path []interface{}, value interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[pathKey(path)] = resolvedValue{value, err}
+}
+
+func pathKey(path []interface{}) string {
+	var b strings.Builder
+	for _, key := range path {
+		b.WriteByte('/')
+		switch key := key.(type) {
+		case string:
+			b.WriteString(key)
+		case int:
+			b.WriteString(strconv.Itoa(key))
+		}
+	}
+	return b.String()
+}
+
+// incrementalExecution is a single execution of the incremental operation:
+// the initial result (nil part), the deferred fragment, or the streamed field.
+//
+// Streamed lists are executed without items first, then each item is executed
+// separately, so items are delivered as soon as they are resolved.
+type incrementalExecution struct {
+	state *incrementalState
+	part  *incrementalPart
+
+	// target is a path to the streamed list, when the execution resolves
+	// the item of the list with the given index.
+	target []interface{}
+	index  int
+}
+
+type incrementalExecutionKey struct{}
+
+// path returns the path of the field within the operation, the streamed
+// item is resolved as the only item of the list, so its index is replaced.
+func (e *incrementalExecution) path(path []interface{}) []interface{} {
+	if e.target == nil || len(path) <= len(e.target) || pathKey(path[:len(e.target)]) != pathKey(e.target) {
+		return path
+	}
+	path = append([]interface{}(nil), path...)
+	path[len(e.target)] = e.index
+	return path
+}
+
+// resolve resolves the field, values of the fields on paths to the parts
+// are resolved only once and reused by the subsequent executions.
+func (e *incrementalExecution) resolve(p graphql.ResolveParams, fn graphql.FieldResolveFn) (interface{}, error) {
+	var (
+		path = e.path(p.Info.Path.AsArray())
+		keys = make([]string, 0, len(path))
+	)
+	for _, key := range path {
+		if key, ok := key.(string); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	stream := e.state.op.stream(keys, p.Info.FieldASTs)
+	if v, ok := e.state.load(path); ok {
+		return e.complete(stream, path, v.value, true), v.err
+	}
+	if !e.state.op.ancestor(keys) {
+		return fn(p)
+	}
+
+	value, err := fn(p)
+	if thunk, ok := value.(func() (interface{}, error)); ok {
+		return func() (interface{}, error) {
+			value, err := thunk()
+			e.state.store(path, value, err)
+			return e.complete(stream, path, value, false), err
+		}, nil
+	}
+
+	e.state.store(path, value, err)
+	return e.complete(stream, path, value, false), err
+}
+
+// complete returns the items of the streamed list resolved within the
+// execution: the initial items, when the list is resolved for the first
+// time, and a single item, when the execution resolves the item.
+func (e *incrementalExecution) complete(
+	stream *incrementalPart, path []interface{}, value interface{}, resolved bool,
+) interface{} {
+	switch {
+	case stream == nil:
+		return value
+	case stream == e.part && e.target != nil && pathKey(path) == pathKey(e.target):
+		return sliceItems(value, e.index, e.index+1)
+	case stream == e.part:
+		return sliceItems(value, 0, 0)
+	case resolved:
+		return value
+	default:
+		return sliceItems(value, 0, stream.initialCount)
+	}
+}
+
+// sliceItems returns items of the list from i to j, the value is returned
+// unchanged, when it is not a list.
+func sliceItems(value interface{}, i, j int) interface{} {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+		return value
+	}
+	if j > v.Len() {
+		j = v.Len()
+	}
+	if i > j {
+		i = j
+	}
+
+	items := make([]interface{}, 0, j-i)
+	for ; i < j; i++ {
+		items = append(items, v.Index(i).Interface())
+	}
+	return items
+}
+
+// listLen returns the length of the list, or zero, when value is not a list.
+func listLen(value interface{}) int {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+		return 0
+	}
+	return v.Len()
+}
+
+// newIncrementalFunc wraps the field resolve function to resume parts of the
+// incremental operation from the values resolved by the previous executions.
+func newIncrementalFunc(fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		exec, ok := p.Context.Value(incrementalExecutionKey{}).(*incrementalExecution)
+		if !ok {
+			return fn(p)
+		}
+		return exec.resolve(p, fn)
+	}
+}
+
+// withIncrementalFuncs wraps resolve functions of all fields of the schema
+// to support incremental delivery.
+func withIncrementalFuncs(schema graphql.Schema) {
+	for name, t := range schema.TypeMap() {
+		obj, ok := t.(*graphql.Object)
+		if !ok || strings.HasPrefix(name, "__") {
+			continue
+		}
+		for _, field := range obj.Fields() {
+			resolve := field.Resolve
+			if resolve == nil {
+				resolve = graphql.DefaultResolveFn
+			}
+			field.Resolve = newIncrementalFunc(resolve)
+		}
+	}
+}
+
+// payloads returns payloads of the deferred fragment from the result of the
+// part execution.
+func (op *incrementalOperation) payloads(x *incrementalPart, res *graphql.Result) (payloads []IncrementalPayload) {
+	visitObjects(res.Data, x.path, []interface{}{}, func(obj map[string]interface{}, path []interface{}) {
+		if len(obj) == 0 {
+			return
+		}
+		payloads = append(payloads, IncrementalPayload{
+			Data:   obj,
+			Path:   path,
+			Label:  x.label,
+			Errors: filterErrors(res.Errors, path, 0),
+		})
+	})
+	return payloads
+}
+
+// item returns the payload of the streamed item from the result of the item
+// execution, the item is resolved as the first item of the list.
+func (op *incrementalOperation) item(x *incrementalPart, exec *incrementalExecution, res *graphql.Result) IncrementalPayload {
+	path := append(exec.target[:len(exec.target):len(exec.target)], exec.index)
+	payload := IncrementalPayload{Path: path, Label: x.label}
+
+	var list interface{} = res.Data
+	for _, key := range exec.target {
+		switch key := key.(type) {
+		case string:
+			obj, _ := list.(map[string]interface{})
+			list = obj[key]
+		case int:
+			items, _ := list.([]interface{})
+			list = nil
+			if key < len(items) {
+				list = items[key]
+			}
+		}
+	}
+	if items, ok := list.([]interface{}); ok && len(items) > 0 {
+		payload.Items = items[:1]
+	}
+
+	errs := make([]gqlerrors.FormattedError, 0, len(res.Errors))
+	for _, err := range res.Errors {
+		if len(err.Path) > len(exec.target) {
+			err.Path = append([]interface{}(nil), err.Path...)
+			err.Path[len(exec.target)] = exec.index
+		}
+		errs = append(errs, err)
+	}
+	payload.Errors = filterErrors(errs, path, 0)
+	return payload
+}
+
+// run executes the part and sends its payloads, items of the streamed list
+// are executed and sent one by one. The last payloads of the part are sent
+// with done flag.
+func (op *incrementalOperation) run(
+	ctx context.Context, r *Request, state *incrementalState, x *incrementalPart,
+	send func(payloads []IncrementalPayload, done bool) bool,
+) {
+	res := op.execute(ctx, r, &incrementalExecution{state: state, part: x})
+	if x.kind == deferKind {
+		send(op.payloads(x, res), true)
+		return
+	}
+
+	// The streamed list is resolved without items, so all items are known
+	// before the execution of items.
+	var (
+		items []*incrementalExecution
+		key   = x.path[len(x.path)-1]
+	)
+	visitObjects(res.Data, x.path[:len(x.path)-1], []interface{}{}, func(obj map[string]interface{}, path []interface{}) {
+		if _, ok := obj[key].([]interface{}); !ok {
+			return
+		}
+		target := append(path[:len(path):len(path)], key)
+		v, _ := state.load(target)
+		for i := x.initialCount; i < listLen(v.value); i++ {
+			items = append(items, &incrementalExecution{state: state, part: x, target: target, index: i})
+		}
+	})
+
+	if len(items) == 0 {
+		send(nil, true)
+		return
+	}
+	for i, exec := range items {
+		payload := op.item(x, exec, op.execute(ctx, r, exec))
+		if !send([]IncrementalPayload{payload}, i == len(items)-1) {
+			return
+		}
+	}
+}
+
+// visitObjects calls the function for each object reachable by the keys,
+// lists are traversed item by item.
+func visitObjects(
+	data interface{}, keys []string, path []interface{}, fn func(map[string]interface{}, []interface{}),
+) {
+	switch data := data.(type) {
+	case map[string]interface{}:
+		if len(keys) == 0 {
+			fn(data, path)
+			return
+		}
+		visitObjects(data[keys[0]], keys[1:], append(path[:len(path):len(path)], keys[0]), fn)
+	case []interface{}:
+		for i, item := range data {
+			visitObjects(item, keys, append(path[:len(path):len(path)], i), fn)
+		}
+	}
+}
+
+// filterErrors returns errors located under the given path, when the start
+// is non-zero, only errors of list items starting from the start index are
+// returned.
+func filterErrors(errs []gqlerrors.FormattedError, prefix []interface{}, start int) []gqlerrors.FormattedError {
+	var filtered []gqlerrors.FormattedError
+	for _, err := range errs {
+		if len(err.Path) < len(prefix) {
+			continue
+		}
+
+		matches := true
+		for i := range prefix {
+			if err.Path[i] != prefix[i] {
+				matches = false
+				break
+			}
+		}
+		if start > 0 {
+			i, ok := pathIndex(err.Path, len(prefix))
+			matches = matches && ok && i >= start
+		}
+		if matches {
+			filtered = append(filtered, err)
+		}
+	}
+	return filtered
+}
+
+func pathIndex(path []interface{}, i int) (int, bool) {
+	if i >= len(path) {
+		return 0, false
+	}
+	switch v := path[i].(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+type incrementalResult struct {
+	part     *incrementalPart
+	payloads []IncrementalPayload
+
+	// done is true for the last result of the part.
+	done bool
+}
+
+// executeIncremental writes the initial result of the operation, and then
+// the payloads of the deferred fragments and streamed lists as soon as they
+// are resolved. Parts are executed after the execution of their parents, so
+// payloads of the nested parts are written after payloads of their parents.
+//
+// When the worker pool is configured, the number of concurrently executed
+// parts is limited by the size of the pool. Parts are not executed within
+// the pool, since the concurrent resolvers of parts acquire workers of the
+// pool themselves.
+func executeIncremental(rw StreamResponseWriter, r *Request, op *incrementalOperation) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	state := newIncrementalState(op)
+	if err := rw.Write(op.execute(ctx, r, &incrementalExecution{state: state})); err != nil {
+		return
+	}
+
+	var sem chan struct{}
+	if pool, ok := ctx.Value(workerPoolKey{}).(*workerPool); ok {
+		sem = make(chan struct{}, cap(pool.sem))
+	}
+
+	results := make(chan incrementalResult)
+	send := func(res incrementalResult) bool {
+		select {
+		case results <- res:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	start := func(parent *incrementalPart) {
+		for _, part := range op.order {
+			if part.parent != parent {
+				continue
+			}
+			go func(part *incrementalPart) {
+				if sem != nil {
+					select {
+					case sem <- struct{}{}:
+						defer func() { <-sem }()
+					case <-ctx.Done():
+						return
+					}
+				}
+				op.run(ctx, r, state, part, func(payloads []IncrementalPayload, done bool) bool {
+					return send(incrementalResult{part: part, payloads: payloads, done: done})
+				})
+			}(part)
+		}
+	}
+	start(nil)
+
+	for remaining := len(op.order); remaining > 0; {
+		var res incrementalResult
+		select {
+		case res = <-results:
+		case <-ctx.Done():
+			return
+		}
+
+		if res.done {
+			remaining--
+			start(res.part)
+		}
+		if len(res.payloads) == 0 && remaining > 0 {
+			continue
+		}
+
+		err := rw.WriteIncremental(&IncrementalResult{
+			Incremental: res.payloads,
+			HasNext:     remaining > 0,
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+// acceptsMultipart returns true when the client accepts incremental delivery
+// of the result through "multipart/mixed" response.
+func acceptsMultipart(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		if strings.TrimSpace(params[0]) != mediaTypeMultipart {
+			continue
+		}
+		for _, param := range params[1:] {
+			if q := strings.TrimSpace(param); q == "q=0" || q == "q=0.0" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+const (
+	// multipartBoundary delimits parts of the incremental response.
+	multipartBoundary = "-"
+	multipartHeader   = "\r\n--" + multipartBoundary + "\r\nContent-Type: application/json; charset=utf-8\r\n\r\n"
+	multipartEnd      = "\r\n--" + multipartBoundary + "--\r\n"
+)
+
+// initialResult is the initial result of the incremental delivery.
+type initialResult struct {
+	*graphql.Result
+	HasNext bool `json:"hasNext"`
+}
+
+// multipartWriter writes each result as a part of the "multipart/mixed"
+// response, parts are flushed to the client immediately.
+type multipartWriter struct {
+	writtenNotifier
+
+	rw         http.ResponseWriter
+	maskErrors bool
+
+	status  int
+	size    int
+	written bool
+	hasNext bool
+}
+
+func (mw *multipartWriter) Header() http.Header {
+	return mw.rw.Header()
+}
+
+func (mw *multipartWriter) IsWritten() bool {
+	return mw.written
+}
+
+// WriteStatus sets the status code of the response, the status has no
+// effect after writing the initial result.
+func (mw *multipartWriter) WriteStatus(statusCode int) {
+	mw.status = statusCode
+}
+
+// Write writes the initial result, the result is always followed by the
+// subsequent payloads or by the final payload written on close.
+func (mw *multipartWriter) Write(res *graphql.Result) error {
+	if mw.written {
+		return errors.New("result is already written")
+	}
+	if mw.maskErrors {
+		maskErrors(res.Errors)
+	}
+	return mw.writePart(initialResult{Result: res, HasNext: true})
+}
+
+func (mw *multipartWriter) WriteIncremental(res *IncrementalResult) error {
+	if !mw.written {
+		return errors.New("initial result is not written")
+	}
+	if mw.maskErrors {
+		for i := range res.Incremental {
+			maskErrors(res.Incremental[i].Errors)
+		}
+	}
+	return mw.writePart(res)
+}
+
+func (mw *multipartWriter) writePart(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if !mw.written {
+		mw.rw.Header().Set("Content-Type",
+			mediaTypeMultipart+`; boundary="`+multipartBoundary+`"; deferSpec=20220824`)
+		status := http.StatusOK
+		if mw.status != 0 {
+			status = mw.status
+		}
+		mw.rw.WriteHeader(status)
+		mw.written = true
+	}
+
+	if err = mw.write([]byte(multipartHeader)); err != nil {
+		return err
+	}
+	if err = mw.write(b); err != nil {
+		return err
+	}
+	if f, ok := mw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	switch res := v.(type) {
+	case initialResult:
+		mw.hasNext = res.HasNext
+	case *IncrementalResult:
+		mw.hasNext = res.HasNext
+	}
+	return nil
+}
+
+// Close completes the response, the final payload is written when the
+// handler did not complete the response.
+func (mw *multipartWriter) Close() error {
+	if !mw.written {
+		if err := mw.writePart(initialResult{Result: new(graphql.Result)}); err != nil {
+			return err
+		}
+	}
+	if mw.hasNext {
+		if err := mw.writePart(&IncrementalResult{HasNext: false}); err != nil {
+			return err
+		}
+	}
+	return mw.write([]byte(multipartEnd))
+}
+
+// write writes the bytes to the response and counts the size of the body.
+func (mw *multipartWriter) write(b []byte) error {
+	n, err := mw.rw.Write(b)
+	mw.size += n
+	return err
+}
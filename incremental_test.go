@@ -0,0 +1,289 @@
+package activegraph
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readParts reads JSON payloads of the multipart response.
+func readParts(t *testing.T, contentType string, body io.Reader) (parts []string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(part)
+		require.NoError(t, err)
+		parts = append(parts, string(b))
+	}
+}
+
+func TestController_Incremental(t *testing.T) {
+	type Post struct {
+		Title  string `json:"title"`
+		Author string `json:"author"`
+	}
+
+	summary := NewFunc("summary", func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	posts := NewFunc("posts", func(ctx context.Context) ([]Post, error) {
+		return []Post{{"A", "X"}, {"B", "Y"}, {"C", "Z"}}, nil
+	})
+
+	s := Controller{Types: []TypeDef{NewType(Post{}, nil)}, Queries: []FuncDef{summary, posts}}
+	h := s.HandleHTTP()
+
+	tests := []struct {
+		name        string
+		query       string
+		initial     string
+		incremental string
+	}{
+		{
+			name:        "Defer",
+			query:       `{summary ...@defer(label:"posts"){posts{title}}}`,
+			initial:     `{"summary":"ok"}`,
+			incremental: `[{"data":{"posts":[{"title":"A"},{"title":"B"},{"title":"C"}]},"path":[],"label":"posts"}]`,
+		},
+		{
+			name:    "DeferFragment",
+			query:   `{posts{title ...P @defer}} fragment P on Post{author}`,
+			initial: `{"posts":[{"title":"A"},{"title":"B"},{"title":"C"}]}`,
+			incremental: `[
+				{"data":{"author":"X"},"path":["posts",0]},
+				{"data":{"author":"Y"},"path":["posts",1]},
+				{"data":{"author":"Z"},"path":["posts",2]}
+			]`,
+		},
+		{
+			name:    "Stream",
+			query:   `{summary,posts @stream(initialCount:1){title}}`,
+			initial: `{"summary":"ok","posts":[{"title":"A"}]}`,
+			incremental: `[
+				{"items":[{"title":"B"}],"path":["posts",1]},
+				{"items":[{"title":"C"}],"path":["posts",2]}
+			]`,
+		},
+		{
+			name:    "StreamNested",
+			query:   `{posts @stream{title ...on Post @defer{author}}}`,
+			initial: `{"posts":[]}`,
+			incremental: `[
+				{"items":[{"title":"A"}],"path":["posts",0]},
+				{"items":[{"title":"B"}],"path":["posts",1]},
+				{"items":[{"title":"C"}],"path":["posts",2]},
+				{"data":{"author":"X"},"path":["posts",0]},
+				{"data":{"author":"Y"},"path":["posts",1]},
+				{"data":{"author":"Z"},"path":["posts",2]}
+			]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(tt.query), nil)
+			r.Header.Set("Accept", "multipart/mixed, application/json")
+
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, r)
+			require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+
+			parts := readParts(t, rw.Header().Get("Content-Type"), rw.Body)
+			require.True(t, len(parts) > 1, parts)
+
+			var initial struct {
+				Data    json.RawMessage `json:"data"`
+				HasNext bool            `json:"hasNext"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(parts[0]), &initial))
+			assert.JSONEq(t, tt.initial, string(initial.Data))
+			assert.True(t, initial.HasNext)
+
+			// Payloads of parts are delivered in separate results, as soon
+			// as they are resolved.
+			incremental := []json.RawMessage{}
+			for i, part := range parts[1:] {
+				var res struct {
+					Incremental []json.RawMessage `json:"incremental"`
+					HasNext     bool              `json:"hasNext"`
+				}
+				require.NoError(t, json.Unmarshal([]byte(part), &res))
+				assert.Equal(t, i < len(parts)-2, res.HasNext)
+				incremental = append(incremental, res.Incremental...)
+			}
+
+			b, err := json.Marshal(incremental)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.incremental, string(b))
+		})
+	}
+
+	// Queries are executed as a whole, when the client does not accept
+	// multipart responses, or all parts are disabled.
+	fallbacks := []struct {
+		name    string
+		accept  string
+		enabled bool
+	}{
+		{"NotAccepted", "application/json", true},
+		{"Disabled", "multipart/mixed", false},
+	}
+	for _, tt := range fallbacks {
+		t.Run(tt.name, func(t *testing.T) {
+			query := `query($defer:Boolean){summary ...@defer(if:$defer){posts{title}}}`
+			vars := `{"defer":` + strconv.FormatBool(tt.enabled) + `}`
+
+			r := httptest.NewRequest(http.MethodGet,
+				"/graphql?query="+url.QueryEscape(query)+"&variables="+url.QueryEscape(vars), nil)
+			r.Header.Set("Accept", tt.accept)
+
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, r)
+			require.Equal(t, http.StatusOK, rw.Code)
+			assert.JSONEq(t, `{"data":{"summary":"ok","posts":[{"title":"A"},{"title":"B"},{"title":"C"}]}}`,
+				rw.Body.String())
+		})
+	}
+}
+
+func TestController_IncrementalDelivery(t *testing.T) {
+	release := make(chan struct{})
+
+	summary := NewFunc("summary", func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	slow := NewFunc("slow", func(ctx context.Context) ([]string, error) {
+		<-release
+		return []string{"done"}, nil
+	})
+
+	s := Controller{Queries: []FuncDef{summary, slow}}
+	srv := httptest.NewServer(s.HandleHTTP())
+	defer srv.Close()
+
+	r, err := http.NewRequest(http.MethodGet, srv.URL+"?query="+url.QueryEscape(`{summary,slow @stream}`), nil)
+	require.NoError(t, err)
+	r.Header.Set("Accept", "multipart/mixed")
+
+	resp, err := http.DefaultClient.Do(r)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+
+	read := func() (payload map[string]interface{}) {
+		part, err := mr.NextPart()
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(part).Decode(&payload))
+		return payload
+	}
+
+	// The initial result is delivered before the slow list is resolved.
+	assert.Equal(t, map[string]interface{}{
+		"data":    map[string]interface{}{"summary": "ok", "slow": []interface{}{}},
+		"hasNext": true,
+	}, read())
+
+	close(release)
+	assert.Equal(t, map[string]interface{}{
+		"incremental": []interface{}{
+			map[string]interface{}{"items": []interface{}{"done"}, "path": []interface{}{"slow", 0.0}},
+		},
+		"hasNext": false,
+	}, read())
+}
+
+func TestController_IncrementalResume(t *testing.T) {
+	type Comment struct {
+		Text string `json:"text"`
+	}
+	type Post struct {
+		Title string `json:"title"`
+	}
+
+	var (
+		numPosts    int32
+		numComments int32
+	)
+	posts := NewFunc("posts", func(ctx context.Context) ([]Post, error) {
+		atomic.AddInt32(&numPosts, 1)
+		return []Post{{"A"}, {"B"}}, nil
+	})
+	posts.Concurrent = true
+
+	post := NewType(Post{}, Funcs{
+		"comments": func(ctx context.Context, post Post) ([]Comment, error) {
+			atomic.AddInt32(&numComments, 1)
+			return []Comment{{post.Title + "1"}, {post.Title + "2"}}, nil
+		},
+	})
+	comments := post.Funcs["comments"]
+	comments.Concurrent = true
+	post.Funcs["comments"] = comments
+
+	s := Controller{
+		Types:          []TypeDef{post, NewType(Comment{}, nil)},
+		Queries:        []FuncDef{posts},
+		MaxConcurrency: 1,
+	}
+	h := s.HandleHTTP()
+
+	query := `{posts{title ...on Post @defer{comments @stream(initialCount:1){text ...on Comment @defer{text}}}}}`
+	r := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(query), nil)
+	r.Header.Set("Accept", "multipart/mixed")
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+
+	parts := readParts(t, rw.Header().Get("Content-Type"), rw.Body)
+	assert.JSONEq(t, `{"data":{"posts":[{"title":"A"},{"title":"B"}]},"hasNext":true}`, parts[0])
+
+	incremental := []json.RawMessage{}
+	for _, part := range parts[1:] {
+		var res struct {
+			Incremental []json.RawMessage `json:"incremental"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(part), &res))
+		incremental = append(incremental, res.Incremental...)
+	}
+
+	b, err := json.Marshal(incremental)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"data":{"comments":[{"text":"A1"}]},"path":["posts",0]},
+		{"data":{"comments":[{"text":"B1"}]},"path":["posts",1]},
+		{"items":[{"text":"A2"}],"path":["posts",0,"comments",1]},
+		{"items":[{"text":"B2"}],"path":["posts",1,"comments",1]},
+		{"data":{"text":"A1"},"path":["posts",0,"comments",0]},
+		{"data":{"text":"A2"},"path":["posts",0,"comments",1]},
+		{"data":{"text":"B1"},"path":["posts",1,"comments",0]},
+		{"data":{"text":"B2"},"path":["posts",1,"comments",1]}
+	]`, string(b))
+
+	// Parts are resumed from the resolved values of their parents.
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numPosts))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&numComments))
+}
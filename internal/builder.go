@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
+)
+
+// SchemaBuilder assembles a single GraphQL schema from types and fields
+// contributed by independent sources, e.g. functions of the controller and
+// actions of the mapped resources. Contributors share registered types,
+// Relay nodes and federated entities.
+type SchemaBuilder struct {
+	// Inputs and Outputs are registered types by their names.
+	Inputs  map[string]graphql.Type
+	Outputs map[string]graphql.Type
+
+	Queries   graphql.Fields
+	Mutations graphql.Fields
+
+	// Nodes are types implementing the Relay "Node" interface.
+	Nodes *Nodes
+
+	// Entities are types with federation keys, the schema is exposed as
+	// a federated subgraph, when there are entities or Federation is set.
+	Entities   []Entity
+	Federation bool
+}
+
+// NewSchemaBuilder returns a new empty schema builder.
+func NewSchemaBuilder() *SchemaBuilder {
+	return &SchemaBuilder{
+		Inputs:    make(map[string]graphql.Type),
+		Outputs:   make(map[string]graphql.Type),
+		Queries:   make(graphql.Fields),
+		Mutations: make(graphql.Fields),
+		Nodes:     NewNodes(),
+	}
+}
+
+// AddOutput registers the output type with the given name.
+func (b *SchemaBuilder) AddOutput(name string, output graphql.Type) error {
+	if _, dup := b.Outputs[name]; dup {
+		return errors.New("activegraph: multiple type registrations for " + name)
+	}
+	b.Outputs[name] = output
+	return nil
+}
+
+// AddQuery adds the field to the query type.
+func (b *SchemaBuilder) AddQuery(name string, field *graphql.Field) error {
+	if _, dup := b.Queries[name]; dup {
+		return errors.New("activegraph: multiple registrations for " + name)
+	}
+	b.Queries[name] = field
+	return nil
+}
+
+// AddMutation adds the field to the mutation type.
+func (b *SchemaBuilder) AddMutation(name string, field *graphql.Field) error {
+	if _, dup := b.Mutations[name]; dup {
+		return errors.New("activegraph: multiple registrations for " + name)
+	}
+	b.Mutations[name] = field
+	return nil
+}
+
+// Build creates the schema from the configuration, query and mutation types
+// of the configuration are replaced with the registered fields.
+func (b *SchemaBuilder) Build(config graphql.SchemaConfig) (graphql.Schema, error) {
+	queries := make(graphql.Fields, len(b.Queries)+4)
+	for name, field := range b.Queries {
+		queries[name] = field
+	}
+	if b.Nodes.Len() > 0 {
+		b.Nodes.Identify(queries)
+	}
+	if b.Federation || len(b.Entities) > 0 {
+		Federate(queries, b.Entities)
+	}
+
+	config.Query = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query", Fields: queries,
+	})
+
+	config.Mutation = nil
+	if len(b.Mutations) > 0 {
+		config.Mutation = graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation", Fields: b.Mutations,
+		})
+	}
+	return graphql.NewSchema(config)
+}
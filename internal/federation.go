@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/pkg/errors"
+)
+
+// federationLink imports federation directives used in the subgraph schema.
+const federationLink = `extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key"])`
+
+// EntityKey is a key of the federated entity, the key is selected for the
+// entity resolution when the representation contains all fields of the key.
+type EntityKey struct {
+	// Fields is a field set of the key, e.g. "id" or "sku package".
+	Fields string
+
+	// Resolve returns the entity by the representation.
+	Resolve func(ctx context.Context, representation map[string]interface{}) (interface{}, error)
+}
+
+// Entity is an object type, that could be resolved by the federation
+// gateway through the "_entities" query.
+type Entity struct {
+	Object *graphql.Object
+	Keys   []EntityKey
+
+	// IsTypeOf returns true, when the resolved value is an entity of the object.
+	IsTypeOf func(value interface{}) bool
+}
+
+var anyScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "_Any",
+	Description: "Representation of the federated entity.",
+	Serialize:   func(value interface{}) interface{} { return value },
+	ParseValue:  func(value interface{}) interface{} { return value },
+	ParseLiteral: func(value ast.Value) interface{} {
+		return valueFromAST(value)
+	},
+})
+
+func valueFromAST(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.ObjectValue:
+		m := make(map[string]interface{}, len(value.Fields))
+		for _, field := range value.Fields {
+			m[field.Name.Value] = valueFromAST(field.Value)
+		}
+		return m
+	case *ast.ListValue:
+		list := make([]interface{}, 0, len(value.Values))
+		for _, item := range value.Values {
+			list = append(list, valueFromAST(item))
+		}
+		return list
+	case *ast.IntValue:
+		if i, err := strconv.Atoi(value.Value); err == nil {
+			return i
+		}
+	case *ast.FloatValue:
+		if f, err := strconv.ParseFloat(value.Value, 64); err == nil {
+			return f
+		}
+	case *ast.StringValue:
+		return value.Value
+	case *ast.EnumValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	}
+	return nil
+}
+
+// isFederationName returns true for the types and fields of the federation
+// specification, they are omitted from the subgraph schema.
+func isFederationName(name string) bool {
+	switch name {
+	case "_Any", "_Entity", "_Service", "_service", "_entities":
+		return true
+	}
+	return false
+}
+
+// Federate adds "_service" and "_entities" queries of the Apollo Federation
+// v2 subgraph specification to the query fields. The "_entities" query is
+// added only when there is at least one entity.
+func Federate(queries graphql.Fields, entities []Entity) {
+	var (
+		once sync.Once
+		sdl  string
+	)
+
+	keys := make(map[string][]string, len(entities))
+	for _, entity := range entities {
+		for _, key := range entity.Keys {
+			keys[entity.Object.Name()] = append(keys[entity.Object.Name()],
+				"@key(fields: "+strconv.Quote(key.Fields)+")")
+		}
+	}
+
+	service := graphql.NewObject(graphql.ObjectConfig{
+		Name: "_Service",
+		Fields: graphql.Fields{
+			"sdl": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					once.Do(func() {
+						sdl = PrintSDL(&p.Info.Schema, SDLOptions{
+							Header:         federationLink,
+							Exclude:        isFederationName,
+							TypeDirectives: func(name string) []string { return keys[name] },
+							OmitDirectives: true,
+						})
+					})
+					return sdl, nil
+				},
+			},
+		},
+	})
+
+	queries["_service"] = &graphql.Field{
+		Type: graphql.NewNonNull(service),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return struct{}{}, nil
+		},
+	}
+
+	if len(entities) == 0 {
+		return
+	}
+
+	types := make([]*graphql.Object, 0, len(entities))
+	for _, entity := range entities {
+		types = append(types, entity.Object)
+	}
+
+	entityType := graphql.NewUnion(graphql.UnionConfig{
+		Name:  "_Entity",
+		Types: types,
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			for _, entity := range entities {
+				if entity.IsTypeOf(p.Value) {
+					return entity.Object
+				}
+			}
+			return nil
+		},
+	})
+
+	queries["_entities"] = &graphql.Field{
+		Args: graphql.FieldConfigArgument{
+			"representations": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(anyScalar))),
+			},
+		},
+		Type: graphql.NewNonNull(graphql.NewList(entityType)),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			reprs, _ := p.Args["representations"].([]interface{})
+
+			// Entities are resolved independently, so the failed resolution
+			// results in the null entity and the error with the entity path.
+			results := make([]interface{}, len(reprs))
+			for i := range reprs {
+				repr := reprs[i]
+				results[i] = func() (interface{}, error) {
+					return resolveEntity(p.Context, entities, repr)
+				}
+			}
+			return results, nil
+		},
+	}
+}
+
+func resolveEntity(ctx context.Context, entities []Entity, repr interface{}) (interface{}, error) {
+	m, ok := repr.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("representation must be an object, is %T", repr)
+	}
+	typename, _ := m["__typename"].(string)
+	if typename == "" {
+		return nil, errors.New("representation must contain __typename")
+	}
+
+	for _, entity := range entities {
+		if entity.Object.Name() != typename {
+			continue
+		}
+		for _, key := range entity.Keys {
+			if hasKeyFields(m, key.Fields) {
+				return key.Resolve(ctx, m)
+			}
+		}
+		return nil, errors.Errorf("representation of %s does not match any key", typename)
+	}
+	return nil, errors.Errorf("unknown entity type %q", typename)
+}
+
+// hasKeyFields returns true, when the representation contains all top-level
+// fields of the key field set.
+func hasKeyFields(repr map[string]interface{}, fields string) bool {
+	fields = strings.NewReplacer("{", " { ", "}", " } ").Replace(fields)
+
+	var depth int
+	for _, name := range strings.Fields(fields) {
+		switch name {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		default:
+			if _, ok := repr[name]; depth == 0 && !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
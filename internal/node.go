@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
+)
+
+// ToGlobalID returns an opaque global identifier of the object, it encodes
+// the name of the type and the local identifier of the object.
+func ToGlobalID(typename string, id interface{}) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(typename + ":" + fmt.Sprint(id)))
+}
+
+// FromGlobalID decodes the type name and the local identifier from the
+// global identifier created with ToGlobalID.
+func FromGlobalID(globalID string) (typename, id string, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(globalID)
+	if err != nil {
+		return "", "", errors.Errorf("invalid node id %q", globalID)
+	}
+	typename, id, ok := strings.Cut(string(b), ":")
+	if !ok || typename == "" {
+		return "", "", errors.Errorf("invalid node id %q", globalID)
+	}
+	return typename, id, nil
+}
+
+// NodeType is an object type, that implements the Relay "Node" interface
+// and could be refetched through the "node" query.
+type NodeType struct {
+	// Name is the type name encoded into the global identifiers.
+	Name   string
+	Object *graphql.Object
+
+	// Fetch returns the object by the local identifier, when the object
+	// does not exist, it returns nil.
+	Fetch func(ctx context.Context, id string) (interface{}, error)
+
+	// IsTypeOf returns true, when the resolved value is an object of the type.
+	IsTypeOf func(value interface{}) bool
+}
+
+// Nodes is a registry of the types, that implement the Relay "Node"
+// interface. The interface must be created before the objects implementing
+// it, therefore node types are registered after the creation of objects.
+type Nodes struct {
+	iface *graphql.Interface
+	types []NodeType
+}
+
+// NewNodes creates a new empty registry of the node types.
+func NewNodes() *Nodes {
+	n := new(Nodes)
+	n.iface = graphql.NewInterface(graphql.InterfaceConfig{
+		Name:        "Node",
+		Description: "An object with a globally unique identifier.",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		},
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			for _, node := range n.types {
+				if node.IsTypeOf(p.Value) {
+					return node.Object
+				}
+			}
+			return nil
+		},
+	})
+	return n
+}
+
+// Interface returns the "Node" interface.
+func (n *Nodes) Interface() *graphql.Interface {
+	return n.iface
+}
+
+// Len returns the number of registered node types.
+func (n *Nodes) Len() int {
+	return len(n.types)
+}
+
+// Add registers the node type.
+func (n *Nodes) Add(node NodeType) {
+	n.types = append(n.types, node)
+}
+
+// IDField returns the "id" field of the node type, the field resolves
+// the global identifier from the local identifier of the object.
+func (n *Nodes) IDField(typename string, resolve graphql.FieldResolveFn) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(graphql.ID),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := resolve(p)
+			if err != nil || id == nil {
+				return nil, err
+			}
+			return ToGlobalID(typename, id), nil
+		},
+	}
+}
+
+// Identify adds "node" and "nodes" queries of the Relay global object
+// identification specification to the query fields.
+func (n *Nodes) Identify(queries graphql.Fields) {
+	queries["node"] = &graphql.Field{
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+		},
+		Type: n.iface,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, _ := p.Args["id"].(string)
+			return n.fetch(p.Context, id)
+		},
+	}
+
+	queries["nodes"] = &graphql.Field{
+		Args: graphql.FieldConfigArgument{
+			"ids": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+			},
+		},
+		Type: graphql.NewNonNull(graphql.NewList(n.iface)),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			ids, _ := p.Args["ids"].([]interface{})
+
+			// Nodes are fetched independently, so the failed fetch results
+			// in the null node and the error with the node path.
+			results := make([]interface{}, len(ids))
+			for i := range ids {
+				id, _ := ids[i].(string)
+				results[i] = func() (interface{}, error) {
+					return n.fetch(p.Context, id)
+				}
+			}
+			return results, nil
+		},
+	}
+}
+
+func (n *Nodes) fetch(ctx context.Context, globalID string) (interface{}, error) {
+	typename, id, err := FromGlobalID(globalID)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range n.types {
+		if node.Name == typename {
+			return node.Fetch(ctx, id)
+		}
+	}
+	return nil, errors.Errorf("unknown node type %q", typename)
+}
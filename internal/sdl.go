@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// SDLOptions configures printing of the schema definition.
+type SDLOptions struct {
+	// Header is printed before the type definitions.
+	Header string
+
+	// Exclude returns true for types and fields of the root types omitted
+	// from the printed schema.
+	Exclude func(name string) bool
+
+	// TypeDirectives returns directives applied to the type.
+	TypeDirectives func(name string) []string
+
+	// OmitDirectives omits definitions of the custom directives.
+	OmitDirectives bool
+}
+
+// PrintSDL returns the schema definition in GraphQL SDL, built-in scalars,
+// directives and introspection types are omitted. Types, fields and
+// arguments are printed in the alphabetical order.
+func PrintSDL(schema *graphql.Schema, opts SDLOptions) string {
+	if opts.Exclude == nil {
+		opts.Exclude = func(string) bool { return false }
+	}
+	if opts.TypeDirectives == nil {
+		opts.TypeDirectives = func(string) []string { return nil }
+	}
+
+	var defs []string
+	if opts.Header != "" {
+		defs = append(defs, opts.Header)
+	}
+
+	if !opts.OmitDirectives {
+		for _, directive := range schema.Directives() {
+			if isSpecifiedDirective(directive) {
+				continue
+			}
+			defs = append(defs, printDirective(directive))
+		}
+	}
+
+	typeMap := schema.TypeMap()
+	names := make([]string, 0, len(typeMap))
+	for name := range typeMap {
+		if strings.HasPrefix(name, "__") || isSpecifiedScalar(name) || opts.Exclude(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		def := printType(typeMap[name], opts)
+		if def != "" {
+			defs = append(defs, def)
+		}
+	}
+	return strings.Join(defs, "\n\n") + "\n"
+}
+
+func isSpecifiedDirective(directive *graphql.Directive) bool {
+	for _, specified := range graphql.SpecifiedDirectives {
+		if directive.Name == specified.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpecifiedScalar(name string) bool {
+	switch name {
+	case "String", "Int", "Float", "Boolean", "ID":
+		return true
+	}
+	return false
+}
+
+func printType(t graphql.Type, opts SDLOptions) string {
+	var b strings.Builder
+
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		printDescription(&b, "", t.Description())
+		fmt.Fprintf(&b, "scalar %s", t.Name())
+	case *graphql.Object:
+		fields := printFields(t.Fields(), opts)
+		if len(fields) == 0 {
+			return ""
+		}
+
+		printDescription(&b, "", t.Description())
+		fmt.Fprintf(&b, "type %s", t.Name())
+		if ifaces := t.Interfaces(); len(ifaces) > 0 {
+			names := make([]string, 0, len(ifaces))
+			for _, iface := range ifaces {
+				names = append(names, iface.Name())
+			}
+			b.WriteString(" implements " + strings.Join(names, " & "))
+		}
+		for _, directive := range opts.TypeDirectives(t.Name()) {
+			b.WriteString(" " + directive)
+		}
+		b.WriteString(" {\n" + strings.Join(fields, "\n") + "\n}")
+	case *graphql.Interface:
+		printDescription(&b, "", t.Description())
+		fmt.Fprintf(&b, "interface %s {\n%s\n}", t.Name(), strings.Join(printFields(t.Fields(), opts), "\n"))
+	case *graphql.Union:
+		names := make([]string, 0, len(t.Types()))
+		for _, obj := range t.Types() {
+			names = append(names, obj.Name())
+		}
+		printDescription(&b, "", t.Description())
+		fmt.Fprintf(&b, "union %s = %s", t.Name(), strings.Join(names, " | "))
+	case *graphql.Enum:
+		printDescription(&b, "", t.Description())
+		fmt.Fprintf(&b, "enum %s {\n", t.Name())
+		values := make([]*graphql.EnumValueDefinition, len(t.Values()))
+		copy(values, t.Values())
+		sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+
+		for _, value := range values {
+			printDescription(&b, "  ", value.Description)
+			b.WriteString("  " + value.Name + printDeprecated(value.DeprecationReason) + "\n")
+		}
+		b.WriteString("}")
+	case *graphql.InputObject:
+		fieldMap := t.Fields()
+		names := make([]string, 0, len(fieldMap))
+		for name := range fieldMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		printDescription(&b, "", t.Description())
+		fmt.Fprintf(&b, "input %s {\n", t.Name())
+		for _, name := range names {
+			field := fieldMap[name]
+			printDescription(&b, "  ", field.Description())
+			b.WriteString("  " + name + ": " + field.Type.String())
+			b.WriteString(printDefault(field.DefaultValue, field.Type) + "\n")
+		}
+		b.WriteString("}")
+	}
+	return b.String()
+}
+
+func printFields(fieldMap graphql.FieldDefinitionMap, opts SDLOptions) []string {
+	names := make([]string, 0, len(fieldMap))
+	for name := range fieldMap {
+		if opts.Exclude(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]string, 0, len(names))
+	for _, name := range names {
+		field := fieldMap[name]
+
+		var b strings.Builder
+		printDescription(&b, "  ", field.Description)
+		b.WriteString("  " + name + printArgs(field.Args) + ": " + field.Type.String())
+		b.WriteString(printDeprecated(field.DeprecationReason))
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+func printArgs(args []*graphql.Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	sorted := make([]*graphql.Argument, len(args))
+	copy(sorted, args)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name() < sorted[j].Name() })
+
+	printed := make([]string, 0, len(sorted))
+	for _, arg := range sorted {
+		printed = append(printed, arg.Name()+": "+arg.Type.String()+printDefault(arg.DefaultValue, arg.Type))
+	}
+	return "(" + strings.Join(printed, ", ") + ")"
+}
+
+func printDirective(directive *graphql.Directive) string {
+	var b strings.Builder
+	printDescription(&b, "", directive.Description)
+	fmt.Fprintf(&b, "directive @%s%s on %s",
+		directive.Name, printArgs(directive.Args), strings.Join(directive.Locations, " | "))
+	return b.String()
+}
+
+func printDescription(b *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	if !strings.Contains(description, "\n") {
+		b.WriteString(indent + strconv.Quote(description) + "\n")
+		return
+	}
+	b.WriteString(indent + `"""` + "\n")
+	for _, line := range strings.Split(description, "\n") {
+		b.WriteString(indent + strings.ReplaceAll(line, `"""`, `\"""`) + "\n")
+	}
+	b.WriteString(indent + `"""` + "\n")
+}
+
+func printDeprecated(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return " @deprecated(reason: " + strconv.Quote(reason) + ")"
+}
+
+func printDefault(value interface{}, t graphql.Input) string {
+	if value == nil {
+		return ""
+	}
+	return " = " + printValue(value, t)
+}
+
+// printValue returns a GraphQL literal of the value of the given type.
+func printValue(value interface{}, t graphql.Type) string {
+	if value == nil {
+		return "null"
+	}
+
+	var fieldMap graphql.InputObjectFieldMap
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return printValue(value, t.OfType)
+	case *graphql.Enum:
+		for _, def := range t.Values() {
+			if reflect.DeepEqual(def.Value, value) {
+				return def.Name
+			}
+		}
+	case *graphql.InputObject:
+		fieldMap = t.Fields()
+	}
+
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Slice, reflect.Array:
+		var ofType graphql.Type
+		if list, ok := graphql.GetNullable(t).(*graphql.List); ok {
+			ofType = list.OfType
+		}
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, printValue(v.Index(i).Interface(), ofType))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, fmt.Sprint(key.Interface()))
+		}
+		sort.Strings(keys)
+
+		fields := make([]string, 0, len(keys))
+		for _, key := range keys {
+			var ftype graphql.Type
+			if field, ok := fieldMap[key]; ok {
+				ftype = field.Type
+			}
+			item := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+			fields = append(fields, key+": "+printValue(item.Interface(), ftype))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return fmt.Sprint(value)
+}
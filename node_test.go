@@ -0,0 +1,141 @@
+package activegraph
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Article struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+type Comment struct {
+	Text    string  `json:"text"`
+	Article Article `json:"article"`
+}
+
+func newNodeController() Controller {
+	articles := map[string]Article{
+		"1": {ID: 1, Title: "Hello"},
+		"2": {ID: 2, Title: "World"},
+	}
+
+	article := NewType(Article{}, nil)
+	article.Node = NewNode(func(ctx context.Context, key struct {
+		ID string `json:"id"`
+	}) (*Article, error) {
+		if a, ok := articles[key.ID]; ok {
+			return &a, nil
+		}
+		return nil, nil
+	})
+
+	return Controller{
+		// Comment references the node type, and registered before it.
+		Types: []TypeDef{NewType(Comment{}, nil), article},
+		Queries: []FuncDef{
+			NewFunc("comments", func(ctx context.Context) ([]Comment, error) {
+				return []Comment{{Text: "Nice", Article: articles["1"]}}, nil
+			}),
+		},
+	}
+}
+
+func TestController_Node(t *testing.T) {
+	c := newNodeController()
+	h := c.HandleHTTP()
+
+	id1, id2 := ToGlobalID("Article", 1), ToGlobalID("Article", 2)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "GlobalID",
+			query: `{comments{article{id,title}}}`,
+			want:  `{"data":{"comments":[{"article":{"id":"` + id1 + `","title":"Hello"}}]}}`,
+		},
+		{
+			name:  "Node",
+			query: `{node(id:"` + id2 + `"){id,...on Article{title}}}`,
+			want:  `{"data":{"node":{"id":"` + id2 + `","title":"World"}}}`,
+		},
+		{
+			name:  "NodeNotFound",
+			query: `{node(id:"` + ToGlobalID("Article", 3) + `"){id}}`,
+			want:  `{"data":{"node":null}}`,
+		},
+		{
+			name: "Nodes",
+			query: `{nodes(ids:["` + id1 + `","` + ToGlobalID("Comment", 1) + `","invalid!"]){
+				...on Article{title}
+			}}`,
+			want: `{"data":{"nodes":[{"title":"Hello"},null,null]},"errors":[
+				{"message":"unknown node type \"Comment\"","locations":[{"line":1,"column":2}],"path":["nodes",1]},
+				{"message":"invalid node id \"invalid!\"","locations":[{"line":1,"column":2}],"path":["nodes",2]}
+			]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{"query": {tt.query}}
+
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil))
+			require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+			assert.JSONEq(t, tt.want, rw.Body.String())
+		})
+	}
+}
+
+func TestController_NodeSchema(t *testing.T) {
+	c := newNodeController()
+	schema, err := c.CreateSchema()
+	require.NoError(t, err)
+
+	sdl := PrintSchema(schema)
+	assert.Contains(t, sdl, "type Article implements Node {\n  id: ID!\n  title: String!\n}\n")
+	assert.Contains(t, sdl, "interface Node {\n  id: ID!\n}\n")
+	assert.Contains(t, sdl, "  node(id: ID!): Node\n  nodes(ids: [ID!]!): [Node]!\n")
+}
+
+func TestGlobalID(t *testing.T) {
+	typename, id, err := FromGlobalID(ToGlobalID("Article", 42))
+	require.NoError(t, err)
+	assert.Equal(t, "Article", typename)
+	assert.Equal(t, "42", id)
+
+	_, _, err = FromGlobalID(ToGlobalID("", 42))
+	assert.Error(t, err)
+}
+
+func TestDefineNode_Error(t *testing.T) {
+	_, err := DefineNode(func(ctx context.Context) (*Article, error) {
+		return nil, nil
+	})
+	assert.Error(t, err)
+
+	c := Controller{Types: []TypeDef{NewType(Product{}, nil)}}
+	c.Types[0].Node = NewNode(func(ctx context.Context, key struct{ ID string }) (*Product, error) {
+		return nil, nil
+	})
+	_, err = c.CreateSchema()
+	assert.EqualError(t, err, "activegraph: node Product is missing id field")
+
+	c = Controller{Types: []TypeDef{NewType(Article{}, nil)}}
+	c.Types[0].Node = NewNode(func(ctx context.Context, key struct{ ID string }) (*Product, error) {
+		return nil, nil
+	})
+	_, err = c.CreateSchema()
+	assert.EqualError(t, err, "activegraph: node fetch of Article must return activegraph.Article")
+}
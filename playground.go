@@ -1,32 +1,99 @@
 package activegraph
 
 import (
+	"embed"
 	"html/template"
+	"io/fs"
 	"net/http"
+	"strings"
 )
 
-const playgroundVersion = "1.8.10"
+// playgroundAssetsPath is a sub-path of the GraphQL handler, used to serve
+// assets of the IDE. Assets are embedded into the binary and never loaded
+// from the CDN, so the IDE works offline and with the restrictive
+// Content-Security-Policy.
+const playgroundAssetsPath = "/_playground/"
+
+// Assets of the GraphQL Playground (graphql-playground-react build).
+//
+//go:embed assets/playground
+var playgroundAssets embed.FS
+
+// PlaygroundTab is a tab opened in the IDE by default.
+type PlaygroundTab struct {
+	Name      string            `json:"name,omitempty"`
+	Endpoint  string            `json:"endpoint"`
+	Query     string            `json:"query"`
+	Variables string            `json:"variables,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// Playground defines settings of the in-browser IDE.
+//
+// The IDE is served to the browsers requesting the GraphQL handler with
+// "Accept: text/html" header. Assets of the IDE are served from the
+// "_playground/" sub-path of the handler, so the handler must be registered
+// for the whole sub-tree:
+//
+//	h := c.HandleHTTP()
+//	http.Handle("/graphql", h)
+//	http.Handle("/graphql/", h)
+type Playground struct {
+	// Endpoint is the URL of the GraphQL server, by default the path of
+	// the request is used.
+	Endpoint string
+
+	// SubscriptionEndpoint is the URL of the GraphQL subscriptions server.
+	SubscriptionEndpoint string
+
+	// Headers are sent with each request from the IDE.
+	Headers map[string]string
+
+	// Tabs are opened in the IDE by default.
+	Tabs []PlaygroundTab
+
+	// Assets overrides embedded assets of the IDE, e.g. with another build
+	// of the "graphql-playground-react" package. Assets must include
+	// "playground.css", "playground.js", "favicon.png" and "logo.png".
+	Assets http.FileSystem
+}
+
+func (p *Playground) assets() http.FileSystem {
+	if p.Assets != nil {
+		return p.Assets
+	}
+
+	assets, err := fs.Sub(playgroundAssets, "assets/playground")
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(assets)
+}
 
 const playgroundTemplate = `
-{{ define "index" }}
+{{ define "comment" }}
 <!--
 The request to this GraphQL server provided the header "Accept: text/html"
-and as a result has been presented Playground - an in-browser IDE for
+and as a result has been presented {{ .Title }} - an in-browser IDE for
 exploring GraphQL.
 
 If you wish to receive JSON, provide the header "Accept: application/json" or
 add "&raw" to the end of the URL within a browser.
 -->
+{{ end }}
+
+{{ define "playground" }}
+{{ template "comment" . }}
 <!DOCTYPE html>
 <html>
 
 <head>
 	<meta charset=utf-8/>
 	<meta name="viewport" content="user-scalable=no, initial-scale=1.0, minimum-scale=1.0, maximum-scale=1.0, minimal-ui">
-	<title>GraphQL Playground</title>
-	<link rel="stylesheet" href="//cdn.jsdelivr.net/npm/graphql-playground-react/build/static/css/index.css" />
-	<link rel="shortcut icon" href="//cdn.jsdelivr.net/npm/graphql-playground-react/build/favicon.png" />
-	<script src="//cdn.jsdelivr.net/npm/graphql-playground-react/build/static/js/middleware.js"></script>
+	<title>{{ .Title }}</title>
+	<link rel="stylesheet" href="{{ .AssetsURL }}playground.css" />
+	<link rel="shortcut icon" href="{{ .AssetsURL }}favicon.png" />
+	<script src="{{ .AssetsURL }}playground.js"></script>
 </head>
 
 <body>
@@ -58,17 +125,25 @@ add "&raw" to the end of the URL within a browser.
 				font-weight: 400;
 			}
 		</style>
-		<img src='//cdn.jsdelivr.net/npm/graphql-playground-react/build/logo.png' alt=''>
+		<img src="{{ .AssetsURL }}logo.png" alt="">
 		<div class="loading"> Loading
-			<span class="title">GraphQL Playground</span>
+			<span class="title">{{ .Title }}</span>
 		</div>
 	</div>
 	<script>
 	window.addEventListener('load', function (event) {
-		GraphQLPlayground.init(document.getElementById('root'), {
+		var options = {
 			endpoint: {{ .Endpoint }},
+			headers: {{ .Headers }},
 			setTitle: true
-		})
+		}
+		if ({{ .SubscriptionEndpoint }}) {
+			options.subscriptionEndpoint = {{ .SubscriptionEndpoint }}
+		}
+		if ({{ .Tabs }}.length > 0) {
+			options.tabs = {{ .Tabs }}
+		}
+		GraphQLPlayground.init(document.getElementById('root'), options)
 	})
 	</script>
 </body>
@@ -77,26 +152,63 @@ add "&raw" to the end of the URL within a browser.
 {{ end }}
 `
 
+var playgroundTmpl = template.Must(template.New("playground").Parse(playgroundTemplate))
+
 type playgroundData struct {
-	Version  string
-	Endpoint string
+	Title                string
+	AssetsURL            string
+	Endpoint             string
+	SubscriptionEndpoint string
+	Headers              map[string]string
+	Tabs                 []PlaygroundTab
 }
 
-func handlePlayground(rw http.ResponseWriter, r *http.Request) {
-	t := template.New("playground")
-	t, err := t.Parse(playgroundTemplate)
-	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
-		return
+func handlePlayground(rw http.ResponseWriter, r *http.Request, p Playground) {
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = r.URL.Path
 	}
 
+	assetsURL := strings.TrimSuffix(r.URL.Path, "/") + playgroundAssetsPath
+
 	data := playgroundData{
-		Version:  playgroundVersion,
-		Endpoint: r.URL.Path,
+		Title:                "GraphQL Playground",
+		AssetsURL:            assetsURL,
+		Endpoint:             endpoint,
+		SubscriptionEndpoint: p.SubscriptionEndpoint,
+		Headers:              p.Headers,
+	}
+	if data.Headers == nil {
+		data.Headers = make(map[string]string)
+	}
+
+	// Tabs without endpoint are opened for the endpoint of the IDE.
+	data.Tabs = make([]PlaygroundTab, len(p.Tabs))
+	for i, tab := range p.Tabs {
+		if tab.Endpoint == "" {
+			tab.Endpoint = endpoint
+		}
+		data.Tabs[i] = tab
 	}
 
-	err = t.ExecuteTemplate(rw, "index", data)
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := playgroundTmpl.ExecuteTemplate(rw, "playground", data)
 	if err != nil {
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+// handlePlaygroundAssets serves assets of the IDE, when the path of the
+// request is within the assets sub-path of the handler.
+func handlePlaygroundAssets(rw http.ResponseWriter, r *http.Request, p Playground) bool {
+	i := strings.Index(r.URL.Path, playgroundAssetsPath)
+	if i < 0 {
+		return false
+	}
+
+	assets := p.assets()
+	rw.Header().Set("Cache-Control", "public, max-age=86400")
+	prefix := r.URL.Path[:i+len(playgroundAssetsPath)]
+	http.StripPrefix(prefix, http.FileServer(assets)).ServeHTTP(rw, r)
+	return true
+}
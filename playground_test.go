@@ -0,0 +1,92 @@
+package activegraph
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestController_Playground(t *testing.T) {
+	posts := NewFunc("posts", func(ctx context.Context) ([]string, error) {
+		return nil, nil
+	})
+
+	tests := []struct {
+		name       string
+		playground Playground
+		contains   []string
+	}{
+		{
+			name: "Playground",
+			playground: Playground{
+				SubscriptionEndpoint: "ws://localhost/subscriptions",
+				Headers:              map[string]string{"X-Api-Key": "secret"},
+				Tabs:                 []PlaygroundTab{{Name: "Posts", Query: "{posts}"}},
+			},
+			contains: []string{
+				`/graphql/_playground/playground.js`,
+				`"ws://localhost/subscriptions"`,
+				`"X-Api-Key":"secret"`,
+				`{"name":"Posts","endpoint":"/graphql","query":"{posts}"}`,
+			},
+		},
+		{
+			name:       "Endpoint",
+			playground: Playground{Endpoint: "/api"},
+			contains:   []string{`endpoint: "/api"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Controller{Queries: []FuncDef{posts}, Playground: tt.playground}
+
+			r := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+			r.Header.Set("Accept", "text/html")
+
+			rw := httptest.NewRecorder()
+			s.HandleHTTP().ServeHTTP(rw, r)
+
+			assert.Equal(t, http.StatusOK, rw.Code)
+			for _, s := range tt.contains {
+				assert.Contains(t, rw.Body.String(), s)
+			}
+		})
+	}
+}
+
+func TestController_PlaygroundAssets(t *testing.T) {
+	posts := NewFunc("posts", func(ctx context.Context) ([]string, error) {
+		return nil, nil
+	})
+
+	custom := http.FS(fstest.MapFS{
+		"playground.js": &fstest.MapFile{Data: []byte("GraphQLPlayground")},
+	})
+
+	tests := []struct {
+		playground Playground
+		path       string
+		status     int
+	}{
+		{Playground{}, "/graphql/_playground/playground.js", http.StatusOK},
+		{Playground{}, "/graphql/_playground/logo.png", http.StatusOK},
+		{Playground{}, "/graphql/_playground/unknown.js", http.StatusNotFound},
+		{Playground{Assets: custom}, "/graphql/_playground/playground.js", http.StatusOK},
+		{Playground{Assets: custom}, "/graphql/_playground/logo.png", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			s := Controller{Queries: []FuncDef{posts}, Playground: tt.playground}
+
+			rw := httptest.NewRecorder()
+			s.HandleHTTP().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.status, rw.Code)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package activegraph
+
+import (
+	"io"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	qlast "github.com/graphql-go/graphql/language/ast"
+	"github.com/pkg/errors"
+)
+
+// Profile is a set of security settings of the GraphQL handler.
+type Profile int
+
+const (
+	// Development profile enables introspection and playground, and
+	// exposes error messages to the clients.
+	Development Profile = iota
+
+	// Production profile disables introspection and playground, masks
+	// error messages and limits the request body size.
+	Production
+)
+
+const (
+	// DefaultMaxBodySize is the maximum size of the request body in
+	// production profile.
+	DefaultMaxBodySize = 1 << 20
+
+	// maskedErrorMessage replaces error messages in production profile.
+	maskedErrorMessage = "internal server error"
+)
+
+// options returns the handler options of the profile.
+func (p Profile) options() handlerOptions {
+	switch p {
+	case Production:
+		return handlerOptions{
+			maxBodySize: DefaultMaxBodySize,
+			maskErrors:  true,
+		}
+	default:
+		return handlerOptions{playground: true, introspection: true}
+	}
+}
+
+// errBodyTooLarge is returned when request body exceeds the limit.
+var errBodyTooLarge = errors.New("request body too large")
+
+// limitedBody is a request body that returns an error on attempt to read
+// more than the specified number of bytes.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (n int, err error) {
+	if b.remaining <= 0 {
+		// Ensure that the body is actually larger than the limit, and
+		// return an error only in that case.
+		var buf [1]byte
+		if n, err = b.ReadCloser.Read(buf[:]); n > 0 {
+			b.exceeded = true
+			return 0, errBodyTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err = b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// hasIntrospection returns true when the document selects __schema or
+// __type fields.
+func hasIntrospection(doc *qlast.Document) bool {
+	var visit func(set *qlast.SelectionSet) bool
+	visit = func(set *qlast.SelectionSet) bool {
+		if set == nil {
+			return false
+		}
+		for _, sel := range set.Selections {
+			switch sel := sel.(type) {
+			case *qlast.Field:
+				if name := sel.Name.Value; name == "__schema" || name == "__type" {
+					return true
+				}
+				if visit(sel.SelectionSet) {
+					return true
+				}
+			case *qlast.InlineFragment:
+				if visit(sel.SelectionSet) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *qlast.OperationDefinition:
+			if visit(def.SelectionSet) {
+				return true
+			}
+		case *qlast.FragmentDefinition:
+			if visit(def.SelectionSet) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maskErrors replaces messages of the result errors, so the internal
+// details are not exposed to the clients.
+//
+// Errors with extensions are considered as client errors (e.g. ErrForbidden),
+// therefore these errors are returned as is.
+func maskErrors(errs []gqlerrors.FormattedError) {
+	for i := range errs {
+		if len(errs[i].Extensions) > 0 {
+			continue
+		}
+		errs[i] = gqlerrors.FormattedError{
+			Message:   maskedErrorMessage,
+			Locations: errs[i].Locations,
+			Path:      errs[i].Path,
+		}
+	}
+}
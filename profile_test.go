@@ -0,0 +1,82 @@
+package activegraph
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestController_ProductionProfile(t *testing.T) {
+	type Input struct {
+		Name string `json:"name"`
+	}
+
+	s := Controller{
+		Profile:     Production,
+		MaxBodySize: 64,
+		Queries: []FuncDef{
+			NewFunc("posts", func(ctx context.Context) ([]string, error) {
+				return []string{"First"}, nil
+			}),
+			NewFunc("fail", func(ctx context.Context) (*string, error) {
+				return nil, errors.New("connection to 10.0.0.1 refused")
+			}),
+		},
+		Mutations: []FuncDef{
+			NewFunc("rename", func(ctx context.Context, in Input) (string, error) {
+				return in.Name, nil
+			}),
+		},
+	}
+	h := s.HandleHTTP()
+
+	get := func(query string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(query), nil)
+	}
+	post := func(body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/graphql")
+		return r
+	}
+
+	strict := func(r *http.Request) *http.Request {
+		r.Header.Set("Accept", "application/graphql-response+json")
+		return r
+	}
+
+	playground := get("{posts}")
+	playground.Header.Set("Accept", "text/html,*/*;q=0.8")
+
+	tests := []struct {
+		name   string
+		r      *http.Request
+		status int
+		body   string
+	}{
+		{"Query", get("{posts}"), http.StatusOK, `"posts":["First"]`},
+		{"Playground", playground, http.StatusOK, `"posts":["First"]`},
+		{"MaskErrors", get("{fail}"), http.StatusOK, `"message":"internal server error"`},
+		{"Schema", strict(get("{__schema{types{name}}}")), http.StatusBadRequest, "introspection is disabled"},
+		{"Type", strict(get("{...F} fragment F on Query{__type(name:\"Query\"){name}}")), http.StatusBadRequest, "introspection is disabled"},
+		{"SchemaLegacy", get("{__schema{types{name}}}"), http.StatusOK, "introspection is disabled"},
+		{"GetMutation", get(`mutation{rename(input:{name:"x"})}`), http.StatusMethodNotAllowed, "POST"},
+		{"PostMutation", post(`mutation{rename(input:{name:"x"})}`), http.StatusOK, `"rename":"x"`},
+		{"BodyTooLarge", post("{" + strings.Repeat("posts,", 20) + "}"), http.StatusRequestEntityTooLarge, "too large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, tt.r)
+
+			assert.Equal(t, tt.status, rw.Code)
+			assert.Contains(t, rw.Body.String(), tt.body)
+		})
+	}
+}
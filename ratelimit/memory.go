@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemorySweepInterval is the interval of removal of the refilled buckets
+// from the MemoryStore, full buckets are equal to the missing ones.
+const MemorySweepInterval = time.Minute
+
+// memoryBucket is a bucket along with its limit, the limit is required
+// to find out whether the bucket is refilled.
+type memoryBucket struct {
+	bucket
+	limit Limit
+}
+
+// MemoryStore keeps token buckets in memory of the process. Buckets are
+// removed, once they are refilled, so the memory is bounded by the number
+// of clients sent requests within the refill period.
+//
+// The zero value for MemoryStore is an empty store ready to use.
+type MemoryStore struct {
+	buckets map[string]*memoryBucket
+	swept   time.Time
+	mu      sync.Mutex
+}
+
+// Take takes n tokens from the bucket identified by the key.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, n int) (Status, error) {
+	return s.take(key, limit, n, time.Now()), nil
+}
+
+func (s *MemoryStore) take(key string, limit Limit, n int, now time.Time) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buckets == nil {
+		s.buckets = make(map[string]*memoryBucket)
+		s.swept = now
+	}
+	if now.Sub(s.swept) >= MemorySweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = new(memoryBucket)
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	return b.take(limit, n, now)
+}
+
+// sweep removes buckets refilled to the full capacity.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.full(b.limit, now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.swept = now
+}
@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	var (
+		store MemoryStore
+		limit = PerMinute(2)
+		now   = time.Now()
+	)
+
+	store.take("first", limit, 1, now)
+	store.take("second", limit, 2, now.Add(30*time.Second))
+	assert.Len(t, store.buckets, 2)
+
+	// The first bucket is refilled after 30 seconds, while the second
+	// one requires a minute to be refilled.
+	status := store.take("third", limit, 1, now.Add(MemorySweepInterval))
+	assert.True(t, status.Allowed)
+	assert.Len(t, store.buckets, 2)
+	assert.NotContains(t, store.buckets, "first")
+
+	store.take("third", limit, 1, now.Add(3*MemorySweepInterval))
+	assert.Len(t, store.buckets, 1)
+
+	// Removed buckets are equal to the full ones.
+	status = store.take("second", limit, 2, now.Add(3*MemorySweepInterval))
+	assert.True(t, status.Allowed)
+	assert.Equal(t, 0, status.Remaining)
+}
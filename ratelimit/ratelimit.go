@@ -0,0 +1,332 @@
+// Package ratelimit implements limiting of GraphQL operations with token
+// buckets, each client is given its own bucket of tokens.
+//
+//	limiter := ratelimit.Limiter{
+//		Store:    new(ratelimit.MemoryStore),
+//		Identity: ratelimit.HeaderIdentity("X-Api-Key"),
+//		Limit:    ratelimit.PerMinute(100),
+//	}
+//
+//	var c activegraph.Controller
+//	c.AppendBeforeOp(activegraph.OperationQuery, limiter.Callback())
+//	c.AppendBeforeOp(activegraph.OperationMutation, limiter.Callback())
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	qlast "github.com/graphql-go/graphql/language/ast"
+
+	"github.com/activegraph/activegraph"
+)
+
+// Limit defines the token bucket: the bucket holds at most Burst tokens,
+// and it is refilled with Rate tokens every Period.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// PerSecond returns a limit of n tokens per second.
+func PerSecond(n int) Limit {
+	return Limit{Rate: n, Period: time.Second, Burst: n}
+}
+
+// PerMinute returns a limit of n tokens per minute.
+func PerMinute(n int) Limit {
+	return Limit{Rate: n, Period: time.Minute, Burst: n}
+}
+
+// PerHour returns a limit of n tokens per hour.
+func PerHour(n int) Limit {
+	return Limit{Rate: n, Period: time.Hour, Burst: n}
+}
+
+// refill returns a number of tokens added to the bucket within a duration.
+func (l Limit) refill(d time.Duration) float64 {
+	if l.Period <= 0 {
+		return 0
+	}
+	return float64(l.Rate) * float64(d) / float64(l.Period)
+}
+
+// duration returns a time required to refill the given number of tokens.
+func (l Limit) duration(tokens float64) time.Duration {
+	if l.Rate <= 0 || tokens <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens * float64(l.Period) / float64(l.Rate)))
+}
+
+// Status describes the state of the bucket after taking tokens.
+type Status struct {
+	// Allowed is true, when the bucket had enough tokens.
+	Allowed bool
+
+	// Limit is the capacity of the bucket.
+	Limit int
+
+	// Remaining is a number of tokens left in the bucket.
+	Remaining int
+
+	// Reset is a time left until the bucket is full.
+	Reset time.Duration
+
+	// RetryAfter is a time left until the bucket has enough tokens to
+	// allow the rejected request.
+	RetryAfter time.Duration
+}
+
+// bucket is a state of the token bucket.
+type bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// take refills the bucket and takes n tokens from it, when there are
+// enough tokens.
+func (b *bucket) take(limit Limit, n int, now time.Time) Status {
+	if b.Updated.IsZero() {
+		b.Tokens = float64(limit.Burst)
+	} else if now.After(b.Updated) {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+limit.refill(now.Sub(b.Updated)))
+	}
+	b.Updated = now
+
+	status := Status{Limit: limit.Burst}
+	if b.Tokens >= float64(n) {
+		b.Tokens -= float64(n)
+		status.Allowed = true
+	} else {
+		status.RetryAfter = limit.duration(float64(n) - b.Tokens)
+	}
+
+	status.Remaining = int(math.Floor(b.Tokens))
+	status.Reset = limit.duration(float64(limit.Burst) - b.Tokens)
+	return status
+}
+
+// full returns true, when the bucket is refilled to the full capacity.
+func (b *bucket) full(limit Limit, now time.Time) bool {
+	return b.Tokens+limit.refill(now.Sub(b.Updated)) >= float64(limit.Burst)
+}
+
+// Store keeps the state of token buckets.
+type Store interface {
+	// Take takes n tokens from the bucket identified by the key.
+	Take(ctx context.Context, key string, limit Limit, n int) (Status, error)
+}
+
+// IdentityFunc returns an identity of the client sent the request. Requests
+// with empty identity are identified by the IP address of the client.
+type IdentityFunc func(*activegraph.Request) string
+
+// HeaderIdentity identifies clients by the value of the request header,
+// e.g. API key.
+func HeaderIdentity(name string) IdentityFunc {
+	return func(r *activegraph.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RemoteAddrIdentity identifies clients by the IP address.
+func RemoteAddrIdentity() IdentityFunc {
+	return func(r *activegraph.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
+
+// ContextIdentity identifies clients by the value stored in the request
+// context, e.g. authenticated user.
+func ContextIdentity(key interface{}) IdentityFunc {
+	return func(r *activegraph.Request) string {
+		val := r.Context().Value(key)
+		if val == nil {
+			return ""
+		}
+		return fmt.Sprint(val)
+	}
+}
+
+// CostFunc returns a number of tokens required to execute the request.
+type CostFunc func(*activegraph.Request) int
+
+// QueryCost calculates the cost of the request as the number of fields
+// selected within the requested operation, including nested fields and
+// fields of fragments.
+func QueryCost(r *activegraph.Request) int {
+	doc := r.Document()
+	if doc == nil {
+		return 1
+	}
+
+	fragments := make(map[string]*qlast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragdef, ok := def.(*qlast.FragmentDefinition); ok && fragdef.Name != nil {
+			fragments[fragdef.Name.Value] = fragdef
+		}
+	}
+
+	var cost func(set *qlast.SelectionSet, visited map[string]bool) int
+	cost = func(set *qlast.SelectionSet, visited map[string]bool) (n int) {
+		if set == nil {
+			return 0
+		}
+		for _, sel := range set.Selections {
+			switch sel := sel.(type) {
+			case *qlast.Field:
+				n += 1 + cost(sel.SelectionSet, visited)
+			case *qlast.InlineFragment:
+				n += cost(sel.SelectionSet, visited)
+			case *qlast.FragmentSpread:
+				name := sel.Name.Value
+				if fragdef, ok := fragments[name]; ok && !visited[name] {
+					visited[name] = true
+					n += cost(fragdef.SelectionSet, visited)
+				}
+			}
+		}
+		return n
+	}
+
+	for _, def := range doc.Definitions {
+		opdef, ok := def.(*qlast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if r.OperationName != "" && (opdef.Name == nil || opdef.Name.Value != r.OperationName) {
+			continue
+		}
+		if n := cost(opdef.SelectionSet, make(map[string]bool)); n > 0 {
+			return n
+		}
+		return 1
+	}
+	return 1
+}
+
+// Error is returned to the client, when the request exceeds the limit.
+type Error struct {
+	Status Status
+}
+
+// Error returns a string representation of the error.
+func (e *Error) Error() string {
+	return "rate limit exceeded, retry in " + e.Status.RetryAfter.String()
+}
+
+// Extensions returns extensions of the GraphQL error.
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       "RATE_LIMITED",
+		"retryAfter": seconds(e.Status.RetryAfter),
+	}
+}
+
+// Limiter limits the number of GraphQL operations executed by each client.
+type Limiter struct {
+	// Store keeps the state of the buckets, use SQLiteStore to share the
+	// limits among multiple processes.
+	Store Store
+
+	// Identity identifies the client of the request.
+	Identity IdentityFunc
+
+	// Limit is the default limit of all operations.
+	Limit Limit
+
+	// Operations defines limits for the operations by name, each such
+	// operation is limited independently from the others. The name of the
+	// operation is taken from the document, so the operation name of the
+	// request cannot select the limit of another operation.
+	Operations map[string]Limit
+
+	// Cost returns the number of tokens taken by each request, when cost
+	// function is not specified, each request takes a single token.
+	Cost CostFunc
+}
+
+func (l *Limiter) limit(r *activegraph.Request) (key string, limit Limit) {
+	opdef := r.OperationDefinition()
+	if opdef == nil || opdef.Name == nil {
+		return "*", l.Limit
+	}
+	if limit, ok := l.Operations[opdef.Name.Value]; ok {
+		return opdef.Name.Value, limit
+	}
+	return "*", l.Limit
+}
+
+// identity returns the identity of the client, clients without identity
+// are limited by the IP address, so omitting the identity does not bypass
+// the limit.
+func (l *Limiter) identity(r *activegraph.Request) string {
+	if identity := l.Identity(r); identity != "" {
+		return "id:" + identity
+	}
+	return "addr:" + RemoteAddrIdentity()(r)
+}
+
+// Callback returns a callback that rejects requests exceeding the limit
+// and emits RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+// Rejected requests are responded with 429 Too Many Requests status and
+// Retry-After header.
+func (l *Limiter) Callback() activegraph.Callback {
+	if l.Store == nil {
+		panic("ratelimit: nil store")
+	}
+	if l.Identity == nil {
+		panic("ratelimit: nil identity function")
+	}
+
+	return func(rw activegraph.ResponseWriter, r *activegraph.Request) {
+		identity := l.identity(r)
+
+		cost := 1
+		if l.Cost != nil {
+			cost = l.Cost(r)
+		}
+
+		name, limit := l.limit(r)
+		status, err := l.Store.Take(r.Context(), identity+":"+name, limit, cost)
+		if err != nil {
+			rw.Write(&graphql.Result{
+				Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)},
+			})
+			return
+		}
+
+		header := rw.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(status.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(status.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(status.Reset)))
+
+		if !status.Allowed {
+			header.Set("Retry-After", strconv.Itoa(seconds(status.RetryAfter)))
+			activegraph.WriteStatus(rw, http.StatusTooManyRequests)
+
+			e := &Error{Status: status}
+			rw.Write(&graphql.Result{
+				Errors: []gqlerrors.FormattedError{{Message: e.Error(), Extensions: e.Extensions()}},
+			})
+		}
+	}
+}
+
+// seconds rounds the duration up to seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,222 @@
+package ratelimit_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph"
+	"github.com/activegraph/activegraph/ratelimit"
+)
+
+func TestLimiter_Callback(t *testing.T) {
+	limiter := ratelimit.Limiter{
+		Store:    new(ratelimit.MemoryStore),
+		Identity: ratelimit.HeaderIdentity("X-Api-Key"),
+		Limit:    ratelimit.PerMinute(2),
+	}
+
+	c := activegraph.Controller{
+		Queries: []activegraph.FuncDef{
+			activegraph.NewFunc("posts", func(ctx context.Context) ([]string, error) {
+				return []string{"First"}, nil
+			}),
+		},
+	}
+	c.AppendBeforeOp(activegraph.OperationQuery, limiter.Callback())
+	h := c.HandleHTTP()
+
+	serve := func(apiKey string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		r := httptest.NewRequest(http.MethodGet, "/graphql?query={posts}", nil)
+		r.Header.Set("X-Api-Key", apiKey)
+
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &body))
+		return rw, body
+	}
+
+	rw, body := serve("first")
+	assert.Nil(t, body["errors"])
+	assert.Equal(t, "2", rw.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rw.Header().Get("RateLimit-Remaining"))
+
+	rw, body = serve("first")
+	assert.Nil(t, body["errors"])
+	assert.Equal(t, "0", rw.Header().Get("RateLimit-Remaining"))
+
+	rw, body = serve("first")
+	assert.Nil(t, body["data"])
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Equal(t, "30", rw.Header().Get("Retry-After"))
+
+	errs := body["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "RATE_LIMITED", errs[0].(map[string]interface{})["extensions"].(map[string]interface{})["code"])
+
+	// The status does not depend on the media type of the response.
+	r := httptest.NewRequest(http.MethodGet, "/graphql?query={posts}", nil)
+	r.Header.Set("X-Api-Key", "first")
+	r.Header.Set("Accept", "application/graphql-response+json")
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+
+	// Buckets of different clients are independent.
+	rw, body = serve("second")
+	assert.Nil(t, body["errors"])
+	assert.Equal(t, "1", rw.Header().Get("RateLimit-Remaining"))
+}
+
+func TestLimiter_CallbackIdentity(t *testing.T) {
+	limiter := ratelimit.Limiter{
+		Store:      new(ratelimit.MemoryStore),
+		Identity:   ratelimit.HeaderIdentity("X-Api-Key"),
+		Limit:      ratelimit.PerMinute(2),
+		Operations: map[string]ratelimit.Limit{"Posts": ratelimit.PerMinute(1)},
+	}
+
+	c := activegraph.Controller{
+		Queries: []activegraph.FuncDef{
+			activegraph.NewFunc("posts", func(ctx context.Context) ([]string, error) {
+				return []string{"First"}, nil
+			}),
+		},
+	}
+	c.AppendBeforeOp(activegraph.OperationQuery, limiter.Callback())
+	h := c.HandleHTTP()
+
+	// limited returns true, when the request is rejected by the limiter.
+	limited := func(query url.Values, remoteAddr string) bool {
+		r := httptest.NewRequest(http.MethodGet, "/graphql?"+query.Encode(), nil)
+		r.RemoteAddr = remoteAddr
+
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		return rw.Header().Get("Retry-After") != ""
+	}
+
+	// Requests without identity are limited by the IP address.
+	anonymous := url.Values{"query": {"{posts}"}}
+	assert.False(t, limited(anonymous, "192.0.2.1:1234"))
+	assert.False(t, limited(anonymous, "192.0.2.1:1235"))
+	assert.True(t, limited(anonymous, "192.0.2.1:1236"))
+	assert.False(t, limited(anonymous, "192.0.2.2:1234"))
+
+	// The limit of the operation is selected by the name of the operation
+	// in the document, even when the operation name is omitted.
+	posts := url.Values{"query": {"query Posts {posts}"}}
+	assert.False(t, limited(posts, "192.0.2.3:1234"))
+	assert.True(t, limited(posts, "192.0.2.3:1234"))
+
+	posts.Set("operationName", "Posts")
+	assert.True(t, limited(posts, "192.0.2.3:1234"))
+}
+
+func TestLimiter_CallbackCached(t *testing.T) {
+	limiter := ratelimit.Limiter{
+		Store:    new(ratelimit.MemoryStore),
+		Identity: ratelimit.HeaderIdentity("X-Api-Key"),
+		Limit:    ratelimit.PerMinute(1),
+	}
+
+	var numCalls int
+	posts := activegraph.NewFunc("posts", func(ctx context.Context) ([]string, error) {
+		numCalls++
+		return []string{"First"}, nil
+	})
+	posts.Cache = &activegraph.CacheHint{MaxAge: time.Minute}
+
+	c := activegraph.Controller{
+		Queries: []activegraph.FuncDef{posts},
+		Cache:   new(activegraph.MemoryCache),
+	}
+	c.AppendBeforeOp(activegraph.OperationQuery, limiter.Callback())
+	h := c.HandleHTTP()
+
+	serve := func(apiKey string) map[string]interface{} {
+		r := httptest.NewRequest(http.MethodGet, "/graphql?query={posts}", nil)
+		r.Header.Set("X-Api-Key", apiKey)
+
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &body))
+		return body
+	}
+
+	body := serve("first")
+	assert.Nil(t, body["errors"])
+
+	// The second client is served from the cache.
+	body = serve("second")
+	assert.Nil(t, body["errors"])
+	assert.Equal(t, map[string]interface{}{"posts": []interface{}{"First"}}, body["data"])
+	assert.Equal(t, 1, numCalls)
+
+	// The cached response is rejected, when the limit is exceeded.
+	body = serve("first")
+	assert.Nil(t, body["data"])
+	require.Len(t, body["errors"], 1)
+}
+
+func TestSQLiteStore_Take(t *testing.T) {
+	defer os.Remove(t.Name() + ".db")
+
+	db, err := sql.Open("sqlite3", "file:"+t.Name()+".db?_txlock=immediate")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, err := ratelimit.NewSQLiteStore(db)
+	require.NoError(t, err)
+
+	limit := ratelimit.Limit{Rate: 1, Period: time.Hour, Burst: 3}
+
+	status, err := store.Take(context.TODO(), "key", limit, 2)
+	require.NoError(t, err)
+	assert.True(t, status.Allowed)
+	assert.Equal(t, 1, status.Remaining)
+
+	status, err = store.Take(context.TODO(), "key", limit, 2)
+	require.NoError(t, err)
+	assert.False(t, status.Allowed)
+	assert.Equal(t, 1, status.Remaining)
+
+	status, err = store.Take(context.TODO(), "key", limit, 1)
+	require.NoError(t, err)
+	assert.True(t, status.Allowed)
+	assert.Equal(t, 0, status.Remaining)
+}
+
+func TestQueryCost(t *testing.T) {
+	c := activegraph.Controller{
+		Queries: []activegraph.FuncDef{
+			activegraph.NewFunc("posts", func(ctx context.Context) ([]string, error) {
+				return nil, nil
+			}),
+		},
+	}
+
+	var cost int
+	c.AppendBeforeOp(activegraph.OperationQuery, func(rw activegraph.ResponseWriter, r *activegraph.Request) {
+		cost = ratelimit.QueryCost(r)
+	})
+
+	query := url.QueryEscape("{a:posts,b:posts,...F} fragment F on Query{posts}")
+	r := httptest.NewRequest(http.MethodGet, "/graphql?query="+query, nil)
+	c.HandleHTTP().ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, 3, cost)
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const sqliteCreateTable = `
+CREATE TABLE IF NOT EXISTS ratelimit_buckets (
+	key        VARCHAR NOT NULL,
+	tokens     REAL NOT NULL,
+	updated_at INTEGER NOT NULL,
+
+	PRIMARY KEY(key)
+)`
+
+// SQLiteStore keeps token buckets in the SQLite database, so the limits
+// could be shared among multiple processes.
+//
+// Open the database with "_txlock=immediate" option to serialize access to
+// the buckets from different processes:
+//
+//	db, err := sql.Open("sqlite3", "file:ratelimit.db?_txlock=immediate")
+//	store, err := ratelimit.NewSQLiteStore(db)
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates a new store and ensures that the table of buckets
+// exists in the database.
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	if _, err := db.Exec(sqliteCreateTable); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Take takes n tokens from the bucket identified by the key.
+func (s *SQLiteStore) Take(ctx context.Context, key string, limit Limit, n int) (
+	status Status, err error,
+) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return status, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var (
+		b         bucket
+		updatedAt int64
+	)
+
+	const selectStmt = `SELECT tokens, updated_at FROM ratelimit_buckets WHERE key = ?`
+	err = tx.QueryRowContext(ctx, selectStmt, key).Scan(&b.Tokens, &updatedAt)
+	switch err {
+	case nil:
+		b.Updated = time.Unix(0, updatedAt)
+	case sql.ErrNoRows:
+	default:
+		return status, err
+	}
+
+	status = b.take(limit, n, time.Now())
+
+	const upsertStmt = `
+	INSERT INTO ratelimit_buckets (key, tokens, updated_at) VALUES (?, ?, ?)
+	ON CONFLICT(key) DO UPDATE SET tokens = excluded.tokens, updated_at = excluded.updated_at`
+
+	_, err = tx.ExecContext(ctx, upsertStmt, key, b.Tokens, b.Updated.UnixNano())
+	if err != nil {
+		return status, err
+	}
+	return status, tx.Commit()
+}
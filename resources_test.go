@@ -0,0 +1,708 @@
+package activegraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/actioncontroller"
+	actiongraphql "github.com/activegraph/activegraph/actioncontroller/graphql"
+	"github.com/activegraph/activegraph/actionview"
+	"github.com/activegraph/activegraph/activerecord"
+	_ "github.com/activegraph/activegraph/activerecord/sqlite3"
+	"github.com/activegraph/activegraph/activesupport"
+)
+
+type Novel struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+func newResourcesController() *Controller {
+	novel := activerecord.New("novel", func(r *activerecord.R) {
+		r.AttrString("title")
+	})
+	novels := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Index(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.ViewResult(activesupport.Return(novel.WithContext(ctx).All().ToA()))
+		})
+		c.Show(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.ViewResult(novel.WithContext(ctx).Find(ctx.Params["id"]))
+		})
+	})
+
+	c := &Controller{
+		Queries: []FuncDef{
+			// The function returns the type of the resource.
+			NewFunc("featuredNovel", func(ctx context.Context) (*Novel, error) {
+				return &Novel{ID: 1, Title: "Dune"}, nil
+			}),
+		},
+	}
+	return c.HandleResources(novel, novels)
+}
+
+func TestController_Resources(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer activerecord.RemoveConnection("primary")
+
+	c := newResourcesController()
+
+	conn, err := activerecord.PrimaryConnection()
+	require.NoError(t, err)
+	require.NoError(t, conn.Exec(context.Background(), `
+		CREATE TABLE novels (id INTEGER PRIMARY KEY, title VARCHAR);
+		INSERT INTO novels (id, title) VALUES (1, 'Dune');
+	`))
+
+	// Callbacks of the controller apply to the resource actions.
+	var ops []string
+	c.AppendBeforeOp(OperationQuery, func(rw ResponseWriter, r *Request) {
+		ops = append(ops, r.Operation())
+	})
+
+	q := url.Values{"query": {`{novels{id,title},featuredNovel{id,title}}`}}
+	rw := httptest.NewRecorder()
+	c.HandleHTTP().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil))
+	require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+
+	id := ToGlobalID("novel", 1)
+	assert.JSONEq(t, `{"data":{
+		"novels":[{"id":"`+id+`","title":"Dune"}],
+		"featuredNovel":{"id":"`+id+`","title":"Dune"}
+	}}`, rw.Body.String())
+	assert.Equal(t, []string{OperationQuery}, ops)
+}
+
+func TestController_ResourcesSchema(t *testing.T) {
+	schema, err := newResourcesController().CreateSchema()
+	require.NoError(t, err)
+
+	sdl := PrintSchema(schema)
+	assert.Contains(t, sdl, "type Novel implements Node {\n  id: ID!\n  title: String\n}\n")
+	assert.Contains(t, sdl, "  featuredNovel: Novel\n")
+
+	// The index action does not declare filtering and ordering, so the
+	// arguments are not advertised.
+	assert.Contains(t, sdl, "  novels: [Novel]\n")
+	assert.NotContains(t, sdl, "NovelFilter")
+	assert.NotContains(t, sdl, "NovelOrder")
+}
+
+func TestController_ResourcesSchemaFilterable(t *testing.T) {
+	novel := activerecord.New("novel", func(r *activerecord.R) {
+		r.AttrString("title")
+	})
+	novels := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Filterable()
+		c.Sortable()
+		c.Index(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			rel, err := novel.WithContext(ctx).Filter(ctx.Params.Get("where"))
+			if err == nil {
+				orders, _ := ctx.Params["orderBy"].([]interface{})
+				rel, err = rel.OrderBy(orders)
+			}
+			if err != nil {
+				return actionview.ViewResult(activesupport.Err(err))
+			}
+			return actionview.ViewResult(activesupport.Return(rel.ToA()))
+		})
+	})
+
+	schema, err := (&Controller{}).HandleResources(novel, novels).CreateSchema()
+	require.NoError(t, err)
+
+	sdl := PrintSchema(schema)
+	assert.Contains(t, sdl, "  novels(orderBy: [NovelOrder!], where: NovelFilter): [Novel]\n")
+	assert.Contains(t, sdl, "input NovelOrder {\n  direction: OrderDirection = ASC\n  field: NovelOrderField!\n}\n")
+	assert.Contains(t, sdl, "enum NovelOrderField {\n  ID\n  TITLE\n}\n")
+	assert.Contains(t, sdl, "input NovelFilter {\n  and: [NovelFilter!]\n  id: IntFilter\n  not: NovelFilter\n  or: [NovelFilter!]\n  title: StringFilter\n}\n")
+}
+
+func TestController_ResourcesError(t *testing.T) {
+	c := newResourcesController()
+	c.HandleQuery("novels", func(ctx context.Context) ([]Novel, error) {
+		return nil, nil
+	})
+
+	_, err := c.CreateSchema()
+	assert.EqualError(t, err, "activegraph: multiple registrations for novels")
+
+	c = newResourcesController()
+	c.Types = append(c.Types, NewType(Novel{}, nil))
+	_, err = c.CreateSchema()
+	assert.EqualError(t, err, "activegraph: multiple type registrations for Novel")
+}
+
+func newMatchController() *Controller {
+	stats := func(version string) actioncontroller.Action {
+		return &actioncontroller.NamedAction{
+			Name: "stats",
+			AnonymousAction: func(ctx *actioncontroller.Context) actioncontroller.Result {
+				return actionview.ResultFunc(func(ctx *actioncontroller.Context) (interface{}, error) {
+					genre, _ := ctx.Params["genre"].(string)
+					return map[string]interface{}{"genre": genre, "version": version}, nil
+				})
+			},
+		}
+	}
+
+	var (
+		request = &actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+			activerecord.StringAttr{Name: "genre"},
+		}}
+		response = &actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+			activerecord.StringAttr{Name: "genre"},
+		}}
+		responseV2 = &actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+			activerecord.StringAttr{Name: "genre"}, activerecord.StringAttr{Name: "version"},
+		}}
+	)
+
+	c := &Controller{Resources: new(actiongraphql.Mapper)}
+	c.Resources.Match(actiongraphql.OperationQuery, "stats", stats("1"), actioncontroller.Constraints{
+		Request: request, Response: response,
+	})
+	c.Resources.Match(actiongraphql.OperationQuery, "stats", stats("2"), actioncontroller.Constraints{
+		Request: request, Response: responseV2,
+		Match: actioncontroller.MatchHeader("X-Api-Version", "2"),
+	})
+	c.Resources.Match(actiongraphql.OperationQuery, "beta", stats("beta"), actioncontroller.Constraints{
+		Request: request, Response: responseV2,
+		Match: actioncontroller.MatcherFunc(func(*actioncontroller.Request) bool { return false }),
+	})
+	return c
+}
+
+func TestController_Match(t *testing.T) {
+	h := newMatchController().HandleHTTP()
+
+	tests := []struct {
+		name    string
+		query   string
+		version string
+		want    string
+	}{
+		{
+			name:  "Default",
+			query: `{stats(genre:"fiction"){genre,version}}`,
+			want:  `{"data":{"stats":{"genre":"fiction","version":"1"}}}`,
+		},
+		{
+			name:    "Header",
+			query:   `{stats(genre:"poetry"){genre,version}}`,
+			version: "2",
+			want:    `{"data":{"stats":{"genre":"poetry","version":"2"}}}`,
+		},
+		{
+			name:  "NotMatched",
+			query: `{beta{version}}`,
+			want: `{"data":{"beta":null},"errors":[
+				{"message":"no action matches query 'beta'","locations":[{"line":1,"column":2}],"path":["beta"]}
+			]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{"query": {tt.query}}
+			r := httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil)
+			if tt.version != "" {
+				r.Header.Set("X-Api-Version", tt.version)
+			}
+
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, r)
+			require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+			assert.JSONEq(t, tt.want, rw.Body.String())
+		})
+	}
+}
+
+func TestController_MatchSchema(t *testing.T) {
+	schema, err := newMatchController().CreateSchema()
+	require.NoError(t, err)
+
+	sdl := PrintSchema(schema)
+	assert.Contains(t, sdl, "  stats(genre: String): StatsPayload\n")
+	assert.Contains(t, sdl, "type StatsPayload {\n  genre: String\n  version: String\n}\n")
+
+	c := newMatchController()
+	c.Resources.Match(actiongraphql.OperationQuery, "stats", nil, actioncontroller.Constraints{
+		Request: &actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+			activerecord.IntAttr{Name: "genre"},
+		}},
+		Response: &actioncontroller.StrongParameters{},
+	})
+	_, err = c.CreateSchema()
+	assert.EqualError(t, err, `request of query 'stats': attribute "genre" is both string and int`)
+}
+
+func TestController_ResourcesPermit(t *testing.T) {
+	novel := activerecord.New("novel", func(r *activerecord.R) {
+		r.AttrString("title")
+		r.AttrInt("year")
+	})
+
+	var created actioncontroller.Parameters
+	novels := actioncontroller.New(func(c *actioncontroller.C) {
+		c.PermitParameters(actioncontroller.Require(novel).Permit("title").PermitArray("tags",
+			&actioncontroller.StrongParameters{Attributes: []activerecord.Attribute{
+				activerecord.StringAttr{Name: "name"},
+			}},
+		), actioncontroller.ActionCreate)
+
+		c.Create(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.ResultFunc(func(ctx *actioncontroller.Context) (interface{}, error) {
+				created = ctx.Params.Get("novel")
+				return map[string]interface{}{"id": 1, "title": created["title"]}, nil
+			})
+		})
+		c.Show(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.ViewResult(novel.WithContext(ctx).Find(ctx.Params["id"]))
+		})
+	})
+
+	c := (&Controller{}).HandleResources(novel, novels)
+
+	schema, err := c.CreateSchema()
+	require.NoError(t, err)
+
+	sdl := PrintSchema(schema)
+	assert.Contains(t, sdl, "input CreateNovelInput {\n  tags: [CreateNovelTagsInput!]\n  title: String\n}\n")
+	assert.Contains(t, sdl, "input CreateNovelTagsInput {\n  name: String\n}\n")
+
+	body := `{"query":"mutation{createNovel(novel:{title:\"Dune\",tags:[{name:\"classic\"}]}){title}}"}`
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	rw := httptest.NewRecorder()
+	c.HandleHTTP().ServeHTTP(rw, r)
+	require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+
+	assert.JSONEq(t, `{"data":{"createNovel":{"title":"Dune"}}}`, rw.Body.String())
+	assert.Equal(t, actioncontroller.Parameters{
+		"title": "Dune", "tags": []actioncontroller.Parameters{{"name": "classic"}},
+	}, created)
+}
+
+func TestResourceController(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer activerecord.RemoveConnection("primary")
+
+	conn, err := activerecord.PrimaryConnection()
+	require.NoError(t, err)
+	require.NoError(t, conn.Exec(context.Background(), `
+		CREATE TABLE novels (id INTEGER PRIMARY KEY, title VARCHAR, year INTEGER);
+		INSERT INTO novels (id, title, year) VALUES (1, 'Utopia', 1516);
+	`))
+
+	novel := activerecord.New("novel", func(r *activerecord.R) {
+		r.AttrString("title", activerecord.MaxLen(12))
+		r.AttrInt("year")
+	})
+
+	novels := actioncontroller.ResourceController(novel,
+		actioncontroller.WithScope(func(ctx *actioncontroller.Context, rel *activerecord.Relation) *activerecord.Relation {
+			return rel.Where("year > ?", 1900)
+		}),
+		actioncontroller.WithAuthorize(func(ctx *actioncontroller.Context, actionName string) error {
+			if actionName == actioncontroller.ActionDestroy {
+				return errors.New("not allowed")
+			}
+			return nil
+		}),
+		actioncontroller.WithBeforeSave(func(ctx *actioncontroller.Context, rec *activerecord.ActiveRecord) error {
+			if rec.Attribute("year") == nil {
+				return rec.AssignAttribute("year", 1965)
+			}
+			return nil
+		}),
+	)
+
+	h := (&Controller{}).HandleResources(novel, novels).HandleHTTP()
+
+	do := func(query string) string {
+		body, err := json.Marshal(map[string]string{"query": query})
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+		return rw.Body.String()
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "Create",
+			query: `mutation{createNovel(novel:{title:"Dune"}){title,year}}`,
+			want:  `{"data":{"createNovel":{"title":"Dune","year":1965}}}`,
+		},
+		{
+			name:  "CreateInvalid",
+			query: `mutation{createNovel(novel:{title:"Children of Dune"}){title}}`,
+			want: `{"data":{"createNovel":null},"errors":[{
+				"message":"validation of novel failed: \"Children of Dune\" lenght is >12",
+				"locations":[{"line":1,"column":10}],"path":["createNovel"]
+			}]}`,
+		},
+		{
+			name:  "Update",
+			query: `mutation{updateNovel(id:2,novel:{title:"Dune Messiah"}){title,year}}`,
+			want:  `{"data":{"updateNovel":{"title":"Dune Messiah","year":1965}}}`,
+		},
+		{
+			name:  "Index",
+			query: `{novels{title}}`,
+			want:  `{"data":{"novels":[{"title":"Dune Messiah"}]}}`,
+		},
+		{
+			name:  "NotFound",
+			query: `{novel(id:1){title}}`,
+			want: `{"data":{"novel":null},"errors":[{
+				"message":"record not found by id = 1",
+				"locations":[{"line":1,"column":2}],"path":["novel"]
+			}]}`,
+		},
+		{
+			name:  "Unauthorized",
+			query: `mutation{deleteNovel(id:2){title}}`,
+			want: `{"data":{"deleteNovel":null},"errors":[{
+				"message":"not allowed",
+				"locations":[{"line":1,"column":10}],"path":["deleteNovel"]
+			}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.want, do(tt.query))
+		})
+	}
+}
+
+func TestResourceController_GlobalID(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer activerecord.RemoveConnection("primary")
+
+	conn, err := activerecord.PrimaryConnection()
+	require.NoError(t, err)
+	require.NoError(t, conn.Exec(context.Background(), `
+		CREATE TABLE scrolls (id INTEGER PRIMARY KEY, title VARCHAR);
+		INSERT INTO scrolls (id, title) VALUES (1, 'Dead Sea');
+	`))
+
+	scroll := activerecord.New("scroll", func(r *activerecord.R) {
+		r.AttrString("title")
+	})
+	h := (&Controller{}).HandleResources(scroll, actioncontroller.ResourceController(scroll)).HandleHTTP()
+
+	do := func(query string, vars map[string]interface{}) string {
+		body, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+		require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+		return rw.Body.String()
+	}
+
+	var resp struct {
+		Data struct {
+			Scrolls []struct{ ID string }
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(do(`{scrolls{id}}`, nil)), &resp))
+	require.Len(t, resp.Data.Scrolls, 1)
+
+	// Identifiers returned by the "id" field are accepted by the actions.
+	id := resp.Data.Scrolls[0].ID
+	assert.Equal(t, ToGlobalID("scroll", 1), id)
+
+	vars := map[string]interface{}{"id": id}
+	assert.JSONEq(t, `{"data":{"scroll":{"id":"`+id+`","title":"Dead Sea"}}}`,
+		do(`query($id:ID!){scroll(id:$id){id,title}}`, vars))
+	assert.JSONEq(t, `{"data":{"updateScroll":{"title":"Copper"}}}`,
+		do(`mutation($id:ID!){updateScroll(id:$id,scroll:{title:"Copper"}){title}}`, vars))
+	assert.JSONEq(t, `{"data":{"deleteScroll":{"id":"`+id+`"}}}`,
+		do(`mutation($id:ID!){deleteScroll(id:$id){id}}`, vars))
+	assert.JSONEq(t, `{"data":{"scrolls":[]}}`, do(`{scrolls{id}}`, nil))
+}
+
+func TestResourceController_AuthorizeNode(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer activerecord.RemoveConnection("primary")
+
+	conn, err := activerecord.PrimaryConnection()
+	require.NoError(t, err)
+	require.NoError(t, conn.Exec(context.Background(), `
+		CREATE TABLE manuscripts (id INTEGER PRIMARY KEY, title VARCHAR);
+		INSERT INTO manuscripts (id, title) VALUES (1, 'Voynich');
+	`))
+
+	manuscript := activerecord.New("manuscript", func(r *activerecord.R) {
+		r.AttrString("title")
+	})
+
+	// Nodes and entities are fetched through the show action, so the
+	// authorization of the action applies to them.
+	manuscripts := actioncontroller.ResourceController(manuscript,
+		actioncontroller.WithAuthorize(func(ctx *actioncontroller.Context, actionName string) error {
+			if actionName == actioncontroller.ActionShow {
+				return errors.New("not allowed")
+			}
+			return nil
+		}),
+	)
+
+	c := (&Controller{}).HandleResources(manuscript, manuscripts)
+	c.Resources.Federation = true
+	h := c.HandleHTTP()
+
+	id := ToGlobalID("manuscript", 1)
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "Node",
+			query: `{node(id:"` + id + `"){...on Manuscript{title}}}`,
+			want: `{"data":{"node":null},"errors":[{
+				"message":"not allowed","locations":[{"line":1,"column":2}],"path":["node"]
+			}]}`,
+		},
+		{
+			name:  "Nodes",
+			query: `{nodes(ids:["` + id + `"]){...on Manuscript{title}}}`,
+			want: `{"data":{"nodes":[null]},"errors":[{
+				"message":"not allowed","locations":[{"line":1,"column":2}],"path":["nodes",0]
+			}]}`,
+		},
+		{
+			name:  "Entities",
+			query: `{_entities(representations:[{__typename:"Manuscript",id:1}]){...on Manuscript{title}}}`,
+			want: `{"data":{"_entities":[null]},"errors":[{
+				"message":"not allowed","locations":[{"line":1,"column":2}],"path":["_entities",0]
+			}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{"query": {tt.query}}
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil))
+			assert.JSONEq(t, tt.want, rw.Body.String())
+		})
+	}
+}
+
+func TestController_ResourcesFilter(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer activerecord.RemoveConnection("primary")
+
+	conn, err := activerecord.PrimaryConnection()
+	require.NoError(t, err)
+	require.NoError(t, conn.Exec(context.Background(), `
+		CREATE TABLE writers (id INTEGER PRIMARY KEY, name VARCHAR);
+		CREATE TABLE essays (id INTEGER PRIMARY KEY, title VARCHAR, year INTEGER, writer_id INTEGER);
+		INSERT INTO writers (id, name) VALUES (1, 'Montaigne'), (2, 'Orwell');
+		INSERT INTO essays (id, title, year, writer_id) VALUES
+			(1, 'Of Idleness', 1580, 1),
+			(2, 'Of Cannibals', 1580, 1),
+			(3, 'Shooting an Elephant', 1936, 2),
+			(4, 'Politics and the English Language', 1946, 2);
+	`))
+
+	writer := activerecord.New("writer", func(r *activerecord.R) {
+		r.AttrString("name")
+	})
+	essay := activerecord.New("essay", func(r *activerecord.R) {
+		r.AttrString("title")
+		r.AttrInt("year")
+		r.BelongsTo("writer")
+	})
+
+	c := (&Controller{}).
+		HandleResources(writer, actioncontroller.ResourceController(writer)).
+		HandleResources(essay, actioncontroller.ResourceController(essay))
+
+	schema, err := c.CreateSchema()
+	require.NoError(t, err)
+
+	sdl := PrintSchema(schema)
+	assert.Contains(t, sdl, "  writer: WriterFilter\n  writer_id: IntFilter\n")
+	assert.Contains(t, sdl, "input StringFilter {\n  contains: String\n  eq: String\n  gt: String\n"+
+		"  in: [String!]\n  isNull: Boolean\n  lt: String\n  ne: String\n}\n")
+
+	h := c.HandleHTTP()
+
+	tests := []struct {
+		name  string
+		where string
+		want  string
+	}{
+		{
+			name:  "Operators",
+			where: `{year:{gt:1900},title:{contains:"an"}}`,
+			want:  `[{"title":"Shooting an Elephant"},{"title":"Politics and the English Language"}]`,
+		},
+		{
+			name:  "Combinators",
+			where: `{or:[{title:{eq:"Of Idleness"}},{not:{year:{in:[1580,1936]}}}]}`,
+			want:  `[{"title":"Of Idleness"},{"title":"Politics and the English Language"}]`,
+		},
+		{
+			name:  "BelongsTo",
+			where: `{writer:{name:{eq:"Montaigne"}},title:{ne:"Of Idleness"}}`,
+			want:  `[{"title":"Of Cannibals"}]`,
+		},
+		{
+			name:  "Injection",
+			where: `{title:{eq:"x' OR '1'='1"}}`,
+			want:  `[]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{"query": {`{essays(where:` + tt.where + `){title}}`}}
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil))
+			require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+			assert.JSONEq(t, `{"data":{"essays":`+tt.want+`}}`, rw.Body.String())
+		})
+	}
+}
+
+func TestController_ResourcesOrder(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	defer activerecord.RemoveConnection("primary")
+
+	conn, err := activerecord.PrimaryConnection()
+	require.NoError(t, err)
+	require.NoError(t, conn.Exec(context.Background(), `
+		CREATE TABLE writers (id INTEGER PRIMARY KEY, name VARCHAR);
+		CREATE TABLE essays (id INTEGER PRIMARY KEY, title VARCHAR, year INTEGER, writer_id INTEGER);
+		INSERT INTO writers (id, name) VALUES (1, 'Orwell'), (2, 'Montaigne');
+		INSERT INTO essays (id, title, year, writer_id) VALUES
+			(1, 'Shooting an Elephant', 1936, 1),
+			(2, 'Of Idleness', 1580, 2),
+			(3, 'Politics and the English Language', 1946, 1),
+			(4, 'Of Cannibals', 1580, 2);
+	`))
+
+	// The association is resolved through the reflection of the relations.
+	activerecord.New("writer", func(r *activerecord.R) {
+		r.AttrString("name")
+	})
+	essay := activerecord.New("essay", func(r *activerecord.R) {
+		r.AttrString("title")
+		r.AttrInt("year")
+		r.BelongsTo("writer")
+	})
+
+	c := (&Controller{}).HandleResources(essay, actioncontroller.ResourceController(essay,
+		actioncontroller.WithSortable("year", "writer.name"),
+	))
+
+	schema, err := c.CreateSchema()
+	require.NoError(t, err)
+	assert.Contains(t, PrintSchema(schema), "enum EssayOrderField {\n  WRITER_NAME\n  YEAR\n}\n")
+
+	h := c.HandleHTTP()
+
+	tests := []struct {
+		name    string
+		orderBy string
+		want    string
+	}{
+		{
+			name:    "Direction",
+			orderBy: `[{field:YEAR,direction:DESC}]`,
+			want:    `[3,1,2,4]`,
+		},
+		{
+			name:    "Tiebreak",
+			orderBy: `[{field:YEAR}]`,
+			want:    `[2,4,1,3]`,
+		},
+		{
+			name:    "BelongsTo",
+			orderBy: `[{field:WRITER_NAME},{field:YEAR,direction:DESC}]`,
+			want:    `[2,4,3,1]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{"query": {`{essays(orderBy:` + tt.orderBy + `){id}}`}}
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil))
+			require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+
+			var ids []int
+			require.NoError(t, json.Unmarshal([]byte(tt.want), &ids))
+
+			want := make([]map[string]interface{}, 0, len(ids))
+			for _, id := range ids {
+				want = append(want, map[string]interface{}{"id": ToGlobalID("essay", id)})
+			}
+			body, err := json.Marshal(map[string]interface{}{"data": map[string]interface{}{"essays": want}})
+			require.NoError(t, err)
+			assert.JSONEq(t, string(body), rw.Body.String())
+		})
+	}
+
+	// Fields out of the allowlist are rejected by the schema.
+	q := url.Values{"query": {`{essays(orderBy:[{field:TITLE}]){id}}`}}
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil))
+	assert.Contains(t, rw.Body.String(), `Expected type \"EssayOrderField\", found TITLE.`)
+
+	// Unknown sortable fields are detected on the schema creation.
+	c = (&Controller{}).HandleResources(essay, actioncontroller.ResourceController(essay,
+		actioncontroller.WithSortable("writer.email"),
+	))
+	_, err = c.CreateSchema()
+	assert.EqualError(t, err, `unknown order "writer.email" for essay`)
+}
@@ -1,10 +1,14 @@
 package activegraph
 
 import (
+	"context"
 	"reflect"
 
 	"github.com/graphql-go/graphql"
 	"github.com/pkg/errors"
+
+	actiongraphql "github.com/activegraph/activegraph/actioncontroller/graphql"
+	"github.com/activegraph/activegraph/internal"
 )
 
 // objectType defines the "direction" of the object, it's either input
@@ -19,37 +23,65 @@ const (
 // GraphQL is GraphQL schema compiler, it produces GraphQL
 // schema definition from the Go type and function definitions.
 type GraphQL struct {
-	inputs    map[string]graphql.Type
-	outputs   map[string]graphql.Type
-	queries   graphql.Fields
-	mutations graphql.Fields
+	// schema is shared with the resources mapper, so resources and
+	// functions are served within a single schema.
+	schema *internal.SchemaBuilder
+
+	extensions []graphql.Extension
+
+	// hints are cache hints of the registered types.
+	hints map[reflect.Type]*CacheHint
 }
 
 func (c *GraphQL) init() {
-	if c.inputs == nil {
-		c.inputs = make(map[string]graphql.Type)
+	if c.schema == nil {
+		c.schema = internal.NewSchemaBuilder()
 	}
-	if c.outputs == nil {
-		c.outputs = make(map[string]graphql.Type)
+	if c.hints == nil {
+		c.hints = make(map[reflect.Type]*CacheHint)
 	}
-	if c.queries == nil {
-		c.queries = make(graphql.Fields)
+}
+
+// cacheHint returns a cache hint of the function, when the function does
+// not define the hint explicitly, the hint of the returned type is used.
+func (c *GraphQL) cacheHint(funcdef FuncDef) *CacheHint {
+	if funcdef.Cache != nil {
+		return funcdef.Cache
 	}
-	if c.mutations == nil {
-		c.mutations = make(graphql.Fields)
+
+	gotype := funcdef.Out
+	for gotype.Kind() == reflect.Ptr || gotype.Kind() == reflect.Slice || gotype.Kind() == reflect.Array {
+		gotype = gotype.Elem()
 	}
+	return c.hints[gotype]
 }
 
 // AddType registers the given type in the GraphQL schema.
 func (c *GraphQL) AddType(typedef TypeDef) error {
 	c.init()
 
-	if _, exist := c.outputs[typedef.Name]; exist {
+	if _, exist := c.schema.Outputs[typedef.Name]; exist {
 		return errors.New("activegraph: multiple type registrations for " + typedef.Name)
 	}
 
+	// Objects implement interfaces from the moment of creation, therefore
+	// the node object is created before the regular type lookup.
+	if typedef.Node != nil {
+		if _, exist := c.schema.Outputs[typedef.Type.Name()]; exist {
+			return errors.Errorf(
+				"activegraph: node %s must be registered before types referencing it",
+				typedef.Type.Name())
+		}
+		obj, err := newObject(
+			typedef.Type.Name(), typedef.Type, outObjectType, c.schema.Outputs, c.schema.Nodes.Interface())
+		if err != nil {
+			return err
+		}
+		c.schema.Outputs[obj.Name()] = graphql.NewNonNull(obj)
+	}
+
 	// Create a new GraphQL object from the Go type definition.
-	gqltype, err := newType(typedef.Type, outObjectType, c.outputs)
+	gqltype, err := newType(typedef.Type, outObjectType, c.schema.Outputs)
 	if err != nil {
 		return err
 	}
@@ -59,71 +91,198 @@ func (c *GraphQL) AddType(typedef TypeDef) error {
 		return errors.New("activegraph: type expected to be an object")
 	}
 
+	if typedef.Cache != nil {
+		c.hints[typedef.Type] = typedef.Cache
+	}
+
 	// Add methods for a new GraphQL type. All methods should be
 	// bounded to this GraphQL type.
 	for name, funcdef := range typedef.Funcs {
-		out, err := newType(funcdef.Out, outObjectType, c.outputs)
+		out, err := newType(funcdef.Out, outObjectType, c.schema.Outputs)
 		if err != nil {
 			return err
 		}
 
+		resolve := newAuthorizedFunc(funcdef, newBoundFunc(funcdef))
+		resolve = newCacheFunc(c.cacheHint(funcdef), false, resolve)
+
 		obj.AddFieldConfig(name, &graphql.Field{
 			Name:    name,
-			Type:    out,
-			Resolve: newBoundFunc(funcdef),
+			Type:    newAuthorizedType(funcdef, out),
+			Resolve: newConcurrentFunc(funcdef, resolve),
 		})
 	}
 
-	c.outputs[typedef.Name] = obj
+	if len(typedef.Keys) > 0 {
+		entity, err := newEntity(typedef, obj)
+		if err != nil {
+			return err
+		}
+		c.schema.Entities = append(c.schema.Entities, entity)
+	}
+
+	if typedef.Node != nil {
+		node, err := newNode(typedef, obj)
+		if err != nil {
+			return err
+		}
+		obj.AddFieldConfig("id", c.schema.Nodes.IDField(obj.Name(), graphql.DefaultResolveFn))
+		c.schema.Nodes.Add(node)
+	}
+
+	c.schema.Outputs[typedef.Name] = obj
 	return nil
 }
 
+// newIsTypeOf returns a function, that returns true, when the value (or
+// pointer to the value) is of the given Go type.
+func newIsTypeOf(gotype reflect.Type) func(value interface{}) bool {
+	return func(value interface{}) bool {
+		valueType := reflect.TypeOf(value)
+		for valueType != nil && valueType.Kind() == reflect.Ptr {
+			valueType = valueType.Elem()
+		}
+		return valueType == gotype
+	}
+}
+
+// newEntity creates a federated entity from the type definition with keys.
+func newEntity(typedef TypeDef, obj *graphql.Object) (entity internal.Entity, err error) {
+	entity = internal.Entity{Object: obj, IsTypeOf: newIsTypeOf(typedef.Type)}
+
+	for _, keydef := range typedef.Keys {
+		gotype := keydef.Resolve.Out
+		for gotype.Kind() == reflect.Ptr {
+			gotype = gotype.Elem()
+		}
+		if gotype != typedef.Type {
+			return entity, errors.Errorf(
+				"activegraph: key %q of %s must return %s", keydef.Fields, obj.Name(), typedef.Type)
+		}
+
+		// Entities are resolved with the same authorization rules, as the
+		// regular fields.
+		funcdef := keydef.Resolve
+		resolve := newAuthorizedFunc(funcdef, func(p graphql.ResolveParams) (interface{}, error) {
+			return funcdef.CallUnbound(p.Context, p.Source)
+		})
+
+		entity.Keys = append(entity.Keys, internal.EntityKey{
+			Fields: keydef.Fields,
+			Resolve: func(ctx context.Context, repr map[string]interface{}) (interface{}, error) {
+				return resolve(graphql.ResolveParams{
+					Context: ctx, Source: repr, Info: graphql.ResolveInfo{FieldName: "_entities"},
+				})
+			},
+		})
+	}
+	return entity, nil
+}
+
+// newNode creates a Relay node from the type definition with a fetch function.
+func newNode(typedef TypeDef, obj *graphql.Object) (node internal.NodeType, err error) {
+	if _, ok := obj.Fields()["id"]; !ok {
+		return node, errors.Errorf("activegraph: node %s is missing id field", obj.Name())
+	}
+
+	funcdef := typedef.Node.Fetch
+	gotype := funcdef.Out
+	for gotype.Kind() == reflect.Ptr {
+		gotype = gotype.Elem()
+	}
+	if gotype != typedef.Type {
+		return node, errors.Errorf(
+			"activegraph: node fetch of %s must return %s", obj.Name(), typedef.Type)
+	}
+
+	// Nodes are fetched with the same authorization rules, as the regular
+	// fields.
+	resolve := newAuthorizedFunc(funcdef, func(p graphql.ResolveParams) (interface{}, error) {
+		return funcdef.CallUnbound(p.Context, p.Source)
+	})
+
+	return internal.NodeType{
+		Name:   obj.Name(),
+		Object: obj,
+		Fetch: func(ctx context.Context, id string) (interface{}, error) {
+			return resolve(graphql.ResolveParams{
+				Context: ctx,
+				Source:  map[string]interface{}{"id": id},
+				Info:    graphql.ResolveInfo{FieldName: "node"},
+			})
+		},
+		IsTypeOf: newIsTypeOf(typedef.Type),
+	}, nil
+}
+
 func (c *GraphQL) AddQuery(funcdef FuncDef) error {
 	c.init()
-	if _, dup := c.queries[funcdef.Name]; dup {
+	if _, dup := c.schema.Queries[funcdef.Name]; dup {
 		return errors.New("activegraph: multiple registrations for " + funcdef.Name)
 	}
 
-	in, err := newQueryArgs(funcdef.In, c.inputs)
+	in, err := newQueryArgs(funcdef.In, c.schema.Inputs)
 	if err != nil {
 		return err
 	}
 
-	out, err := newType(funcdef.Out, outObjectType, c.outputs)
+	out, err := newType(funcdef.Out, outObjectType, c.schema.Outputs)
 	if err != nil {
 		return err
 	}
 
-	c.queries[funcdef.Name] = &graphql.Field{
+	resolve := newAuthorizedFunc(funcdef, newQueryFunc(funcdef))
+	resolve = newCacheFunc(c.cacheHint(funcdef), true, resolve)
+
+	return c.schema.AddQuery(funcdef.Name, &graphql.Field{
 		Args:    in,
-		Type:    out,
-		Resolve: newQueryFunc(funcdef),
-	}
-	return nil
+		Type:    newAuthorizedType(funcdef, out),
+		Resolve: newConcurrentFunc(funcdef, resolve),
+	})
 }
 
 func (c *GraphQL) AddMutation(funcdef FuncDef) (err error) {
 	c.init()
-	if _, dup := c.mutations[funcdef.Name]; dup {
+	if _, dup := c.schema.Mutations[funcdef.Name]; dup {
 		return errors.New("activegraph: multiple registrations for " + funcdef.Name)
 	}
 
-	in, err := newMutationArgs(funcdef.In, c.inputs)
+	in, err := newMutationArgs(funcdef.In, c.schema.Inputs)
 	if err != nil {
 		return err
 	}
 
-	out, err := newType(funcdef.Out, outObjectType, c.outputs)
+	out, err := newType(funcdef.Out, outObjectType, c.schema.Outputs)
 	if err != nil {
 		return err
 	}
 
-	c.mutations[funcdef.Name] = &graphql.Field{
+	return c.schema.AddMutation(funcdef.Name, &graphql.Field{
 		Args:    in,
-		Type:    out,
-		Resolve: newMutationFunc(funcdef),
-	}
-	return nil
+		Type:    newAuthorizedType(funcdef, out),
+		Resolve: newAuthorizedFunc(funcdef, newMutationFunc(funcdef)),
+	})
+}
+
+// AddResources registers types, queries and mutations of the resources
+// mapped by the mapper, resource types could be referenced by functions
+// registered after the resources.
+func (c *GraphQL) AddResources(m *actiongraphql.Mapper) error {
+	c.init()
+	return m.MapSchema(c.schema)
+}
+
+// AddExtension registers the extension notified about the execution of
+// each request.
+func (c *GraphQL) AddExtension(ext graphql.Extension) {
+	c.extensions = append(c.extensions, ext)
+}
+
+// EnableFederation exposes the schema as an Apollo Federation v2 subgraph,
+// federation is enabled implicitly when any type declares keys.
+func (c *GraphQL) EnableFederation() {
+	c.init()
+	c.schema.Federation = true
 }
 
 // Compile creates GraphQL schema based on registered types, queries and
@@ -131,21 +290,41 @@ func (c *GraphQL) AddMutation(funcdef FuncDef) (err error) {
 func (c *GraphQL) CreateSchema() (graphql.Schema, error) {
 	c.init()
 
-	query := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Query", Fields: c.queries,
-	})
+	// Incremental delivery directives are supported in addition to the
+	// directives of the specification.
+	directives := make([]*graphql.Directive, 0, len(graphql.SpecifiedDirectives)+2)
+	directives = append(directives, graphql.SpecifiedDirectives...)
+	directives = append(directives, deferDirective, streamDirective)
 
-	var mutation *graphql.Object
-	if len(c.mutations) > 0 {
-		mutation = graphql.NewObject(graphql.ObjectConfig{
-			Name: "Mutation", Fields: c.mutations,
-		})
+	schema, err := c.schema.Build(graphql.SchemaConfig{
+		Directives: directives,
+		Extensions: c.extensions,
+	})
+	if err != nil {
+		return schema, err
 	}
 
-	return graphql.NewSchema(graphql.SchemaConfig{
-		Query:    query,
-		Mutation: mutation,
-	})
+	withIncrementalFuncs(schema)
+	return schema, nil
+}
+
+// PrintSchema returns the schema definition in GraphQL SDL. Built-in scalars,
+// directives and introspection types are omitted, types and fields are
+// printed in the alphabetical order.
+func PrintSchema(schema graphql.Schema) string {
+	return internal.PrintSDL(&schema, internal.SDLOptions{})
+}
+
+// ToGlobalID returns an opaque global identifier of the Relay node, that
+// encodes the name of the type and the local identifier of the node.
+func ToGlobalID(typename string, id interface{}) string {
+	return internal.ToGlobalID(typename, id)
+}
+
+// FromGlobalID decodes the type name and the local identifier from the
+// global identifier of the Relay node.
+func FromGlobalID(globalID string) (typename, id string, err error) {
+	return internal.FromGlobalID(globalID)
 }
 
 // newBoundFunc creates a field resolve function that can be
@@ -231,8 +410,12 @@ func newQueryFunc(funcdef FuncDef) graphql.FieldResolveFn {
 // newObject returns a new GraphQL object with the given name and
 // the set of fields. Object type specifies the type: either input or
 // output object.
+//
+// Output objects implement the given interfaces, interfaces are ignored
+// for the input objects.
 func newObject(
 	name string, gotype reflect.Type, ot objectType, types map[string]graphql.Type,
+	interfaces ...*graphql.Interface,
 ) (graphql.Type, error) {
 	fields := make(map[string]graphql.Type)
 	for i := 0; i < gotype.NumField(); i++ {
@@ -266,8 +449,9 @@ func newObject(
 			objFields[fname] = &graphql.Field{Name: fname, Type: t}
 		}
 		return graphql.NewObject(graphql.ObjectConfig{
-			Name:   name,
-			Fields: objFields,
+			Name:       name,
+			Fields:     objFields,
+			Interfaces: interfaces,
 		}), nil
 	default:
 		return nil, errors.New("unknown object type")
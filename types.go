@@ -26,6 +26,33 @@ type TypeDef struct {
 
 	// Funcs is a list of methods for this type.
 	Funcs map[string]FuncDef
+
+	// Cache is a cache hint of all functions that return this type.
+	Cache *CacheHint
+
+	// Keys declare the type as a federated entity, see DefineKey for
+	// details.
+	Keys []KeyDef
+
+	// Node declares the type as a Relay node, see DefineNode for details.
+	Node *NodeDef
+}
+
+// NodeDef declares the type as an implementation of the Relay "Node"
+// interface, the node is refetched by the global identifier.
+type NodeDef struct {
+	// Fetch is a function that returns the node by its local identifier.
+	Fetch FuncDef
+}
+
+// KeyDef is a key of the federated entity, it's declared with the "@key"
+// directive on the type within the subgraph schema.
+type KeyDef struct {
+	// Fields is a field set of the key, e.g. "id" or "sku package".
+	Fields string
+
+	// Resolve is a function that returns the entity by its representation.
+	Resolve FuncDef
 }
 
 // ClosureDef represents anonymous closure function definition.
@@ -48,6 +75,19 @@ type FuncDef struct {
 	// Out is the type that function returns as the first return parameter.
 	// The second return parameter must be an error type.
 	Out reflect.Type
+
+	// Concurrent marks the function as safe for concurrent execution. Such
+	// functions are resolved in parallel with sibling fields of the query,
+	// when the server is configured with non-zero MaxConcurrency.
+	Concurrent bool
+
+	// Cache is a cache hint of the function result, it overrides the
+	// cache hint of the returned type.
+	Cache *CacheHint
+
+	// Rules are authorization rules of the function, the function is
+	// executed only when all rules pass. See Authorize for details.
+	Rules []Rule
 }
 
 func (fd FuncDef) Call(in []reflect.Value) (interface{}, error) {
@@ -277,3 +317,75 @@ func NewType(v interface{}, methods Funcs) TypeDef {
 	}
 	return typedef
 }
+
+// DefineKey returns a new key definition of the federated entity. The
+// function resolves the entity by its representation, the representation
+// is decoded into the second argument of the function:
+//
+//	typedef := activegraph.NewType(Product{}, nil)
+//	typedef.Keys = []activegraph.KeyDef{
+//		activegraph.NewKey("upc", func(ctx context.Context, key struct {
+//			UPC string `json:"upc"`
+//		}) (*Product, error) {
+//			// Fetch product here.
+//		}),
+//	}
+//
+// The key is used to resolve the entity, when the representation contains
+// all fields of the key.
+func DefineKey(fields string, v interface{}) (keydef KeyDef, err error) {
+	funcdef, err := DefineFunc(fields, v)
+	if err != nil {
+		return keydef, err
+	}
+	if funcdef.In == nil {
+		return keydef, errors.Errorf("key %q is missing representation argument", fields)
+	}
+	return KeyDef{Fields: fields, Resolve: funcdef}, nil
+}
+
+// NewKey creates a new key definition, on error it panics.
+//
+// See documentation of DefineKey for more details.
+func NewKey(fields string, v interface{}) KeyDef {
+	keydef, err := DefineKey(fields, v)
+	if err != nil {
+		panic(err)
+	}
+	return keydef
+}
+
+// DefineNode returns a new node definition of the type. The function fetches
+// the node by its local identifier, the identifier is decoded into the second
+// argument of the function:
+//
+//	typedef := activegraph.NewType(Post{}, nil)
+//	typedef.Node = activegraph.NewNode(func(ctx context.Context, key struct {
+//		ID string `json:"id"`
+//	}) (*Post, error) {
+//		// Fetch post here.
+//	})
+//
+// The "id" field of the type is exposed as an opaque global identifier,
+// that encodes the name of the type and the local identifier.
+func DefineNode(v interface{}) (nodedef *NodeDef, err error) {
+	funcdef, err := DefineFunc("node", v)
+	if err != nil {
+		return nil, err
+	}
+	if funcdef.In == nil {
+		return nil, errors.New("node is missing identifier argument")
+	}
+	return &NodeDef{Fetch: funcdef}, nil
+}
+
+// NewNode creates a new node definition, on error it panics.
+//
+// See documentation of DefineNode for more details.
+func NewNode(v interface{}) *NodeDef {
+	nodedef, err := DefineNode(v)
+	if err != nil {
+		panic(err)
+	}
+	return nodedef
+}